@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/database"
+	"github.com/corporateanon/barker/pkg/dbclient"
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Number of users in the benchmark audience, can be changed with BARKER_BENCH_USERS
+const defaultBenchmarkUsers = 1000000
+
+type benchmarkSetup struct {
+	deliveryDao dao.DeliveryDao
+	botID       int64
+}
+
+var benchmarkSetups = map[dbclient.RecipientSelection]benchmarkSetup{}
+
+// prepareBenchmark creates a bot with a large audience, an old campaign which every user has got
+// and a new active campaign. It is done once per strategy, since it takes a while.
+func prepareBenchmark(b *testing.B, selection dbclient.RecipientSelection) (dao.DeliveryDao, int64) {
+	if setup, ok := benchmarkSetups[selection]; ok {
+		return setup.deliveryDao, setup.botID
+	}
+
+	usersCount := defaultBenchmarkUsers
+	if env := os.Getenv("BARKER_BENCH_USERS"); env != "" {
+		var err error
+		if usersCount, err = strconv.Atoi(env); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	db, err := database.NewDatabase(sqlite.Open(fmt.Sprintf("file:benchmark%d.db?mode=memory&cache=shared", selection)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	db = db.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)})
+
+	botDao := dbclient.NewBotDaoImplGorm(db)
+	userDao := dbclient.NewUserDaoImplGorm(db)
+	campaignDao := dbclient.NewCampaignDaoImplGorm(db)
+	deliveryDao := dbclient.NewDeliveryDaoImplGormWithSelection(db, campaignDao, userDao, selection)
+
+	bot, err := botDao.Create(&types.Bot{Title: "Benchmark bot", Token: "benchmark"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	oldCampaign, err := campaignDao.Create(&types.Campaign{
+		BotID:   bot.ID,
+		Title:   "Old campaign",
+		Message: "Old message",
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	if err := db.Exec(
+		"INSERT INTO users (created_at, updated_at, display_name, telegram_id, bot_id) "+
+			"WITH RECURSIVE seq(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM seq WHERE n < ?) "+
+			"SELECT datetime('now'), datetime('now'), 'User ' || n, n, ? FROM seq",
+		usersCount, bot.ID,
+	).Error; err != nil {
+		b.Fatal(err)
+	}
+	if err := db.Exec(
+		"INSERT INTO deliveries (created_at, updated_at, campaign_id, bot_id, telegram_id, state) "+
+			"SELECT datetime('now'), datetime('now'), ?, bot_id, telegram_id, ? FROM users",
+		oldCampaign.ID, types.DeliveryStateSuccess,
+	).Error; err != nil {
+		b.Fatal(err)
+	}
+
+	if _, err := campaignDao.Create(&types.Campaign{
+		BotID:   bot.ID,
+		Title:   "New campaign",
+		Message: "New message",
+		Active:  true,
+	}); err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarkSetups[selection] = benchmarkSetup{deliveryDao: deliveryDao, botID: bot.ID}
+	return deliveryDao, bot.ID
+}
+
+func benchmarkTake(b *testing.B, selection dbclient.RecipientSelection) {
+	deliveryDao, botID := prepareBenchmark(b, selection)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, err := deliveryDao.Take(botID, 0, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if result == nil {
+			b.Fatal("The audience is exhausted, increase BARKER_BENCH_USERS")
+		}
+	}
+}
+
+func BenchmarkTakeQueue(b *testing.B) {
+	benchmarkTake(b, dbclient.RecipientSelectionQueue)
+}
+
+func BenchmarkTakeJoin(b *testing.B) {
+	benchmarkTake(b, dbclient.RecipientSelectionJoin)
+}
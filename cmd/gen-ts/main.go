@@ -17,7 +17,28 @@ func main() {
 		Add(types.Delivery{}).
 		Add(types.PaginatorRequest{}).
 		Add(types.PaginatorResponse{}).
+		Add(types.Worker{}).
+		Add(types.CampaignOperation{}).
+		Add(types.MessageJob{}).
+		Add(types.Click{}).
+		Add(types.Conversion{}).
+		Add(types.Answer{}).
+		Add(types.SurveyResults{}).
+		Add(types.InboxMessage{}).
+		Add(types.Conversation{}).
+		Add(types.AutoResponderRule{}).
+		Add(types.SourceUsers{}).
+		Add(types.CampaignSourceStatistics{}).
+		Add(types.CampaignLanguageStatistics{}).
+		Add(types.MediaAsset{}).
+		Add(types.CampaignPreview{}).
+		Add(types.Tester{}).
+		Add(types.CampaignRevision{}).
+		Add(types.CampaignRevisionDiff{}).
+		Add(types.DeliveryStateReport{}).
+		Add(types.Sequence{}).
 		AddEnum(types.AllDeliveryStates).
+		AddEnum(types.AllMessageJobStates).
 		Add(dao.DeliveryTakeResult{})
 
 	converter.CreateInterface = true
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/corporateanon/barker/pkg/client"
+	"github.com/corporateanon/barker/pkg/config"
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/sender"
+	"github.com/corporateanon/barker/pkg/telegram"
+	"github.com/go-resty/resty/v2"
+	"go.uber.org/fx"
+)
+
+const version = "0.1.0"
+
+func newBarkerClient(c *config.SenderConfig) *resty.Client {
+	return resty.New().SetHostURL(c.BarkerURL)
+}
+
+func newTelegramClient(c *config.SenderConfig) *telegram.Client {
+	return telegram.NewClient(c.TelegramAPIURL)
+}
+
+func newSender(
+	c *config.SenderConfig,
+	botDao dao.BotDao,
+	deliveryDao dao.DeliveryDao,
+	workerDao dao.WorkerDao,
+	operationDao dao.CampaignOperationDao,
+	mediaDao dao.MediaDao,
+	telegramClient *telegram.Client,
+) *sender.Sender {
+	host, _ := os.Hostname()
+	return sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+		WorkerID:    c.WorkerID,
+		Version:     version,
+		Host:        host,
+		BatchSize:   c.BatchSize,
+		RateLimit:   c.RateLimit,
+		LinkBaseURL: c.PublicURL,
+	})
+}
+
+func start(lc fx.Lifecycle, s *sender.Sender, shutdowner fx.Shutdowner) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				if err := s.Run(ctx); err != nil {
+					log.Printf("Sender has stopped: %s", err)
+					shutdowner.Shutdown()
+				}
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	})
+}
+
+func main() {
+	app := fx.New(
+		fx.Provide(
+			config.NewSenderConfig,
+			newBarkerClient,
+			newTelegramClient,
+			newSender,
+			client.NewBotDaoImplResty,
+			client.NewDeliveryDaoImplResty,
+			client.NewWorkerDaoImplResty,
+			client.NewCampaignOperationDaoImplResty,
+			client.NewMediaDaoImplResty,
+		),
+		fx.Invoke(start),
+	)
+
+	app.Run()
+}
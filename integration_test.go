@@ -22,6 +22,19 @@ func TestLocalGorm(t *testing.T) {
 	}
 }
 
+func TestLocalGormJoinSelection(t *testing.T) {
+	app := fx.New(
+		createIntegrationTestConfigurationGormJoinSelection(),
+		createIntegrationTestInvocation(t),
+	)
+
+	startCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := app.Start(startCtx); err != nil {
+		log.Fatal(err)
+	}
+}
+
 func TestClientServer(t *testing.T) {
 	serverApp := fx.New(
 		createIntegrationTestConfigurationServer(),
@@ -60,3 +73,68 @@ func TestRoundRobin(t *testing.T) {
 		log.Fatal(err)
 	}
 }
+
+func TestWorkers(t *testing.T) {
+	app := fx.New(
+		createIntegrationTestConfigurationWorkers(),
+		createIntegrationTestWorkersInvocation(t),
+	)
+
+	startCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := app.Start(startCtx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func TestLongPolling(t *testing.T) {
+	app := fx.New(
+		createIntegrationTestConfigurationLongPolling(),
+		createIntegrationTestLongPollingInvocation(t),
+	)
+
+	startCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := app.Start(startCtx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func TestSender(t *testing.T) {
+	app := fx.New(
+		createIntegrationTestConfigurationSender(),
+		createIntegrationTestSenderInvocation(t),
+	)
+
+	startCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := app.Start(startCtx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	app := fx.New(
+		createIntegrationTestConfigurationWebhook(),
+		createIntegrationTestWebhookInvocation(t),
+	)
+
+	startCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := app.Start(startCtx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func TestPolling(t *testing.T) {
+	app := fx.New(
+		createIntegrationTestConfigurationPolling(),
+		createIntegrationTestPollingInvocation(t),
+	)
+
+	startCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := app.Start(startCtx); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/corporateanon/barker/pkg/config"
+	"github.com/corporateanon/barker/pkg/dao"
 	"github.com/corporateanon/barker/pkg/database"
 	"github.com/corporateanon/barker/pkg/dbclient"
+	"github.com/corporateanon/barker/pkg/ingest"
+	"github.com/corporateanon/barker/pkg/notify"
 	"github.com/corporateanon/barker/pkg/server"
+	"github.com/corporateanon/barker/pkg/telegram"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/fx"
 )
@@ -15,6 +22,41 @@ func start(r *gin.Engine) {
 	http.ListenAndServe(":3000", r)
 }
 
+func startWorkerReaper(workerDao dao.WorkerDao, notifier *notify.Notifier) {
+	go func() {
+		for range time.Tick(dao.WorkerTimeout / 4) {
+			workers, err := workerDao.ReleaseExpired(dao.WorkerTimeout)
+			if err != nil {
+				log.Printf("Failed to release expired workers: %s", err)
+				continue
+			}
+			for _, worker := range workers {
+				log.Printf("Worker %s has expired, its deliveries are released", worker.ID)
+			}
+			if len(workers) > 0 {
+				notifier.Notify()
+			}
+		}
+	}()
+}
+
+func newTelegramClient(c *config.Config) *telegram.Client {
+	return telegram.NewClient(c.TelegramAPIURL)
+}
+
+func newPoller(
+	botDao dao.BotDao,
+	ingester *ingest.Ingester,
+	telegramClient *telegram.Client,
+	notifier *notify.Notifier,
+) *ingest.Poller {
+	return ingest.NewPoller(botDao, ingester, telegramClient, notifier, ingest.PollerOptions{})
+}
+
+func startPoller(poller *ingest.Poller) {
+	go poller.Run(context.Background())
+}
+
 func main() {
 	app := fx.New(
 		fx.Provide(
@@ -24,9 +66,26 @@ func main() {
 			dbclient.NewCampaignDaoImplGorm,
 			dbclient.NewDeliveryDaoImplGorm,
 			dbclient.NewBotDaoImplGorm,
+			dbclient.NewWorkerDaoImplGorm,
+			dbclient.NewCampaignOperationDaoImplGorm,
+			dbclient.NewClickDaoImplGorm,
+			dbclient.NewLinkDaoImplGorm,
+			dbclient.NewConversionDaoImplGorm,
+			dbclient.NewAnswerDaoImplGorm,
+			dbclient.NewInboxDaoImplGorm,
+			dbclient.NewAutoResponderDaoImplGorm,
+			dbclient.NewMediaDaoImplGorm,
+			dbclient.NewTesterDaoImplGorm,
+			dbclient.NewSequenceDaoImplGorm,
 			database.NewDatabase,
 			database.NewDialectorMySQL,
+			notify.NewNotifier,
+			ingest.NewIngester,
+			newTelegramClient,
+			newPoller,
 		),
+		fx.Invoke(startWorkerReaper),
+		fx.Invoke(startPoller),
 		fx.Invoke(start),
 	)
 
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"strconv"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/types"
+	"github.com/go-resty/resty/v2"
+)
+
+type AnswerDaoImplResty struct {
+	resty *resty.Client
+}
+
+func NewAnswerDaoImplResty(resty *resty.Client) dao.AnswerDao {
+	return &AnswerDaoImplResty{
+		resty: resty,
+	}
+}
+
+func (dao *AnswerDaoImplResty) Put(answer *types.Answer) (*types.Answer, error) {
+	resultWrapper := &struct{ Data *types.Answer }{Data: &types.Answer{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(answer).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID": strconv.FormatInt(answer.BotID, 10),
+		}).
+		Put("/bot/{BotID}/answer")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *AnswerDaoImplResty) Retract(answer *types.Answer) error {
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(answer).
+		SetPathParams(map[string]string{
+			"BotID": strconv.FormatInt(answer.BotID, 10),
+		}).
+		Post("/bot/{BotID}/answer/retract")
+	if err != nil {
+		return err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return httpErr.(*ErrorResponse)
+	}
+	return nil
+}
+
+func (dao *AnswerDaoImplResty) Results(botID int64, campaignID int64) (*types.SurveyResults, error) {
+	resultWrapper := &struct{ Data *types.SurveyResults }{Data: &types.SurveyResults{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"CampaignID": strconv.FormatInt(campaignID, 10),
+		}).
+		Get("/bot/{BotID}/campaign/{CampaignID}/results")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *AnswerDaoImplResty) List(botID int64, campaignID int64) ([]types.Answer, error) {
+	resultWrapper := &struct{ Data []types.Answer }{}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"CampaignID": strconv.FormatInt(campaignID, 10),
+		}).
+		Get("/bot/{BotID}/campaign/{CampaignID}/answer")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
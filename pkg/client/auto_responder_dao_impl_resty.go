@@ -0,0 +1,112 @@
+package client
+
+import (
+	"strconv"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/types"
+	"github.com/go-resty/resty/v2"
+)
+
+type AutoResponderDaoImplResty struct {
+	resty *resty.Client
+}
+
+func NewAutoResponderDaoImplResty(resty *resty.Client) dao.AutoResponderDao {
+	return &AutoResponderDaoImplResty{
+		resty: resty,
+	}
+}
+
+func (dao *AutoResponderDaoImplResty) Create(rule *types.AutoResponderRule) (*types.AutoResponderRule, error) {
+	resultWrapper := &struct{ Data *types.AutoResponderRule }{Data: &types.AutoResponderRule{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(rule).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID": strconv.FormatInt(rule.BotID, 10),
+		}).
+		Post("/bot/{BotID}/auto-responder")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *AutoResponderDaoImplResty) Update(rule *types.AutoResponderRule) (*types.AutoResponderRule, error) {
+	resultWrapper := &struct{ Data *types.AutoResponderRule }{Data: &types.AutoResponderRule{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(rule).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":  strconv.FormatInt(rule.BotID, 10),
+			"RuleID": strconv.FormatInt(rule.ID, 10),
+		}).
+		Put("/bot/{BotID}/auto-responder/{RuleID}")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *AutoResponderDaoImplResty) Get(botID int64, ruleID int64) (*types.AutoResponderRule, error) {
+	resultWrapper := &struct{ Data *types.AutoResponderRule }{Data: &types.AutoResponderRule{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":  strconv.FormatInt(botID, 10),
+			"RuleID": strconv.FormatInt(ruleID, 10),
+		}).
+		Get("/bot/{BotID}/auto-responder/{RuleID}")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *AutoResponderDaoImplResty) Delete(botID int64, ruleID int64) error {
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetPathParams(map[string]string{
+			"BotID":  strconv.FormatInt(botID, 10),
+			"RuleID": strconv.FormatInt(ruleID, 10),
+		}).
+		Delete("/bot/{BotID}/auto-responder/{RuleID}")
+	if err != nil {
+		return err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return httpErr.(*ErrorResponse)
+	}
+	return nil
+}
+
+func (dao *AutoResponderDaoImplResty) List(botID int64) ([]types.AutoResponderRule, error) {
+	resultWrapper := &struct{ Data []types.AutoResponderRule }{}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID": strconv.FormatInt(botID, 10),
+		}).
+		Get("/bot/{BotID}/auto-responder")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
@@ -123,3 +123,36 @@ func (dao *BotDaoImplResty) RRTake() (*types.Bot, error) {
 	}
 	return resultWrapper.Data, nil
 }
+
+func (dao *BotDaoImplResty) SetUpdateOffset(botID int64, offset int64) error {
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(map[string]int64{"Offset": offset}).
+		SetPathParams(map[string]string{
+			"BotID": strconv.FormatInt(botID, 10),
+		}).
+		Put("/bot/{BotID}/update-offset")
+	if err != nil {
+		return err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return httpErr.(*ErrorResponse)
+	}
+	return nil
+}
+
+func (dao *BotDaoImplResty) SetTokenRevoked(botID int64) error {
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetPathParams(map[string]string{
+			"BotID": strconv.FormatInt(botID, 10),
+		}).
+		Put("/bot/{BotID}/token-revoked")
+	if err != nil {
+		return err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return httpErr.(*ErrorResponse)
+	}
+	return nil
+}
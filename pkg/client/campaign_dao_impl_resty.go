@@ -57,6 +57,46 @@ func (dao *CampaignDaoImplResty) Update(campaign *types.Campaign) (*types.Campai
 	return resultWrapper.Data, nil
 }
 
+func (dao *CampaignDaoImplResty) SetState(botID int64, campaignID int64, state string) (*types.Campaign, error) {
+	resultWrapper := &struct{ Data *types.Campaign }{Data: &types.Campaign{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(map[string]string{"State": state}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"CampaignID": strconv.FormatInt(campaignID, 10),
+		}).
+		Put("/bot/{BotID}/campaign/{CampaignID}/state")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *CampaignDaoImplResty) Approve(botID int64, campaignID int64, approvedBy string) (*types.Campaign, error) {
+	resultWrapper := &struct{ Data *types.Campaign }{Data: &types.Campaign{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(map[string]string{"ApprovedBy": approvedBy}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"CampaignID": strconv.FormatInt(campaignID, 10),
+		}).
+		Post("/bot/{BotID}/campaign/{CampaignID}/approve")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
 func (dao *CampaignDaoImplResty) Get(botID int64, ID int64) (*types.Campaign, error) {
 	resultWrapper := &struct{ Data *types.Campaign }{Data: &types.Campaign{}}
 	res, err := dao.resty.R().
@@ -76,6 +116,88 @@ func (dao *CampaignDaoImplResty) Get(botID int64, ID int64) (*types.Campaign, er
 	return resultWrapper.Data, nil
 }
 
+func (dao *CampaignDaoImplResty) ListRevisions(botID int64, campaignID int64) ([]types.CampaignRevision, error) {
+	resultWrapper := &struct{ Data []types.CampaignRevision }{}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"CampaignID": strconv.FormatInt(campaignID, 10),
+		}).
+		Get("/bot/{BotID}/campaign/{CampaignID}/revision")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *CampaignDaoImplResty) GetRevision(botID int64, campaignID int64, revision int64) (*types.CampaignRevision, error) {
+	resultWrapper := &struct{ Data *types.CampaignRevision }{Data: &types.CampaignRevision{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"CampaignID": strconv.FormatInt(campaignID, 10),
+			"Revision":   strconv.FormatInt(revision, 10),
+		}).
+		Get("/bot/{BotID}/campaign/{CampaignID}/revision/{Revision}")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *CampaignDaoImplResty) DiffRevisions(botID int64, campaignID int64, from int64, to int64) (*types.CampaignRevisionDiff, error) {
+	resultWrapper := &struct{ Data *types.CampaignRevisionDiff }{Data: &types.CampaignRevisionDiff{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetQueryParams(map[string]string{
+			"From": strconv.FormatInt(from, 10),
+			"To":   strconv.FormatInt(to, 10),
+		}).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"CampaignID": strconv.FormatInt(campaignID, 10),
+		}).
+		Get("/bot/{BotID}/campaign/{CampaignID}/revision-diff")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *CampaignDaoImplResty) GetDeliveredRevision(botID int64, campaignID int64, telegramID int64) (*types.CampaignRevision, error) {
+	resultWrapper := &struct{ Data *types.CampaignRevision }{Data: &types.CampaignRevision{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"CampaignID": strconv.FormatInt(campaignID, 10),
+			"TelegramID": strconv.FormatInt(telegramID, 10),
+		}).
+		Get("/bot/{BotID}/campaign/{CampaignID}/delivery/{TelegramID}/revision")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
 func (dao *CampaignDaoImplResty) List(botID int64, pageRequest *types.PaginatorRequest) ([]types.Campaign, *types.PaginatorResponse, error) {
 	resultWrapper := &struct {
 		Data   []types.Campaign
@@ -119,3 +241,46 @@ func (dao *CampaignDaoImplResty) GetAggregatedStatistics(botID int64, campaignID
 	return resultWrapper.Data, nil
 
 }
+
+func (dao *CampaignDaoImplResty) GetSourceStatistics(botID int64, campaignID int64, touch string) ([]types.CampaignSourceStatistics, error) {
+	resultWrapper := &struct {
+		Data []types.CampaignSourceStatistics
+	}{}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetQueryParam("Touch", touch).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"CampaignID": strconv.FormatInt(campaignID, 10),
+		}).
+		Get("/bot/{BotID}/campaign/{CampaignID}/sourceStatistics")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *CampaignDaoImplResty) GetLanguageStatistics(botID int64, campaignID int64) ([]types.CampaignLanguageStatistics, error) {
+	resultWrapper := &struct {
+		Data []types.CampaignLanguageStatistics
+	}{}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"CampaignID": strconv.FormatInt(campaignID, 10),
+		}).
+		Get("/bot/{BotID}/campaign/{CampaignID}/languageStatistics")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
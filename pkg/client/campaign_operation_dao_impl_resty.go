@@ -0,0 +1,186 @@
+package client
+
+import (
+	"strconv"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/types"
+	"github.com/go-resty/resty/v2"
+)
+
+type CampaignOperationDaoImplResty struct {
+	resty *resty.Client
+}
+
+func NewCampaignOperationDaoImplResty(resty *resty.Client) dao.CampaignOperationDao {
+	return &CampaignOperationDaoImplResty{
+		resty: resty,
+	}
+}
+
+func (dao *CampaignOperationDaoImplResty) Edit(botID int64, campaignID int64, text string) (*types.CampaignOperation, error) {
+	resultWrapper := &struct{ Data *types.CampaignOperation }{Data: &types.CampaignOperation{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(map[string]string{"Text": text}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"CampaignID": strconv.FormatInt(campaignID, 10),
+		}).
+		Post("/bot/{BotID}/campaign/{CampaignID}/edit")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *CampaignOperationDaoImplResty) Recall(botID int64, campaignID int64) (*types.CampaignOperation, error) {
+	resultWrapper := &struct{ Data *types.CampaignOperation }{Data: &types.CampaignOperation{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"CampaignID": strconv.FormatInt(campaignID, 10),
+		}).
+		Post("/bot/{BotID}/campaign/{CampaignID}/recall")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *CampaignOperationDaoImplResty) Test(botID int64, campaignID int64) (*types.CampaignOperation, error) {
+	resultWrapper := &struct{ Data *types.CampaignOperation }{Data: &types.CampaignOperation{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"CampaignID": strconv.FormatInt(campaignID, 10),
+		}).
+		Post("/bot/{BotID}/campaign/{CampaignID}/test")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *CampaignOperationDaoImplResty) Get(botID int64, campaignID int64, operationID int64) (*types.CampaignOperation, error) {
+	resultWrapper := &struct{ Data *types.CampaignOperation }{Data: &types.CampaignOperation{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":       strconv.FormatInt(botID, 10),
+			"CampaignID":  strconv.FormatInt(campaignID, 10),
+			"OperationID": strconv.FormatInt(operationID, 10),
+		}).
+		Get("/bot/{BotID}/campaign/{CampaignID}/operation/{OperationID}")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *CampaignOperationDaoImplResty) List(botID int64, campaignID int64) ([]types.CampaignOperation, error) {
+	resultWrapper := &struct{ Data []types.CampaignOperation }{}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"CampaignID": strconv.FormatInt(campaignID, 10),
+		}).
+		Get("/bot/{BotID}/campaign/{CampaignID}/operation")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *CampaignOperationDaoImplResty) TakeJobs(workerID string, botID int64, size int) ([]types.MessageJob, error) {
+	resultWrapper := &struct{ Data []types.MessageJob }{}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID": strconv.FormatInt(botID, 10),
+		}).
+		SetQueryParams(map[string]string{
+			"Size":     strconv.Itoa(size),
+			"WorkerID": workerID,
+		}).
+		Post("/bot/{BotID}/message-job/batch")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *CampaignOperationDaoImplResty) SetJobStates(jobs []types.MessageJob) error {
+	jobsByBot := map[int64][]types.MessageJob{}
+	for _, job := range jobs {
+		jobsByBot[job.BotID] = append(jobsByBot[job.BotID], job)
+	}
+
+	for botID, botJobs := range jobsByBot {
+		res, err := dao.resty.R().
+			SetError(&ErrorResponse{}).
+			SetBody(botJobs).
+			SetPathParams(map[string]string{
+				"BotID": strconv.FormatInt(botID, 10),
+			}).
+			Put("/bot/{BotID}/message-job/state")
+		if err != nil {
+			return err
+		}
+		if httpErr := res.Error(); httpErr != nil {
+			return httpErr.(*ErrorResponse)
+		}
+	}
+	return nil
+}
+
+func (dao *CampaignOperationDaoImplResty) ReleaseJobs(jobs []types.MessageJob) error {
+	jobsByBot := map[int64][]types.MessageJob{}
+	for _, job := range jobs {
+		jobsByBot[job.BotID] = append(jobsByBot[job.BotID], job)
+	}
+
+	for botID, botJobs := range jobsByBot {
+		res, err := dao.resty.R().
+			SetError(&ErrorResponse{}).
+			SetBody(botJobs).
+			SetPathParams(map[string]string{
+				"BotID": strconv.FormatInt(botID, 10),
+			}).
+			Post("/bot/{BotID}/message-job/release")
+		if err != nil {
+			return err
+		}
+		if httpErr := res.Error(); httpErr != nil {
+			return httpErr.(*ErrorResponse)
+		}
+	}
+	return nil
+}
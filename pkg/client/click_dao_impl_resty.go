@@ -0,0 +1,39 @@
+package client
+
+import (
+	"strconv"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/types"
+	"github.com/go-resty/resty/v2"
+)
+
+type ClickDaoImplResty struct {
+	resty *resty.Client
+}
+
+func NewClickDaoImplResty(resty *resty.Client) dao.ClickDao {
+	return &ClickDaoImplResty{
+		resty: resty,
+	}
+}
+
+func (dao *ClickDaoImplResty) Add(click *types.Click) (*types.Click, error) {
+	resultWrapper := &struct{ Data *types.Click }{Data: &types.Click{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(click).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(click.BotID, 10),
+			"CampaignID": strconv.FormatInt(click.CampaignID, 10),
+		}).
+		Post("/bot/{BotID}/campaign/{CampaignID}/click")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
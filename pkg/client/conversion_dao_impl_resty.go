@@ -0,0 +1,38 @@
+package client
+
+import (
+	"strconv"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/types"
+	"github.com/go-resty/resty/v2"
+)
+
+type ConversionDaoImplResty struct {
+	resty *resty.Client
+}
+
+func NewConversionDaoImplResty(resty *resty.Client) dao.ConversionDao {
+	return &ConversionDaoImplResty{
+		resty: resty,
+	}
+}
+
+func (dao *ConversionDaoImplResty) Create(conversion *types.Conversion) (*types.Conversion, error) {
+	resultWrapper := &struct{ Data *types.Conversion }{Data: &types.Conversion{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(conversion).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID": strconv.FormatInt(conversion.BotID, 10),
+		}).
+		Post("/bot/{BotID}/conversion")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
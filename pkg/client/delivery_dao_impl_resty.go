@@ -2,6 +2,7 @@ package client
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/corporateanon/barker/pkg/dao"
 	"github.com/corporateanon/barker/pkg/types"
@@ -10,6 +11,7 @@ import (
 
 type DeliveryDaoImplResty struct {
 	resty *resty.Client
+	wait  time.Duration
 }
 
 func NewDeliveryDaoImplResty(
@@ -20,7 +22,23 @@ func NewDeliveryDaoImplResty(
 	}
 }
 
+// NewDeliveryDaoImplRestyLongPolling creates a client which asks the server
+// to hold take requests open for up to wait until a delivery becomes available
+func NewDeliveryDaoImplRestyLongPolling(
+	resty *resty.Client,
+	wait time.Duration,
+) dao.DeliveryDao {
+	return &DeliveryDaoImplResty{
+		resty: resty,
+		wait:  wait,
+	}
+}
+
 func (this *DeliveryDaoImplResty) Take(botID int64, campaignID int64, telegramID int64) (*dao.DeliveryTakeResult, error) {
+	return this.TakeForWorker("", botID, campaignID, telegramID)
+}
+
+func (this *DeliveryDaoImplResty) TakeForWorker(workerID string, botID int64, campaignID int64, telegramID int64) (*dao.DeliveryTakeResult, error) {
 	resultWrapper := &struct {
 		Data *dao.DeliveryTakeResult
 	}{
@@ -40,6 +58,8 @@ func (this *DeliveryDaoImplResty) Take(botID int64, campaignID int64, telegramID
 			"CampaignID": strconv.FormatInt(campaignID, 10),
 		}).
 		SetQueryParam("TelegramID", strconv.FormatInt(telegramID, 10)).
+		SetQueryParam("WorkerID", workerID).
+		SetQueryParam("Wait", this.waitParam()).
 		Post(url)
 	if err != nil {
 		return nil, err
@@ -50,12 +70,71 @@ func (this *DeliveryDaoImplResty) Take(botID int64, campaignID int64, telegramID
 	return resultWrapper.Data, nil
 }
 
+func (this *DeliveryDaoImplResty) NextJob(workerID string) (*dao.DeliveryTakeResult, error) {
+	resultWrapper := &struct {
+		Data *dao.DeliveryTakeResult
+	}{
+		Data: &dao.DeliveryTakeResult{},
+	}
+
+	res, err := this.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetQueryParam("WorkerID", workerID).
+		SetQueryParam("Wait", this.waitParam()).
+		Post("/rr/delivery")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (this *DeliveryDaoImplResty) TakeBatch(workerID string, botID int64, size int) ([]dao.DeliveryTakeResult, error) {
+	resultWrapper := &struct {
+		Data []dao.DeliveryTakeResult
+	}{}
+
+	res, err := this.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID": strconv.FormatInt(botID, 10),
+		}).
+		SetQueryParams(map[string]string{
+			"Size":     strconv.Itoa(size),
+			"WorkerID": workerID,
+			"Wait":     this.waitParam(),
+		}).
+		Post("/bot/{BotID}/delivery/batch")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
 func (dao *DeliveryDaoImplResty) SetState(delivery *types.Delivery, state types.DeliveryState) error {
 	stateString, err := state.ToString()
 	if err != nil {
 		return err
 	}
 
+	queryParams := map[string]string{}
+	if delivery.Reason != "" {
+		queryParams["Reason"] = delivery.Reason
+	}
+	if delivery.MessageID != 0 {
+		queryParams["MessageID"] = strconv.FormatInt(delivery.MessageID, 10)
+	}
+	if delivery.PollID != "" {
+		queryParams["PollID"] = delivery.PollID
+	}
+
 	res, err := dao.resty.R().
 		SetError(&ErrorResponse{}).
 		SetPathParams(map[string]string{
@@ -64,6 +143,7 @@ func (dao *DeliveryDaoImplResty) SetState(delivery *types.Delivery, state types.
 			"TelegramID": strconv.FormatInt(delivery.TelegramID, 10),
 			"State":      stateString,
 		}).
+		SetQueryParams(queryParams).
 		Put("/bot/{BotID}/campaign/{CampaignID}/delivery/{TelegramID}/state/{State}")
 	if err != nil {
 		return err
@@ -74,6 +154,34 @@ func (dao *DeliveryDaoImplResty) SetState(delivery *types.Delivery, state types.
 	return nil
 }
 
+func (dao *DeliveryDaoImplResty) SetStates(deliveries []types.Delivery) error {
+	reportsByBot := map[int64][]types.DeliveryStateReport{}
+	for i := range deliveries {
+		report, err := types.NewDeliveryStateReport(&deliveries[i])
+		if err != nil {
+			return err
+		}
+		reportsByBot[deliveries[i].BotID] = append(reportsByBot[deliveries[i].BotID], *report)
+	}
+
+	for botID, botReports := range reportsByBot {
+		res, err := dao.resty.R().
+			SetError(&ErrorResponse{}).
+			SetBody(botReports).
+			SetPathParams(map[string]string{
+				"BotID": strconv.FormatInt(botID, 10),
+			}).
+			Put("/bot/{BotID}/delivery/state")
+		if err != nil {
+			return err
+		}
+		if httpErr := res.Error(); httpErr != nil {
+			return httpErr.(*ErrorResponse)
+		}
+	}
+	return nil
+}
+
 func (dao *DeliveryDaoImplResty) GetState(delivery *types.Delivery) (types.DeliveryState, error) {
 	resultWrapper := &struct {
 		Data string
@@ -100,3 +208,34 @@ func (dao *DeliveryDaoImplResty) GetState(delivery *types.Delivery) (types.Deliv
 	}
 	return state, nil
 }
+
+func (this *DeliveryDaoImplResty) waitParam() string {
+	return strconv.FormatInt(int64(this.wait/time.Second), 10)
+}
+
+func (dao *DeliveryDaoImplResty) Release(deliveries []types.Delivery) error {
+	deliveriesByBot := map[int64][]types.Delivery{}
+	for _, delivery := range deliveries {
+		deliveriesByBot[delivery.BotID] = append(deliveriesByBot[delivery.BotID], types.Delivery{
+			CampaignID: delivery.CampaignID,
+			TelegramID: delivery.TelegramID,
+		})
+	}
+
+	for botID, botDeliveries := range deliveriesByBot {
+		res, err := dao.resty.R().
+			SetError(&ErrorResponse{}).
+			SetBody(botDeliveries).
+			SetPathParams(map[string]string{
+				"BotID": strconv.FormatInt(botID, 10),
+			}).
+			Post("/bot/{BotID}/delivery/release")
+		if err != nil {
+			return err
+		}
+		if httpErr := res.Error(); httpErr != nil {
+			return httpErr.(*ErrorResponse)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"strconv"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/types"
+	"github.com/go-resty/resty/v2"
+)
+
+type InboxDaoImplResty struct {
+	resty *resty.Client
+}
+
+func NewInboxDaoImplResty(resty *resty.Client) dao.InboxDao {
+	return &InboxDaoImplResty{
+		resty: resty,
+	}
+}
+
+func (dao *InboxDaoImplResty) Receive(message *types.InboxMessage) (*types.InboxMessage, error) {
+	resultWrapper := &struct{ Data *types.InboxMessage }{}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(message).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID": strconv.FormatInt(message.BotID, 10),
+		}).
+		Post("/bot/{BotID}/inbox")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *InboxDaoImplResty) Reply(botID int64, telegramID int64, text string) (*types.InboxMessage, error) {
+	resultWrapper := &struct{ Data *types.InboxMessage }{Data: &types.InboxMessage{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(map[string]string{"Text": text}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"TelegramID": strconv.FormatInt(telegramID, 10),
+		}).
+		Post("/bot/{BotID}/inbox/{TelegramID}/reply")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *InboxDaoImplResty) AutoReply(botID int64, telegramID int64, replyTo int64, text string) (*types.InboxMessage, error) {
+	resultWrapper := &struct{ Data *types.InboxMessage }{Data: &types.InboxMessage{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(map[string]interface{}{"Text": text, "ReplyTo": replyTo}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"TelegramID": strconv.FormatInt(telegramID, 10),
+		}).
+		Post("/bot/{BotID}/inbox/{TelegramID}/reply")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *InboxDaoImplResty) List(botID int64, pageRequest *types.PaginatorRequest) ([]types.Conversation, *types.PaginatorResponse, error) {
+	resultWrapper := &struct {
+		Data   []types.Conversation
+		Paging *types.PaginatorResponse
+	}{}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetQueryParams(pageRequest.ToMap()).
+		SetPathParams(map[string]string{
+			"BotID": strconv.FormatInt(botID, 10),
+		}).
+		Get("/bot/{BotID}/inbox")
+	if err != nil {
+		return nil, nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, resultWrapper.Paging, nil
+}
+
+func (dao *InboxDaoImplResty) Conversation(botID int64, telegramID int64, pageRequest *types.PaginatorRequest) ([]types.InboxMessage, *types.PaginatorResponse, error) {
+	resultWrapper := &struct {
+		Data   []types.InboxMessage
+		Paging *types.PaginatorResponse
+	}{}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetQueryParams(pageRequest.ToMap()).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"TelegramID": strconv.FormatInt(telegramID, 10),
+		}).
+		Get("/bot/{BotID}/inbox/{TelegramID}")
+	if err != nil {
+		return nil, nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, resultWrapper.Paging, nil
+}
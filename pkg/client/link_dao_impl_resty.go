@@ -0,0 +1,35 @@
+package client
+
+import (
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/types"
+	"github.com/go-resty/resty/v2"
+)
+
+type LinkDaoImplResty struct {
+	resty *resty.Client
+}
+
+func NewLinkDaoImplResty(resty *resty.Client) dao.LinkDao {
+	return &LinkDaoImplResty{
+		resty: resty,
+	}
+}
+
+func (dao *LinkDaoImplResty) Open(code string) (*types.TrackedLink, error) {
+	resultWrapper := &struct{ Data *types.TrackedLink }{Data: &types.TrackedLink{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"Code": code,
+		}).
+		Post("/link/{Code}/open")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/types"
+	"github.com/go-resty/resty/v2"
+)
+
+type MediaDaoImplResty struct {
+	resty *resty.Client
+}
+
+func NewMediaDaoImplResty(resty *resty.Client) dao.MediaDao {
+	return &MediaDaoImplResty{
+		resty: resty,
+	}
+}
+
+func (dao *MediaDaoImplResty) Create(asset *types.MediaAsset, content []byte) (*types.MediaAsset, error) {
+	resultWrapper := &struct{ Data *types.MediaAsset }{Data: &types.MediaAsset{}}
+	form := map[string]string{}
+	if asset.ContentType != "" {
+		form["ContentType"] = asset.ContentType
+	}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetMultipartFormData(form).
+		SetFileReader("File", asset.FileName, bytes.NewReader(content)).
+		SetResult(resultWrapper).
+		Post("/media")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *MediaDaoImplResty) Get(mediaID int64) (*types.MediaAsset, error) {
+	resultWrapper := &struct{ Data *types.MediaAsset }{Data: &types.MediaAsset{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"MediaID": strconv.FormatInt(mediaID, 10),
+		}).
+		Get("/media/{MediaID}")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *MediaDaoImplResty) GetContent(mediaID int64) ([]byte, error) {
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetPathParams(map[string]string{
+			"MediaID": strconv.FormatInt(mediaID, 10),
+		}).
+		Get("/media/{MediaID}/content")
+	if err != nil {
+		return nil, err
+	}
+	//The error is only parsed from JSON responses, the content is not
+	if res.IsError() {
+		return nil, res.Error().(*ErrorResponse)
+	}
+	return res.Body(), nil
+}
+
+func (dao *MediaDaoImplResty) List(pageRequest *types.PaginatorRequest) ([]types.MediaAsset, *types.PaginatorResponse, error) {
+	resultWrapper := &struct {
+		Data   []types.MediaAsset
+		Paging *types.PaginatorResponse
+	}{}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetQueryParams(pageRequest.ToMap()).
+		Get("/media")
+	if err != nil {
+		return nil, nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, resultWrapper.Paging, nil
+}
+
+func (dao *MediaDaoImplResty) SetFileID(botID int64, mediaID int64, fileID string) error {
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(map[string]string{"FileID": fileID}).
+		SetPathParams(map[string]string{
+			"BotID":   strconv.FormatInt(botID, 10),
+			"MediaID": strconv.FormatInt(mediaID, 10),
+		}).
+		Put("/bot/{BotID}/media/{MediaID}/file-id")
+	if err != nil {
+		return err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return httpErr.(*ErrorResponse)
+	}
+	return nil
+}
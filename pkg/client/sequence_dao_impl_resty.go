@@ -0,0 +1,137 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/types"
+	"github.com/go-resty/resty/v2"
+)
+
+type SequenceDaoImplResty struct {
+	resty *resty.Client
+}
+
+func NewSequenceDaoImplResty(resty *resty.Client) dao.SequenceDao {
+	return &SequenceDaoImplResty{
+		resty: resty,
+	}
+}
+
+func (dao *SequenceDaoImplResty) Create(sequence *types.Sequence) (*types.Sequence, error) {
+	resultWrapper := &struct{ Data *types.Sequence }{Data: &types.Sequence{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(sequence).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID": strconv.FormatInt(sequence.BotID, 10),
+		}).
+		Post("/bot/{BotID}/sequence")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *SequenceDaoImplResty) Update(sequence *types.Sequence) (*types.Sequence, error) {
+	resultWrapper := &struct{ Data *types.Sequence }{Data: &types.Sequence{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(sequence).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(sequence.BotID, 10),
+			"SequenceID": strconv.FormatInt(sequence.ID, 10),
+		}).
+		Put("/bot/{BotID}/sequence/{SequenceID}")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *SequenceDaoImplResty) Get(botID int64, sequenceID int64) (*types.Sequence, error) {
+	resultWrapper := &struct{ Data *types.Sequence }{Data: &types.Sequence{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"SequenceID": strconv.FormatInt(sequenceID, 10),
+		}).
+		Get("/bot/{BotID}/sequence/{SequenceID}")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *SequenceDaoImplResty) Delete(botID int64, sequenceID int64) error {
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"SequenceID": strconv.FormatInt(sequenceID, 10),
+		}).
+		Delete("/bot/{BotID}/sequence/{SequenceID}")
+	if err != nil {
+		return err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return httpErr.(*ErrorResponse)
+	}
+	return nil
+}
+
+func (dao *SequenceDaoImplResty) List(botID int64) ([]types.Sequence, error) {
+	resultWrapper := &struct{ Data []types.Sequence }{}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID": strconv.FormatInt(botID, 10),
+		}).
+		Get("/bot/{BotID}/sequence")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *SequenceDaoImplResty) Enroll(botID int64, sequenceID int64, telegramID int64) (*types.Sequence, error) {
+	resultWrapper := &struct{ Data *types.Sequence }{Data: &types.Sequence{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"SequenceID": strconv.FormatInt(sequenceID, 10),
+			"TelegramID": strconv.FormatInt(telegramID, 10),
+		}).
+		Put("/bot/{BotID}/sequence/{SequenceID}/enrollment/{TelegramID}")
+	if err != nil {
+		return nil, err
+	}
+	//Nil is returned for a missing sequence or user, as the DAO contract says
+	if res.StatusCode() == http.StatusNotFound {
+		return nil, nil
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"strconv"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/types"
+	"github.com/go-resty/resty/v2"
+)
+
+type TesterDaoImplResty struct {
+	resty *resty.Client
+}
+
+func NewTesterDaoImplResty(resty *resty.Client) dao.TesterDao {
+	return &TesterDaoImplResty{
+		resty: resty,
+	}
+}
+
+func (dao *TesterDaoImplResty) Add(botID int64, telegramID int64) (*types.Tester, error) {
+	resultWrapper := &struct{ Data *types.Tester }{Data: &types.Tester{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"TelegramID": strconv.FormatInt(telegramID, 10),
+		}).
+		Put("/bot/{BotID}/tester/{TelegramID}")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *TesterDaoImplResty) Remove(botID int64, telegramID int64) error {
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"TelegramID": strconv.FormatInt(telegramID, 10),
+		}).
+		Delete("/bot/{BotID}/tester/{TelegramID}")
+	if err != nil {
+		return err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return httpErr.(*ErrorResponse)
+	}
+	return nil
+}
+
+func (dao *TesterDaoImplResty) List(botID int64) ([]types.Tester, error) {
+	resultWrapper := &struct{ Data []types.Tester }{}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID": strconv.FormatInt(botID, 10),
+		}).
+		Get("/bot/{BotID}/tester")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
@@ -77,3 +77,101 @@ func (dao *UserDaoImplResty) List(botID int64, pageRequest *types.PaginatorReque
 	}
 	return resultWrapper.Data, resultWrapper.Paging, nil
 }
+
+func (dao *UserDaoImplResty) SetBlocked(botID int64, telegramID int64, blocked bool) (*types.User, error) {
+	resultWrapper := &struct{ Data *types.User }{Data: &types.User{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(map[string]bool{"Blocked": blocked}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"TelegramID": strconv.FormatInt(telegramID, 10),
+		}).
+		Put("/bot/{BotID}/user/{TelegramID}/blocked")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *UserDaoImplResty) SetUnsubscribed(botID int64, telegramID int64, unsubscribed bool) (*types.User, error) {
+	resultWrapper := &struct{ Data *types.User }{Data: &types.User{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(map[string]bool{"Unsubscribed": unsubscribed}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"TelegramID": strconv.FormatInt(telegramID, 10),
+		}).
+		Put("/bot/{BotID}/user/{TelegramID}/unsubscribed")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *UserDaoImplResty) Migrate(botID int64, telegramID int64, newTelegramID int64) (*types.User, error) {
+	resultWrapper := &struct{ Data *types.User }{Data: &types.User{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(map[string]int64{"NewTelegramID": newTelegramID}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"TelegramID": strconv.FormatInt(telegramID, 10),
+		}).
+		Post("/bot/{BotID}/user/{TelegramID}/migrate")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *UserDaoImplResty) AddTag(botID int64, telegramID int64, tag string) (*types.User, error) {
+	resultWrapper := &struct{ Data *types.User }{Data: &types.User{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(map[string]string{"Tag": tag}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID":      strconv.FormatInt(botID, 10),
+			"TelegramID": strconv.FormatInt(telegramID, 10),
+		}).
+		Post("/bot/{BotID}/user/{TelegramID}/tag")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *UserDaoImplResty) Sources(botID int64) ([]types.SourceUsers, error) {
+	resultWrapper := &struct{ Data []types.SourceUsers }{}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"BotID": strconv.FormatInt(botID, 10),
+		}).
+		Get("/bot/{BotID}/sources")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
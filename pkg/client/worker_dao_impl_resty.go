@@ -0,0 +1,104 @@
+package client
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/types"
+	"github.com/go-resty/resty/v2"
+)
+
+type WorkerDaoImplResty struct {
+	resty *resty.Client
+}
+
+func NewWorkerDaoImplResty(resty *resty.Client) dao.WorkerDao {
+	return &WorkerDaoImplResty{
+		resty: resty,
+	}
+}
+
+func (dao *WorkerDaoImplResty) Register(worker *types.Worker) (*types.Worker, error) {
+	resultWrapper := &struct{ Data *types.Worker }{Data: &types.Worker{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(worker).
+		SetResult(resultWrapper).
+		Post("/worker")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *WorkerDaoImplResty) Heartbeat(worker *types.Worker) (*types.Worker, error) {
+	resultWrapper := &struct{ Data *types.Worker }{Data: &types.Worker{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetBody(worker).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"WorkerID": worker.ID,
+		}).
+		Put("/worker/{WorkerID}/heartbeat")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *WorkerDaoImplResty) Get(ID string) (*types.Worker, error) {
+	resultWrapper := &struct{ Data *types.Worker }{Data: &types.Worker{}}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetPathParams(map[string]string{
+			"WorkerID": ID,
+		}).
+		Get("/worker/{WorkerID}")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *WorkerDaoImplResty) List() ([]types.Worker, error) {
+	resultWrapper := &struct{ Data []types.Worker }{}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		Get("/worker")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
+
+func (dao *WorkerDaoImplResty) ReleaseExpired(timeout time.Duration) ([]types.Worker, error) {
+	resultWrapper := &struct{ Data []types.Worker }{}
+	res, err := dao.resty.R().
+		SetError(&ErrorResponse{}).
+		SetResult(resultWrapper).
+		SetQueryParam("Timeout", strconv.FormatInt(int64(timeout/time.Second), 10)).
+		Post("/worker-expired/release")
+	if err != nil {
+		return nil, err
+	}
+	if httpErr := res.Error(); httpErr != nil {
+		return nil, httpErr.(*ErrorResponse)
+	}
+	return resultWrapper.Data, nil
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"github.com/corporateanon/barker/pkg/telegram"
 	"github.com/go-playground/validator/v10"
 	"github.com/spf13/viper"
 )
@@ -8,14 +9,18 @@ import (
 type Config struct {
 	DBDriver     string `validate:"required,oneof=mysql sqlite"`
 	DBConnection string `validate:"required"`
+	//Base URL of the Telegram Bot API, used to poll updates of bots in polling mode
+	TelegramAPIURL string `validate:"required,url"`
 }
 
 func NewConfig() (*Config, error) {
 	c := &Config{}
 	v := viper.New()
 	v.AutomaticEnv()
+	v.SetDefault("telegram_api_url", telegram.DefaultAPIURL)
 	c.DBDriver = v.GetString("db_driver")
 	c.DBConnection = v.GetString("db_connection")
+	c.TelegramAPIURL = v.GetString("telegram_api_url")
 	validate := validator.New()
 	err := validate.Struct(c)
 	if err != nil {
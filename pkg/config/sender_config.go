@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/corporateanon/barker/pkg/telegram"
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+)
+
+type SenderConfig struct {
+	//Base URL of the barker server
+	BarkerURL string `validate:"required,url"`
+	//Base URL of the Telegram Bot API, may point to a local Bot API server or a fake
+	TelegramAPIURL string `validate:"required,url"`
+	WorkerID       string `validate:"required"`
+	//How many deliveries are taken at once
+	BatchSize int `validate:"min=1,max=1000"`
+	//Messages per second per bot
+	RateLimit float64 `validate:"gt=0"`
+	//Base URL of tracked links as seen by recipients, the barker URL by default
+	PublicURL string `validate:"required,url"`
+}
+
+func NewSenderConfig() (*SenderConfig, error) {
+	c := &SenderConfig{}
+	v := viper.New()
+	v.AutomaticEnv()
+	v.SetDefault("telegram_api_url", telegram.DefaultAPIURL)
+	v.SetDefault("worker_id", defaultWorkerID())
+	v.SetDefault("batch_size", 100)
+	v.SetDefault("rate_limit", 25)
+	c.BarkerURL = v.GetString("barker_url")
+	c.TelegramAPIURL = v.GetString("telegram_api_url")
+	c.WorkerID = v.GetString("worker_id")
+	c.BatchSize = v.GetInt("batch_size")
+	c.RateLimit = v.GetFloat64("rate_limit")
+	v.SetDefault("public_url", c.BarkerURL)
+	c.PublicURL = v.GetString("public_url")
+	validate := validator.New()
+	err := validate.Struct(c)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func defaultWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "sender"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
@@ -0,0 +1,15 @@
+package dao
+
+import "github.com/corporateanon/barker/pkg/types"
+
+type AnswerDao interface {
+	//Put stores the answer of a user who has got the campaign, replacing the previous one.
+	//Answers to campaigns which have not been delivered to the user are ignored, nil is returned for them.
+	Put(answer *types.Answer) (*types.Answer, error)
+	//Retract deletes the answer, e.g. when a vote in a poll is retracted
+	Retract(answer *types.Answer) error
+	//Results counts answers per option
+	Results(botID int64, campaignID int64) (*types.SurveyResults, error)
+	//List returns all answers of a campaign, oldest first
+	List(botID int64, campaignID int64) ([]types.Answer, error)
+}
@@ -0,0 +1,13 @@
+package dao
+
+import "github.com/corporateanon/barker/pkg/types"
+
+// AutoResponderDao keeps auto-responder rules of bots
+type AutoResponderDao interface {
+	Create(rule *types.AutoResponderRule) (*types.AutoResponderRule, error)
+	Update(rule *types.AutoResponderRule) (*types.AutoResponderRule, error)
+	Get(botID int64, ruleID int64) (*types.AutoResponderRule, error)
+	Delete(botID int64, ruleID int64) error
+	//List returns rules of a bot in the order they are tried
+	List(botID int64) ([]types.AutoResponderRule, error)
+}
@@ -9,4 +9,8 @@ type BotDao interface {
 	GetByToken(token string) (*types.Bot, error)
 	List(pageRequest *types.PaginatorRequest) ([]types.Bot, *types.PaginatorResponse, error)
 	RRTake() (*types.Bot, error)
+	//SetUpdateOffset stores the ID of the next Telegram update to fetch in polling mode
+	SetUpdateOffset(botID int64, offset int64) error
+	//SetTokenRevoked flags a bot whose token Telegram has rejected, round-robin skips it until it is validated again
+	SetTokenRevoked(botID int64) error
 }
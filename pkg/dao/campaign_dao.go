@@ -2,10 +2,29 @@ package dao
 
 import "github.com/corporateanon/barker/pkg/types"
 
+// CampaignDao keeps campaigns and checks the transitions of their lifecycle states.
+// Only running campaigns are active, i.e. sent to users.
 type CampaignDao interface {
+	//Create makes a draft, or a running campaign if it is Active and the bot does not require approval
 	Create(campaign *types.Campaign) (*types.Campaign, error)
+	//Update replaces a campaign. The message of a running campaign cannot be changed unless it is paused.
 	Update(campaign *types.Campaign) (*types.Campaign, error)
+	SetState(botID int64, campaignID int64, state string) (*types.Campaign, error)
+	//Approve moves a campaign pending approval to approved and records the approver
+	Approve(botID int64, campaignID int64, approvedBy string) (*types.Campaign, error)
 	Get(botID int64, ID int64) (*types.Campaign, error)
+	//ListRevisions returns the revisions of a campaign, the oldest first
+	ListRevisions(botID int64, campaignID int64) ([]types.CampaignRevision, error)
+	//GetRevision returns nil if the campaign has no such revision
+	GetRevision(botID int64, campaignID int64, revision int64) (*types.CampaignRevision, error)
+	//DiffRevisions returns nil if the campaign lacks either revision
+	DiffRevisions(botID int64, campaignID int64, from int64, to int64) (*types.CampaignRevisionDiff, error)
+	//GetDeliveredRevision returns the revision a user has got, nil if the campaign has not been delivered to them
+	GetDeliveredRevision(botID int64, campaignID int64, telegramID int64) (*types.CampaignRevision, error)
 	List(botID int64, pageRequest *types.PaginatorRequest) ([]types.Campaign, *types.PaginatorResponse, error)
 	GetAggregatedStatistics(botID int64, campaignID int64) (*types.CampaignAggregatedStatistics, error)
+	//GetSourceStatistics breaks the statistics down by the first or the last deep-link source of users
+	GetSourceStatistics(botID int64, campaignID int64, touch string) ([]types.CampaignSourceStatistics, error)
+	//GetLanguageStatistics breaks the statistics down by the localization sent
+	GetLanguageStatistics(botID int64, campaignID int64) ([]types.CampaignLanguageStatistics, error)
 }
@@ -0,0 +1,24 @@
+package dao
+
+import "github.com/corporateanon/barker/pkg/types"
+
+// CampaignOperationDao changes messages of a campaign after they have been delivered.
+// An operation is split into message jobs, one per delivered message, which workers take and report
+// like deliveries.
+type CampaignOperationDao interface {
+	//Edit replaces the campaign message and queues editing of every delivered message
+	Edit(botID int64, campaignID int64, text string) (*types.CampaignOperation, error)
+	//Recall deactivates the campaign and queues deleting of every delivered message.
+	//Queued edits of the campaign are cancelled.
+	Recall(botID int64, campaignID int64) (*types.CampaignOperation, error)
+	//Test queues sending of the campaign to every tester of the bot. Test sends are message jobs,
+	//so testers still get the campaign when it is active and they do not count in its statistics.
+	Test(botID int64, campaignID int64) (*types.CampaignOperation, error)
+	Get(botID int64, campaignID int64, operationID int64) (*types.CampaignOperation, error)
+	List(botID int64, campaignID int64) ([]types.CampaignOperation, error)
+	//TakeJobs reserves up to size queued message jobs of a bot
+	TakeJobs(workerID string, botID int64, size int) ([]types.MessageJob, error)
+	SetJobStates(jobs []types.MessageJob) error
+	//ReleaseJobs returns message jobs which were taken but not processed back to the queue
+	ReleaseJobs(jobs []types.MessageJob) error
+}
@@ -0,0 +1,10 @@
+package dao
+
+import "github.com/corporateanon/barker/pkg/types"
+
+type ClickDao interface {
+	//Add records a click on a delivered message.
+	//Clicks of messages which have not been delivered and repeated callback queries are ignored,
+	//nil is returned for them.
+	Add(click *types.Click) (*types.Click, error)
+}
@@ -0,0 +1,9 @@
+package dao
+
+import "github.com/corporateanon/barker/pkg/types"
+
+type ConversionDao interface {
+	//Create stores a conversion event and attributes it to the most recent campaign
+	//delivered to the user within the bot's conversion window
+	Create(conversion *types.Conversion) (*types.Conversion, error)
+}
@@ -2,14 +2,37 @@ package dao
 
 import "github.com/corporateanon/barker/pkg/types"
 
+// MaxTakeBatchSize limits the number of deliveries reserved by a single TakeBatch call
+const MaxTakeBatchSize = 1000
+
 type DeliveryTakeResult struct {
 	Delivery *types.Delivery `json:"Delivery,omitempty"`
 	Campaign *types.Campaign `json:"Campaign,omitempty"`
 	User     *types.User     `json:"User,omitempty"`
+	Bot      *types.Bot      `json:"Bot,omitempty"`
+	//Tracked links of the delivery, if the campaign tracks links
+	Links []types.TrackedLink `json:"Links,omitempty"`
+	//Photo of the campaign: the file_id cached for the bot, or the asset to upload if there is none yet
+	FileID string            `json:"FileID,omitempty"`
+	Media  *types.MediaAsset `json:"Media,omitempty"`
 }
 
 type DeliveryDao interface {
 	Take(botID int64, campaignID int64, telegramID int64) (*DeliveryTakeResult, error)
+	//TakeForWorker is the same as Take, but the delivery is attributed to a worker,
+	//so it can be released when the worker stops sending heartbeats
+	TakeForWorker(workerID string, botID int64, campaignID int64, telegramID int64) (*DeliveryTakeResult, error)
+	//NextJob picks the bot which was served least recently among the bots with pending work
+	//and takes a delivery for it. The result carries the bot, so a worker can send a message
+	//right away.
+	NextJob(workerID string) (*DeliveryTakeResult, error)
+	//TakeBatch reserves up to size deliveries of a bot at once
+	TakeBatch(workerID string, botID int64, size int) ([]DeliveryTakeResult, error)
+	//SetState stores the state of a delivery with the reason, message and poll IDs it carries
 	SetState(*types.Delivery, types.DeliveryState) error
+	//SetStates stores states, reasons, message and poll IDs of many deliveries at once
+	SetStates(deliveries []types.Delivery) error
 	GetState(*types.Delivery) (types.DeliveryState, error)
+	//Release returns deliveries which were taken but not sent back to the queue
+	Release(deliveries []types.Delivery) error
 }
@@ -0,0 +1,11 @@
+package dao
+
+// ConflictError is returned when a request does not fit the current state of the data,
+// e.g. a test send of a campaign for a bot without testers
+type ConflictError struct {
+	Message string
+}
+
+func (err *ConflictError) Error() string {
+	return err.Message
+}
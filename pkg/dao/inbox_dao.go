@@ -0,0 +1,20 @@
+package dao
+
+import "github.com/corporateanon/barker/pkg/types"
+
+// InboxDao keeps conversations of bots with their users
+type InboxDao interface {
+	//Receive stores an incoming message and links it to the last campaign delivered to the user.
+	//A message which has already been received is ignored, nil is returned for it.
+	Receive(message *types.InboxMessage) (*types.InboxMessage, error)
+	//Reply queues a message job which sends the text to the user and stores it as an outgoing message.
+	//Nil is returned when the user does not exist.
+	Reply(botID int64, telegramID int64, text string) (*types.InboxMessage, error)
+	//AutoReply is Reply for an automatic response to an incoming message. It is queued once per incoming message,
+	//a repeated call returns the reply queued before, so that a redelivered update is not answered twice.
+	AutoReply(botID int64, telegramID int64, replyTo int64, text string) (*types.InboxMessage, error)
+	//List returns conversations of a bot, the most recently active first
+	List(botID int64, pageRequest *types.PaginatorRequest) ([]types.Conversation, *types.PaginatorResponse, error)
+	//Conversation returns messages exchanged with a user, newest first
+	Conversation(botID int64, telegramID int64, pageRequest *types.PaginatorRequest) ([]types.InboxMessage, *types.PaginatorResponse, error)
+}
@@ -0,0 +1,8 @@
+package dao
+
+import "github.com/corporateanon/barker/pkg/types"
+
+type LinkDao interface {
+	//Open records a click of a tracked link and returns the link, nil if the code is unknown
+	Open(code string) (*types.TrackedLink, error)
+}
@@ -0,0 +1,14 @@
+package dao
+
+import "github.com/corporateanon/barker/pkg/types"
+
+type MediaDao interface {
+	//Create stores an asset with its content
+	Create(asset *types.MediaAsset, content []byte) (*types.MediaAsset, error)
+	Get(mediaID int64) (*types.MediaAsset, error)
+	//GetContent returns the uploaded file, nil if there is no such asset
+	GetContent(mediaID int64) ([]byte, error)
+	List(pageRequest *types.PaginatorRequest) ([]types.MediaAsset, *types.PaginatorResponse, error)
+	//SetFileID caches the file_id Telegram has assigned to the asset for a bot
+	SetFileID(botID int64, mediaID int64, fileID string) error
+}
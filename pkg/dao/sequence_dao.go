@@ -0,0 +1,17 @@
+package dao
+
+import "github.com/corporateanon/barker/pkg/types"
+
+// SequenceDao keeps message sequences of bots and enrolls users in them
+type SequenceDao interface {
+	Create(sequence *types.Sequence) (*types.Sequence, error)
+	Update(sequence *types.Sequence) (*types.Sequence, error)
+	Get(botID int64, sequenceID int64) (*types.Sequence, error)
+	//Delete removes a sequence and cancels its steps which are not sent yet
+	Delete(botID int64, sequenceID int64) error
+	List(botID int64) ([]types.Sequence, error)
+	//Enroll queues the steps of a sequence for a user, each one after its delay.
+	//A user is enrolled in a sequence once, a repeated call does nothing, as well as a call for a blocked or unsubscribed user.
+	//Nil is returned when the sequence or the user does not exist.
+	Enroll(botID int64, sequenceID int64, telegramID int64) (*types.Sequence, error)
+}
@@ -0,0 +1,12 @@
+package dao
+
+import "github.com/corporateanon/barker/pkg/types"
+
+// TesterDao keeps the users of bots who get test sends of campaigns
+type TesterDao interface {
+	//Add makes a user of a bot a tester, adding a tester again changes nothing.
+	//Nil is returned when the user does not exist.
+	Add(botID int64, telegramID int64) (*types.Tester, error)
+	Remove(botID int64, telegramID int64) error
+	List(botID int64) ([]types.Tester, error)
+}
@@ -6,4 +6,17 @@ type UserDao interface {
 	Put(user *types.User) (*types.User, error)
 	Get(botID int64, telegramID int64) (*types.User, error)
 	List(botID int64, pageRequest *types.PaginatorRequest) ([]types.User, *types.PaginatorResponse, error)
+	//SetBlocked marks a user who has blocked or unblocked the bot.
+	//Blocked users are removed from the recipient queues.
+	SetBlocked(botID int64, telegramID int64, blocked bool) (*types.User, error)
+	//SetUnsubscribed marks a user who has asked to get no campaigns.
+	//Like blocked users, unsubscribed ones are removed from the recipient queues.
+	SetUnsubscribed(botID int64, telegramID int64, unsubscribed bool) (*types.User, error)
+	//Migrate moves a group which has been migrated to a supergroup to the new chat ID,
+	//along with its deliveries and everything else referring to it. nil is returned if there is no such group.
+	Migrate(botID int64, telegramID int64, newTelegramID int64) (*types.User, error)
+	//AddTag tags a user, nil is returned if there is no such user
+	AddTag(botID int64, telegramID int64, tag string) (*types.User, error)
+	//Sources counts users by their first and last deep-link sources, ordered by source
+	Sources(botID int64) ([]types.SourceUsers, error)
 }
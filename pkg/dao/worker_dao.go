@@ -0,0 +1,20 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/corporateanon/barker/pkg/types"
+)
+
+// WorkerTimeout is how long a worker may stay silent before it is considered dead
+const WorkerTimeout = time.Minute
+
+type WorkerDao interface {
+	Register(worker *types.Worker) (*types.Worker, error)
+	Heartbeat(worker *types.Worker) (*types.Worker, error)
+	Get(ID string) (*types.Worker, error)
+	List() ([]types.Worker, error)
+	//ReleaseExpired unregisters workers which have not sent a heartbeat within timeout
+	//and returns their in-progress deliveries back to the queue
+	ReleaseExpired(timeout time.Duration) ([]types.Worker, error)
+}
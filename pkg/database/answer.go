@@ -0,0 +1,29 @@
+package database
+
+import (
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+type Answer struct {
+	gorm.Model
+	BotID       int64
+	CampaignID  int64 `gorm:"uniqueIndex:idx_answers_campaign_tg"`
+	TelegramID  int64 `gorm:"uniqueIndex:idx_answers_campaign_tg"`
+	OptionIndex int
+}
+
+func (model *Answer) ToEntity(entity *types.Answer) {
+	entity.BotID = model.BotID
+	entity.CampaignID = model.CampaignID
+	entity.TelegramID = model.TelegramID
+	entity.Option = model.OptionIndex
+	entity.AnsweredAt = model.UpdatedAt
+}
+
+func (model *Answer) FromEntity(entity *types.Answer) {
+	model.BotID = entity.BotID
+	model.CampaignID = entity.CampaignID
+	model.TelegramID = entity.TelegramID
+	model.OptionIndex = entity.Option
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+type AutoResponderRule struct {
+	gorm.Model
+	ID               int64
+	BotID            int64 `gorm:"index"`
+	Match            string
+	Pattern          string
+	Reply            string
+	Priority         int
+	AddTag           string
+	Unsubscribe      bool `gorm:"not null;default:false"`
+	EnrollSequenceID int64
+}
+
+func (model *AutoResponderRule) ToEntity(entity *types.AutoResponderRule) {
+	entity.ID = model.ID
+	entity.BotID = model.BotID
+	entity.Match = model.Match
+	entity.Pattern = model.Pattern
+	entity.Reply = model.Reply
+	entity.Priority = model.Priority
+	entity.AddTag = model.AddTag
+	entity.Unsubscribe = model.Unsubscribe
+	entity.EnrollSequenceID = model.EnrollSequenceID
+	entity.CreatedAt = model.CreatedAt
+}
+
+func (model *AutoResponderRule) FromEntity(entity *types.AutoResponderRule) {
+	model.ID = entity.ID
+	model.BotID = entity.BotID
+	model.Match = entity.Match
+	model.Pattern = entity.Pattern
+	model.Reply = entity.Reply
+	model.Priority = entity.Priority
+	model.AddTag = entity.AddTag
+	model.Unsubscribe = entity.Unsubscribe
+	model.EnrollSequenceID = entity.EnrollSequenceID
+}
@@ -14,6 +14,20 @@ type Bot struct {
 	Token           string    `gorm:"uniqueIndex"`
 	RRAccessTime    time.Time `gorm:"index"`
 	RRPossiblyEmpty bool      `gorm:"index"`
+	WebhookSecret   string
+	IngestionMode   string
+	UpdateOffset    int64
+
+	TelegramID              int64 `gorm:"index"`
+	TelegramUserName        string
+	CanJoinGroups           bool
+	CanReadAllGroupMessages bool
+	SupportsInlineQueries   bool
+	TokenRevoked            bool
+	ValidatedAt             time.Time
+
+	ConversionWindowHours int
+	RequireApproval       bool
 }
 
 func (model *Bot) ToEntity(entity *types.Bot) {
@@ -22,6 +36,18 @@ func (model *Bot) ToEntity(entity *types.Bot) {
 	entity.Token = model.Token
 	entity.RRAccessTime = model.RRAccessTime
 	entity.RRPossiblyEmpty = model.RRPossiblyEmpty
+	entity.WebhookSecret = model.WebhookSecret
+	entity.IngestionMode = model.IngestionMode
+	entity.UpdateOffset = model.UpdateOffset
+	entity.TelegramID = model.TelegramID
+	entity.TelegramUserName = model.TelegramUserName
+	entity.CanJoinGroups = model.CanJoinGroups
+	entity.CanReadAllGroupMessages = model.CanReadAllGroupMessages
+	entity.SupportsInlineQueries = model.SupportsInlineQueries
+	entity.TokenRevoked = model.TokenRevoked
+	entity.ValidatedAt = model.ValidatedAt
+	entity.ConversionWindowHours = model.ConversionWindowHours
+	entity.RequireApproval = model.RequireApproval
 }
 
 func (model *Bot) FromEntity(entity *types.Bot) {
@@ -30,4 +56,15 @@ func (model *Bot) FromEntity(entity *types.Bot) {
 	model.Token = entity.Token
 	model.RRAccessTime = entity.RRAccessTime
 	model.RRPossiblyEmpty = entity.RRPossiblyEmpty
+	model.WebhookSecret = entity.WebhookSecret
+	model.IngestionMode = entity.IngestionMode
+	model.TelegramID = entity.TelegramID
+	model.TelegramUserName = entity.TelegramUserName
+	model.CanJoinGroups = entity.CanJoinGroups
+	model.CanReadAllGroupMessages = entity.CanReadAllGroupMessages
+	model.SupportsInlineQueries = entity.SupportsInlineQueries
+	model.TokenRevoked = entity.TokenRevoked
+	model.ValidatedAt = entity.ValidatedAt
+	model.ConversionWindowHours = entity.ConversionWindowHours
+	model.RequireApproval = entity.RequireApproval
 }
@@ -1,31 +1,108 @@
 package database
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/corporateanon/barker/pkg/types"
 	"gorm.io/gorm"
 )
 
 type Campaign struct {
 	gorm.Model
-	ID      int64
-	BotID   int64 `gorm:"index"`
-	Title   string
-	Message string
-	Active  bool `gorm:"index"`
+	ID        int64
+	BotID     int64 `gorm:"index"`
+	Title     string
+	Message   string
+	ParseMode string `gorm:"size:16"`
+	Active    bool   `gorm:"index"`
+	//Empty for campaigns created before lifecycle states, see ToEntity
+	State      string `gorm:"size:32"`
+	ApprovedBy string
+	ApprovedAt time.Time
+	//Number of the latest CampaignRevision, 0 for campaigns created before revisions
+	Revision   int64
+	TrackLinks bool
+	//JSON-encoded []types.CampaignButton
+	Buttons string
+	Kind    string
+	//JSON-encoded []string
+	Options     string
+	FirstSource string
+	LastSource  string
+	ChatType    string `gorm:"size:16"`
+	//JSON-encoded []types.CampaignLocalization
+	Localizations string
+	MediaID       int64
 }
 
 func (model *Campaign) ToEntity(entity *types.Campaign) {
 	entity.ID = model.ID
 	entity.Active = model.Active
+	entity.State = model.State
+	if entity.State == "" {
+		entity.State = types.CampaignStateDraft
+		if model.Active {
+			entity.State = types.CampaignStateRunning
+		}
+	}
+	entity.ApprovedBy = model.ApprovedBy
+	entity.ApprovedAt = model.ApprovedAt
+	entity.Revision = model.Revision
 	entity.BotID = model.BotID
 	entity.Message = model.Message
+	entity.ParseMode = model.ParseMode
 	entity.Title = model.Title
+	entity.TrackLinks = model.TrackLinks
+	entity.Buttons = nil
+	if model.Buttons != "" {
+		json.Unmarshal([]byte(model.Buttons), &entity.Buttons)
+	}
+	entity.Kind = model.Kind
+	entity.Options = nil
+	if model.Options != "" {
+		json.Unmarshal([]byte(model.Options), &entity.Options)
+	}
+	entity.FirstSource = model.FirstSource
+	entity.LastSource = model.LastSource
+	entity.ChatType = model.ChatType
+	entity.MediaID = model.MediaID
+	entity.Localizations = nil
+	if model.Localizations != "" {
+		json.Unmarshal([]byte(model.Localizations), &entity.Localizations)
+	}
 }
 
 func (model *Campaign) FromEntity(entity *types.Campaign) {
 	model.ID = entity.ID
 	model.Active = entity.Active
+	model.State = entity.State
+	model.ApprovedBy = entity.ApprovedBy
+	model.ApprovedAt = entity.ApprovedAt
+	model.Revision = entity.Revision
 	model.BotID = entity.BotID
 	model.Message = entity.Message
+	model.ParseMode = entity.ParseMode
 	model.Title = entity.Title
+	model.TrackLinks = entity.TrackLinks
+	model.Buttons = ""
+	if len(entity.Buttons) > 0 {
+		buttons, _ := json.Marshal(entity.Buttons)
+		model.Buttons = string(buttons)
+	}
+	model.Kind = entity.Kind
+	model.Options = ""
+	if len(entity.Options) > 0 {
+		options, _ := json.Marshal(entity.Options)
+		model.Options = string(options)
+	}
+	model.FirstSource = entity.FirstSource
+	model.LastSource = entity.LastSource
+	model.ChatType = entity.ChatType
+	model.MediaID = entity.MediaID
+	model.Localizations = ""
+	if len(entity.Localizations) > 0 {
+		localizations, _ := json.Marshal(entity.Localizations)
+		model.Localizations = string(localizations)
+	}
 }
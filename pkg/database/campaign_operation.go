@@ -0,0 +1,85 @@
+package database
+
+import (
+	"time"
+
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+type CampaignOperation struct {
+	gorm.Model
+	ID         int64
+	BotID      int64
+	CampaignID int64 `gorm:"index"`
+	Kind       string
+	Text       string
+	Revision   int64
+}
+
+func (model *CampaignOperation) ToEntity(entity *types.CampaignOperation) {
+	entity.ID = model.ID
+	entity.BotID = model.BotID
+	entity.CampaignID = model.CampaignID
+	entity.Kind = model.Kind
+	entity.Text = model.Text
+	entity.Revision = model.Revision
+	entity.CreatedAt = model.CreatedAt
+}
+
+func (model *CampaignOperation) FromEntity(entity *types.CampaignOperation) {
+	model.ID = entity.ID
+	model.BotID = entity.BotID
+	model.CampaignID = entity.CampaignID
+	model.Kind = entity.Kind
+	model.Text = entity.Text
+	model.Revision = entity.Revision
+}
+
+type MessageJob struct {
+	gorm.Model
+	ID          int64 `gorm:"index:idx_message_jobs_take,priority:3"`
+	OperationID int64 `gorm:"index"`
+	BotID       int64 `gorm:"index:idx_message_jobs_take,priority:1"`
+	CampaignID  int64 `gorm:"index"`
+	SequenceID  int64 `gorm:"index"`
+	TelegramID  int64
+	MessageID   int64
+	Kind        string
+	Text        string
+	State       types.MessageJobState `gorm:"index:idx_message_jobs_take,priority:2"`
+	Reason      string
+	WorkerID    string `gorm:"index"`
+	//A job is not taken before this time, steps of sequences wait for their delay
+	SendAfter time.Time
+}
+
+func (model *MessageJob) ToEntity(entity *types.MessageJob) {
+	entity.ID = model.ID
+	entity.OperationID = model.OperationID
+	entity.BotID = model.BotID
+	entity.CampaignID = model.CampaignID
+	entity.SequenceID = model.SequenceID
+	entity.TelegramID = model.TelegramID
+	entity.MessageID = model.MessageID
+	entity.Kind = model.Kind
+	entity.Text = model.Text
+	entity.State = model.State
+	entity.Reason = model.Reason
+	entity.WorkerID = model.WorkerID
+}
+
+func (model *MessageJob) FromEntity(entity *types.MessageJob) {
+	model.ID = entity.ID
+	model.OperationID = entity.OperationID
+	model.BotID = entity.BotID
+	model.CampaignID = entity.CampaignID
+	model.SequenceID = entity.SequenceID
+	model.TelegramID = entity.TelegramID
+	model.MessageID = entity.MessageID
+	model.Kind = entity.Kind
+	model.Text = entity.Text
+	model.State = entity.State
+	model.Reason = entity.Reason
+	model.WorkerID = entity.WorkerID
+}
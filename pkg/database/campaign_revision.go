@@ -0,0 +1,69 @@
+package database
+
+import (
+	"encoding/json"
+
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+type CampaignRevision struct {
+	gorm.Model
+	ID         int64
+	BotID      int64
+	CampaignID int64 `gorm:"uniqueIndex:idx_campaign_revisions_campaign"`
+	Revision   int64 `gorm:"uniqueIndex:idx_campaign_revisions_campaign"`
+	Title      string
+	Message    string
+	ParseMode  string `gorm:"size:16"`
+	TrackLinks bool
+	//JSON-encoded []types.CampaignButton
+	Buttons string
+	Kind    string
+	//JSON-encoded []string
+	Options string
+	//JSON-encoded []types.CampaignLocalization
+	Localizations string
+	MediaID       int64
+}
+
+func (model *CampaignRevision) ToEntity(entity *types.CampaignRevision) {
+	entity.CampaignID = model.CampaignID
+	entity.BotID = model.BotID
+	entity.Revision = model.Revision
+	entity.Title = model.Title
+	entity.Message = model.Message
+	entity.ParseMode = model.ParseMode
+	entity.TrackLinks = model.TrackLinks
+	entity.Buttons = nil
+	if model.Buttons != "" {
+		json.Unmarshal([]byte(model.Buttons), &entity.Buttons)
+	}
+	entity.Kind = model.Kind
+	entity.Options = nil
+	if model.Options != "" {
+		json.Unmarshal([]byte(model.Options), &entity.Options)
+	}
+	entity.Localizations = nil
+	if model.Localizations != "" {
+		json.Unmarshal([]byte(model.Localizations), &entity.Localizations)
+	}
+	entity.MediaID = model.MediaID
+	entity.CreatedAt = model.CreatedAt
+}
+
+// FromCampaign copies the title and the message of the current revision of a campaign
+func (model *CampaignRevision) FromCampaign(campaign *Campaign) {
+	model.CampaignID = campaign.ID
+	model.BotID = campaign.BotID
+	model.Revision = campaign.Revision
+	model.Title = campaign.Title
+	model.Message = campaign.Message
+	model.ParseMode = campaign.ParseMode
+	model.TrackLinks = campaign.TrackLinks
+	model.Buttons = campaign.Buttons
+	model.Kind = campaign.Kind
+	model.Options = campaign.Options
+	model.Localizations = campaign.Localizations
+	model.MediaID = campaign.MediaID
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+type Click struct {
+	gorm.Model
+	BotID           int64
+	CampaignID      int64 `gorm:"index"`
+	TelegramID      int64
+	Variant         int
+	Button          int
+	CallbackQueryID string `gorm:"uniqueIndex"`
+}
+
+func (model *Click) ToEntity(entity *types.Click) {
+	entity.BotID = model.BotID
+	entity.CampaignID = model.CampaignID
+	entity.TelegramID = model.TelegramID
+	entity.Variant = model.Variant
+	entity.Button = model.Button
+	entity.CallbackQueryID = model.CallbackQueryID
+	entity.CreatedAt = model.CreatedAt
+}
+
+func (model *Click) FromEntity(entity *types.Click) {
+	model.BotID = entity.BotID
+	model.CampaignID = entity.CampaignID
+	model.TelegramID = entity.TelegramID
+	model.Variant = entity.Variant
+	model.Button = entity.Button
+	model.CallbackQueryID = entity.CallbackQueryID
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+type Conversion struct {
+	gorm.Model
+	ID         int64
+	BotID      int64 `gorm:"index"`
+	TelegramID int64
+	Event      string
+	Value      float64
+	CampaignID int64 `gorm:"index"`
+}
+
+func (model *Conversion) ToEntity(entity *types.Conversion) {
+	entity.ID = model.ID
+	entity.BotID = model.BotID
+	entity.TelegramID = model.TelegramID
+	entity.Event = model.Event
+	entity.Value = model.Value
+	entity.CampaignID = model.CampaignID
+	entity.CreatedAt = model.CreatedAt
+}
+
+func (model *Conversion) FromEntity(entity *types.Conversion) {
+	model.ID = entity.ID
+	model.BotID = entity.BotID
+	model.TelegramID = entity.TelegramID
+	model.Event = entity.Event
+	model.Value = entity.Value
+	model.CampaignID = entity.CampaignID
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"github.com/corporateanon/barker/pkg/types"
 	"gorm.io/gorm"
 )
 
@@ -10,8 +11,48 @@ func NewDatabase(dialector gorm.Dialector) (*gorm.DB, error) {
 		return nil, err
 	}
 	db.AutoMigrate(&User{})
+	db.AutoMigrate(&UserTag{})
 	db.AutoMigrate(&Campaign{})
+	deliveredAtExists := db.Migrator().HasColumn(&Delivery{}, "DeliveredAt")
 	db.AutoMigrate(&Delivery{})
+	if !deliveredAtExists {
+		if err := fillDeliveredAt(db); err != nil {
+			return nil, err
+		}
+	}
 	db.AutoMigrate(&Bot{})
+	db.AutoMigrate(&Worker{})
+	db.AutoMigrate(&CampaignOperation{})
+	db.AutoMigrate(&MessageJob{})
+	db.AutoMigrate(&Click{})
+	db.AutoMigrate(&TrackedLink{})
+	db.AutoMigrate(&LinkClick{})
+	db.AutoMigrate(&Conversion{})
+	db.AutoMigrate(&Answer{})
+	db.AutoMigrate(&InboxMessage{})
+	db.AutoMigrate(&Conversation{})
+	db.AutoMigrate(&AutoResponderRule{})
+	db.AutoMigrate(&MediaAsset{})
+	db.AutoMigrate(&MediaFile{})
+	db.AutoMigrate(&Tester{})
+	db.AutoMigrate(&CampaignRevision{})
+	db.AutoMigrate(&Sequence{})
+	db.AutoMigrate(&SequenceEnrollment{})
+	queueExists := db.Migrator().HasTable(&QueuedRecipient{})
+	db.AutoMigrate(&QueuedRecipient{})
+	if !queueExists {
+		if err := fillRecipientQueue(db); err != nil {
+			return nil, err
+		}
+	}
 	return db.Debug(), nil
 }
+
+// fillDeliveredAt sets the delivery time of existing successful deliveries to their last update,
+// which is the best guess left. It is used once, when the column is added.
+func fillDeliveredAt(db *gorm.DB) error {
+	return db.Exec(
+		"UPDATE deliveries SET delivered_at = updated_at WHERE state = ?",
+		types.DeliveryStateSuccess,
+	).Error
+}
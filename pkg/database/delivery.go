@@ -1,6 +1,8 @@
 package database
 
 import (
+	"time"
+
 	"github.com/corporateanon/barker/pkg/types"
 	"gorm.io/gorm"
 )
@@ -11,6 +13,14 @@ type Delivery struct {
 	BotID      int64               `gorm:"uniqueIndex:idx_campaign_bot_tg"`
 	TelegramID int64               `gorm:"uniqueIndex:idx_campaign_bot_tg"`
 	State      types.DeliveryState `gorm:"index"`
+	WorkerID   string              `gorm:"index"`
+	Reason     string
+	MessageID  int64
+	PollID     string `gorm:"index"`
+	Language   string `gorm:"size:16"`
+	Revision   int64
+	//When the delivery has become successful
+	DeliveredAt time.Time `gorm:"index"`
 }
 
 func (model *Delivery) ToEntity(entity *types.Delivery) {
@@ -18,6 +28,12 @@ func (model *Delivery) ToEntity(entity *types.Delivery) {
 	entity.CampaignID = model.CampaignID
 	entity.State = model.State
 	entity.TelegramID = model.TelegramID
+	entity.WorkerID = model.WorkerID
+	entity.Reason = model.Reason
+	entity.MessageID = model.MessageID
+	entity.PollID = model.PollID
+	entity.Language = model.Language
+	entity.Revision = model.Revision
 }
 
 func (model *Delivery) FromEntity(entity *types.Delivery) {
@@ -25,4 +41,10 @@ func (model *Delivery) FromEntity(entity *types.Delivery) {
 	model.CampaignID = entity.CampaignID
 	model.State = entity.State
 	model.TelegramID = entity.TelegramID
+	model.WorkerID = entity.WorkerID
+	model.Reason = entity.Reason
+	model.MessageID = entity.MessageID
+	model.PollID = entity.PollID
+	model.Language = entity.Language
+	model.Revision = entity.Revision
 }
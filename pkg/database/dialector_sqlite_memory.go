@@ -9,6 +9,10 @@ func NewDialectorSQLiteMemoryClient() gorm.Dialector {
 	return sqlite.Open("file:client.db?mode=memory&cache=shared")
 }
 
+func NewDialectorSQLiteMemoryJoinSelection() gorm.Dialector {
+	return sqlite.Open("file:joinSelection.db?mode=memory&cache=shared")
+}
+
 func NewDialectorSQLiteMemoryServer() gorm.Dialector {
 	return sqlite.Open("file:server.db?mode=memory&cache=shared")
 }
@@ -16,3 +20,23 @@ func NewDialectorSQLiteMemoryServer() gorm.Dialector {
 func NewDialectorSQLiteMemoryRoundRobin() gorm.Dialector {
 	return sqlite.Open("file:roundRobin.db?mode=memory&cache=shared")
 }
+
+func NewDialectorSQLiteMemoryWorkers() gorm.Dialector {
+	return sqlite.Open("file:workers.db?mode=memory&cache=shared")
+}
+
+func NewDialectorSQLiteMemoryLongPolling() gorm.Dialector {
+	return sqlite.Open("file:longPolling.db?mode=memory&cache=shared")
+}
+
+func NewDialectorSQLiteMemorySender() gorm.Dialector {
+	return sqlite.Open("file:sender.db?mode=memory&cache=shared")
+}
+
+func NewDialectorSQLiteMemoryWebhook() gorm.Dialector {
+	return sqlite.Open("file:webhook.db?mode=memory&cache=shared")
+}
+
+func NewDialectorSQLiteMemoryPolling() gorm.Dialector {
+	return sqlite.Open("file:polling.db?mode=memory&cache=shared")
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"time"
+
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+type InboxMessage struct {
+	gorm.Model
+	ID int64
+	//Incoming messages are unique by Telegram message ID, outgoing ones by job ID
+	BotID      int64 `gorm:"uniqueIndex:idx_inbox_messages_message,priority:1;index:idx_inbox_messages_conversation,priority:1"`
+	TelegramID int64 `gorm:"uniqueIndex:idx_inbox_messages_message,priority:2;index:idx_inbox_messages_conversation,priority:2"`
+	MessageID  int64 `gorm:"uniqueIndex:idx_inbox_messages_message,priority:3"`
+	JobID      int64 `gorm:"uniqueIndex:idx_inbox_messages_message,priority:4"`
+	Direction  string
+	Text       string
+	CampaignID int64 `gorm:"index"`
+	ReplyTo    int64 `gorm:"index"`
+}
+
+func (model *InboxMessage) ToEntity(entity *types.InboxMessage) {
+	entity.ID = model.ID
+	entity.BotID = model.BotID
+	entity.TelegramID = model.TelegramID
+	entity.MessageID = model.MessageID
+	entity.JobID = model.JobID
+	entity.Direction = model.Direction
+	entity.Text = model.Text
+	entity.CampaignID = model.CampaignID
+	entity.ReplyTo = model.ReplyTo
+	entity.CreatedAt = model.CreatedAt
+}
+
+func (model *InboxMessage) FromEntity(entity *types.InboxMessage) {
+	model.ID = entity.ID
+	model.BotID = entity.BotID
+	model.TelegramID = entity.TelegramID
+	model.MessageID = entity.MessageID
+	model.JobID = entity.JobID
+	model.Direction = entity.Direction
+	model.Text = entity.Text
+	model.CampaignID = entity.CampaignID
+	model.ReplyTo = entity.ReplyTo
+}
+
+// Conversation is updated with every inbox message, so the inbox is paged without grouping messages
+type Conversation struct {
+	gorm.Model
+	BotID         int64 `gorm:"uniqueIndex:idx_conversations_user,priority:1;index:idx_conversations_inbox,priority:1"`
+	TelegramID    int64 `gorm:"uniqueIndex:idx_conversations_user,priority:2"`
+	Messages      int64
+	LastMessageID int64
+	LastMessageAt time.Time `gorm:"index:idx_conversations_inbox,priority:2"`
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+type TrackedLink struct {
+	gorm.Model
+	Code       string `gorm:"uniqueIndex"`
+	BotID      int64
+	CampaignID int64 `gorm:"index"`
+	TelegramID int64
+	URL        string
+}
+
+func (model *TrackedLink) ToEntity(entity *types.TrackedLink) {
+	entity.Code = model.Code
+	entity.BotID = model.BotID
+	entity.CampaignID = model.CampaignID
+	entity.TelegramID = model.TelegramID
+	entity.URL = model.URL
+}
+
+func (model *TrackedLink) FromEntity(entity *types.TrackedLink) {
+	model.Code = entity.Code
+	model.BotID = entity.BotID
+	model.CampaignID = entity.CampaignID
+	model.TelegramID = entity.TelegramID
+	model.URL = entity.URL
+}
+
+// LinkClick is an opening of a tracked link, CreatedAt is the time of the click
+type LinkClick struct {
+	gorm.Model
+	LinkID     uint `gorm:"index"`
+	BotID      int64
+	CampaignID int64 `gorm:"index"`
+	TelegramID int64
+}
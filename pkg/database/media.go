@@ -0,0 +1,38 @@
+package database
+
+import (
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+type MediaAsset struct {
+	gorm.Model
+	ID          int64
+	FileName    string
+	ContentType string
+	Size        int64
+	Content     []byte
+}
+
+// MediaFile is the file_id of a media asset for a bot
+type MediaFile struct {
+	gorm.Model
+	MediaID int64 `gorm:"uniqueIndex:idx_media_files_bot"`
+	BotID   int64 `gorm:"uniqueIndex:idx_media_files_bot"`
+	FileID  string
+}
+
+func (model *MediaAsset) ToEntity(entity *types.MediaAsset) {
+	entity.ID = model.ID
+	entity.FileName = model.FileName
+	entity.ContentType = model.ContentType
+	entity.Size = model.Size
+	entity.CreatedAt = model.CreatedAt
+}
+
+func (model *MediaAsset) FromEntity(entity *types.MediaAsset) {
+	model.ID = entity.ID
+	model.FileName = entity.FileName
+	model.ContentType = entity.ContentType
+	model.Size = entity.Size
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// QueuedRecipient is a recipient which is yet to get a campaign.
+// The queue is filled when a campaign becomes active or a user registers,
+// so that deliveries are taken by an indexed pop instead of a search
+// for users without deliveries.
+type QueuedRecipient struct {
+	ID         int64 `gorm:"primaryKey;index:idx_queued_recipients_pop,priority:3"`
+	CampaignID int64 `gorm:"uniqueIndex:idx_queued_recipients_campaign_bot_tg;index:idx_queued_recipients_pop,priority:2,sort:desc"`
+	BotID      int64 `gorm:"uniqueIndex:idx_queued_recipients_campaign_bot_tg;index:idx_queued_recipients_pop,priority:1"`
+	TelegramID int64 `gorm:"uniqueIndex:idx_queued_recipients_campaign_bot_tg"`
+	CreatedAt  time.Time
+}
+
+// fillRecipientQueue enqueues recipients of all active campaigns.
+// It is used once, when the queue table is created for an existing database.
+func fillRecipientQueue(db *gorm.DB) error {
+	return db.Exec(
+		"INSERT INTO queued_recipients (campaign_id, bot_id, telegram_id, created_at) "+
+			"SELECT campaigns.id, users.bot_id, users.telegram_id, ? "+
+			"FROM campaigns "+
+			"INNER JOIN users ON users.bot_id = campaigns.bot_id "+
+			"WHERE campaigns.active = true "+
+			"AND campaigns.deleted_at IS NULL "+
+			"AND users.deleted_at IS NULL "+
+			"AND NOT EXISTS (SELECT 1 FROM deliveries WHERE "+
+			"deliveries.campaign_id = campaigns.id "+
+			"AND deliveries.bot_id = users.bot_id "+
+			"AND deliveries.telegram_id = users.telegram_id) "+
+			"ORDER BY campaigns.id, users.id",
+		time.Now(),
+	).Error
+}
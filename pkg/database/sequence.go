@@ -0,0 +1,48 @@
+package database
+
+import (
+	"encoding/json"
+
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+type Sequence struct {
+	gorm.Model
+	ID    int64
+	BotID int64 `gorm:"index"`
+	Title string
+	Steps string
+}
+
+func (model *Sequence) ToEntity(entity *types.Sequence) {
+	entity.ID = model.ID
+	entity.BotID = model.BotID
+	entity.Title = model.Title
+	entity.Steps = nil
+	if model.Steps != "" {
+		json.Unmarshal([]byte(model.Steps), &entity.Steps)
+	}
+	entity.CreatedAt = model.CreatedAt
+}
+
+func (model *Sequence) FromEntity(entity *types.Sequence) {
+	model.ID = entity.ID
+	model.BotID = entity.BotID
+	model.Title = entity.Title
+	model.Steps = ""
+	if len(entity.Steps) > 0 {
+		steps, _ := json.Marshal(entity.Steps)
+		model.Steps = string(steps)
+	}
+}
+
+// SequenceEnrollment records that a user has been enrolled in a sequence,
+// so that the user gets the sequence once
+type SequenceEnrollment struct {
+	gorm.Model
+	ID         int64
+	SequenceID int64 `gorm:"uniqueIndex:idx_sequence_enrollments_sequence_bot_tg"`
+	BotID      int64 `gorm:"uniqueIndex:idx_sequence_enrollments_sequence_bot_tg"`
+	TelegramID int64 `gorm:"uniqueIndex:idx_sequence_enrollments_sequence_bot_tg"`
+}
@@ -0,0 +1,24 @@
+package database
+
+import (
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+type Tester struct {
+	gorm.Model
+	ID         int64
+	BotID      int64 `gorm:"uniqueIndex:idx_testers_bot"`
+	TelegramID int64 `gorm:"uniqueIndex:idx_testers_bot"`
+}
+
+func (model *Tester) ToEntity(entity *types.Tester) {
+	entity.BotID = model.BotID
+	entity.TelegramID = model.TelegramID
+	entity.CreatedAt = model.CreatedAt
+}
+
+func (model *Tester) FromEntity(entity *types.Tester) {
+	model.BotID = entity.BotID
+	model.TelegramID = entity.TelegramID
+}
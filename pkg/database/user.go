@@ -13,6 +13,20 @@ type User struct {
 	UserName    string
 	TelegramID  int64 `gorm:"uniqueIndex:idx_telegram_id_bot_id"`
 	BotID       int64 `gorm:"uniqueIndex:idx_telegram_id_bot_id"`
+	Blocked     bool  `gorm:"not null;default:false"`
+	//Unsubscribed users get no campaigns, like blocked ones
+	Unsubscribed bool   `gorm:"not null;default:false"`
+	FirstSource  string `gorm:"index;size:64"`
+	LastSource   string `gorm:"index;size:64"`
+	ChatType     string `gorm:"not null;default:private;size:16"`
+	LanguageCode string `gorm:"size:16"`
+}
+
+type UserTag struct {
+	gorm.Model
+	BotID      int64  `gorm:"uniqueIndex:idx_user_tags_tag,priority:1"`
+	TelegramID int64  `gorm:"uniqueIndex:idx_user_tags_tag,priority:2"`
+	Tag        string `gorm:"uniqueIndex:idx_user_tags_tag,priority:3;size:64"`
 }
 
 func (model *User) ToEntity(user *types.User) {
@@ -22,6 +36,12 @@ func (model *User) ToEntity(user *types.User) {
 	user.DisplayName = model.DisplayName
 	user.TelegramID = model.TelegramID
 	user.UserName = model.UserName
+	user.Blocked = model.Blocked
+	user.Unsubscribed = model.Unsubscribed
+	user.FirstSource = model.FirstSource
+	user.LastSource = model.LastSource
+	user.ChatType = model.ChatType
+	user.LanguageCode = model.LanguageCode
 }
 
 func (model *User) FromEntity(user *types.User) {
@@ -31,4 +51,10 @@ func (model *User) FromEntity(user *types.User) {
 	model.DisplayName = user.DisplayName
 	model.TelegramID = user.TelegramID
 	model.UserName = user.UserName
+	model.Blocked = user.Blocked
+	model.Unsubscribed = user.Unsubscribed
+	model.FirstSource = user.FirstSource
+	model.LastSource = user.LastSource
+	model.ChatType = user.ChatType
+	model.LanguageCode = user.LanguageCode
 }
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"time"
+
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+type Worker struct {
+	gorm.Model
+	WorkerID      string `gorm:"uniqueIndex"`
+	Version       string
+	Host          string
+	BotID         int64
+	Throughput    float64
+	LastHeartbeat time.Time `gorm:"index"`
+}
+
+func (model *Worker) ToEntity(entity *types.Worker) {
+	entity.ID = model.WorkerID
+	entity.Version = model.Version
+	entity.Host = model.Host
+	entity.BotID = model.BotID
+	entity.Throughput = model.Throughput
+	entity.LastHeartbeat = model.LastHeartbeat
+}
+
+func (model *Worker) FromEntity(entity *types.Worker) {
+	model.WorkerID = entity.ID
+	model.Version = entity.Version
+	model.Host = entity.Host
+	model.BotID = entity.BotID
+	model.Throughput = entity.Throughput
+	model.LastHeartbeat = entity.LastHeartbeat
+}
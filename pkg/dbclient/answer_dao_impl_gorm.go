@@ -0,0 +1,144 @@
+package dbclient
+
+import (
+	"errors"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/database"
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AnswerDaoImplGorm struct {
+	db          *gorm.DB
+	campaignDao dao.CampaignDao
+}
+
+func NewAnswerDaoImplGorm(db *gorm.DB, campaignDao dao.CampaignDao) dao.AnswerDao {
+	return &AnswerDaoImplGorm{
+		db:          db,
+		campaignDao: campaignDao,
+	}
+}
+
+func (dao *AnswerDaoImplGorm) Put(answer *types.Answer) (*types.Answer, error) {
+	delivery, err := dao.findDelivery(answer)
+	if err != nil || delivery == nil {
+		return nil, err
+	}
+	campaign, err := dao.campaignDao.Get(delivery.BotID, delivery.CampaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil || answer.Option >= len(campaign.Options) {
+		return nil, nil
+	}
+
+	answerModel := &database.Answer{}
+	answerModel.FromEntity(answer)
+	answerModel.CampaignID = delivery.CampaignID
+	if err := dao.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "campaign_id"}, {Name: "telegram_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"option_index", "updated_at"}),
+	}).Create(answerModel).Error; err != nil {
+		return nil, err
+	}
+
+	resultingAnswer := &types.Answer{}
+	answerModel.ToEntity(resultingAnswer)
+	return resultingAnswer, nil
+}
+
+func (dao *AnswerDaoImplGorm) Retract(answer *types.Answer) error {
+	delivery, err := dao.findDelivery(answer)
+	if err != nil || delivery == nil {
+		return err
+	}
+	//A soft-deleted answer would block the next one in the unique index
+	return dao.db.Unscoped().
+		Where("campaign_id = ? AND telegram_id = ?", delivery.CampaignID, delivery.TelegramID).
+		Delete(&database.Answer{}).Error
+}
+
+// findDelivery looks up the successful delivery the answer refers to by the campaign or the poll
+func (dao *AnswerDaoImplGorm) findDelivery(answer *types.Answer) (*database.Delivery, error) {
+	if answer.CampaignID == 0 && answer.PollID == "" {
+		return nil, errors.New("Campaign ID or poll ID missing")
+	}
+	query := dao.db.
+		Where("bot_id = ? AND telegram_id = ?", answer.BotID, answer.TelegramID).
+		Where("state = ?", types.DeliveryStateSuccess)
+	if answer.CampaignID != 0 {
+		query = query.Where("campaign_id = ?", answer.CampaignID)
+	}
+	if answer.PollID != "" {
+		query = query.Where("poll_id = ?", answer.PollID)
+	}
+	deliveryModel := &database.Delivery{}
+	if err := query.First(deliveryModel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return deliveryModel, nil
+}
+
+func (dao *AnswerDaoImplGorm) Results(botID int64, campaignID int64) (*types.SurveyResults, error) {
+	campaign, err := dao.campaignDao.Get(botID, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, errors.New("Campaign does not exist")
+	}
+
+	optionRows := []struct {
+		OptionIndex int
+		Answers     int64
+	}{}
+	if err := dao.db.Model(&database.Answer{}).
+		Select("option_index, COUNT(*) AS answers").
+		Where("campaign_id = ?", campaignID).
+		Group("option_index").
+		Scan(&optionRows).Error; err != nil {
+		return nil, err
+	}
+
+	results := &types.SurveyResults{
+		Options: make([]types.OptionResult, len(campaign.Options)),
+	}
+	for i, text := range campaign.Options {
+		results.Options[i] = types.OptionResult{Option: i, Text: text}
+	}
+	for _, row := range optionRows {
+		results.Answers += row.Answers
+		if row.OptionIndex < len(results.Options) {
+			results.Options[row.OptionIndex].Answers = row.Answers
+		}
+	}
+	if results.Answers > 0 {
+		for i := range results.Options {
+			results.Options[i].Share = float64(results.Options[i].Answers) / float64(results.Answers)
+		}
+	}
+	return results, nil
+}
+
+func (dao *AnswerDaoImplGorm) List(botID int64, campaignID int64) ([]types.Answer, error) {
+	answerModelsList := []database.Answer{}
+	if err := dao.db.
+		Where("bot_id = ? AND campaign_id = ?", botID, campaignID).
+		Order("updated_at ASC").
+		Order("id ASC").
+		Find(&answerModelsList).Error; err != nil {
+		return nil, err
+	}
+
+	answersList := make([]types.Answer, len(answerModelsList))
+	for i, model := range answerModelsList {
+		model.ToEntity(&answersList[i])
+	}
+	return answersList, nil
+}
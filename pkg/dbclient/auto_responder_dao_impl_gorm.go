@@ -0,0 +1,97 @@
+package dbclient
+
+import (
+	"errors"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/database"
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+type AutoResponderDaoImplGorm struct {
+	db *gorm.DB
+}
+
+func NewAutoResponderDaoImplGorm(db *gorm.DB) dao.AutoResponderDao {
+	return &AutoResponderDaoImplGorm{
+		db: db,
+	}
+}
+
+func (dao *AutoResponderDaoImplGorm) Create(rule *types.AutoResponderRule) (*types.AutoResponderRule, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	ruleModel := &database.AutoResponderRule{}
+	ruleModel.FromEntity(rule)
+	ruleModel.ID = 0
+	if err := dao.db.Create(ruleModel).Error; err != nil {
+		return nil, err
+	}
+	resultingRule := &types.AutoResponderRule{}
+	ruleModel.ToEntity(resultingRule)
+	return resultingRule, nil
+}
+
+func (dao *AutoResponderDaoImplGorm) Update(rule *types.AutoResponderRule) (*types.AutoResponderRule, error) {
+	if rule.ID == 0 {
+		return nil, errors.New("ID missing")
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	ruleModel := &database.AutoResponderRule{}
+	if err := dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("id = ? AND bot_id = ?", rule.ID, rule.BotID).
+			First(ruleModel).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("Rule does not exist")
+			}
+			return err
+		}
+		ruleModel.FromEntity(rule)
+		return tx.Save(ruleModel).Error
+	}); err != nil {
+		return nil, err
+	}
+	resultingRule := &types.AutoResponderRule{}
+	ruleModel.ToEntity(resultingRule)
+	return resultingRule, nil
+}
+
+func (dao *AutoResponderDaoImplGorm) Get(botID int64, ruleID int64) (*types.AutoResponderRule, error) {
+	ruleModel := &database.AutoResponderRule{}
+	if err := dao.db.Where("id = ? AND bot_id = ?", ruleID, botID).First(ruleModel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	rule := &types.AutoResponderRule{}
+	ruleModel.ToEntity(rule)
+	return rule, nil
+}
+
+func (dao *AutoResponderDaoImplGorm) Delete(botID int64, ruleID int64) error {
+	return dao.db.
+		Where("id = ? AND bot_id = ?", ruleID, botID).
+		Delete(&database.AutoResponderRule{}).Error
+}
+
+func (dao *AutoResponderDaoImplGorm) List(botID int64) ([]types.AutoResponderRule, error) {
+	ruleModelsList := []database.AutoResponderRule{}
+	if err := dao.db.
+		Where("bot_id = ?", botID).
+		Order("priority ASC").
+		Order("id ASC").
+		Find(&ruleModelsList).Error; err != nil {
+		return nil, err
+	}
+	rulesList := make([]types.AutoResponderRule, len(ruleModelsList))
+	for i, model := range ruleModelsList {
+		model.ToEntity(&rulesList[i])
+	}
+	return rulesList, nil
+}
@@ -47,7 +47,8 @@ func (dao *BotDaoImplGorm) Update(bot *types.Bot) (*types.Bot, error) {
 
 	botModel.FromEntity(bot)
 
-	if err := dao.db.Save(botModel).Error; err != nil {
+	//The offset is kept by the poller
+	if err := dao.db.Omit("update_offset").Save(botModel).Error; err != nil {
 		return nil, err
 	}
 	resultingBot := &types.Bot{}
@@ -121,6 +122,7 @@ func (dao *BotDaoImplGorm) RRTake() (*types.Bot, error) {
 	if err := dao.db.Transaction(func(tx *gorm.DB) error {
 		botModel := &database.Bot{}
 		if err := tx.Order("rr_access_time ASC").
+			Where("token_revoked = ?", false).
 			Where(
 				"rr_possibly_empty = ? OR rr_access_time < ?",
 				false,
@@ -147,3 +149,17 @@ func (dao *BotDaoImplGorm) RRTake() (*types.Bot, error) {
 
 	return bot, nil
 }
+
+func (dao *BotDaoImplGorm) SetUpdateOffset(botID int64, offset int64) error {
+	return dao.db.
+		Model(&database.Bot{}).
+		Where("id = ?", botID).
+		Update("update_offset", offset).Error
+}
+
+func (dao *BotDaoImplGorm) SetTokenRevoked(botID int64) error {
+	return dao.db.
+		Model(&database.Bot{}).
+		Where("id = ?", botID).
+		Update("token_revoked", true).Error
+}
@@ -2,6 +2,8 @@ package dbclient
 
 import (
 	"errors"
+	"sort"
+	"time"
 
 	"github.com/corporateanon/barker/pkg/dao"
 	"github.com/corporateanon/barker/pkg/database"
@@ -23,7 +25,31 @@ func NewCampaignDaoImplGorm(db *gorm.DB) dao.CampaignDao {
 func (dao *CampaignDaoImplGorm) Create(campaign *types.Campaign) (*types.Campaign, error) {
 	campaignModel := &database.Campaign{}
 	campaignModel.FromEntity(campaign)
-	if err := dao.db.Create(campaignModel).Error; err != nil {
+	if campaignModel.State == "" {
+		campaignModel.State = types.CampaignStateDraft
+		if campaign.Active {
+			campaignModel.State = types.CampaignStateRunning
+		}
+	}
+	campaignModel.Active = campaignModel.State == types.CampaignStateRunning
+	campaignModel.ApprovedBy = ""
+	campaignModel.ApprovedAt = time.Time{}
+	campaignModel.Revision = 1
+	if err := dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkCampaignTransition(tx, campaignModel.BotID, "", campaignModel.State); err != nil {
+			return err
+		}
+		if err := tx.Create(campaignModel).Error; err != nil {
+			return err
+		}
+		if err := recordCampaignRevision(tx, campaignModel); err != nil {
+			return err
+		}
+		if campaignModel.Active {
+			return enqueueCampaign(tx, campaignModel.ID, campaignModel.BotID)
+		}
+		return nil
+	}); err != nil {
 		return nil, err
 	}
 	resultingCampaign := &types.Campaign{}
@@ -37,15 +63,134 @@ func (dao *CampaignDaoImplGorm) Update(campaign *types.Campaign) (*types.Campaig
 	}
 	campaignModel := &database.Campaign{}
 
-	if err := dao.db.
-		Where("id = ? AND bot_id = ?", campaign.ID, campaign.BotID).
-		First(campaignModel).Error; err != nil {
+	if err := dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("id = ? AND bot_id = ?", campaign.ID, campaign.BotID).
+			First(campaignModel).Error; err != nil {
+			return err
+		}
+		if err := ensureCampaignRevision(tx, campaignModel); err != nil {
+			return err
+		}
+		current := &types.Campaign{}
+		campaignModel.ToEntity(current)
+		if current.State == types.CampaignStateArchived {
+			return conflict("Archived campaigns cannot be changed")
+		}
+
+		state := campaign.State
+		if state == "" || state == current.State {
+			//Clients which only know Active start and pause campaigns with it
+			state = current.State
+			if campaign.Active && !current.Active {
+				state = types.CampaignStateRunning
+			}
+			if !campaign.Active && current.Active {
+				state = types.CampaignStatePaused
+			}
+		}
+		if state != current.State {
+			if err := checkCampaignTransition(tx, current.BotID, current.State, state); err != nil {
+				return err
+			}
+		}
+
+		//The message may change along with pausing the campaign
+		messageChanged := campaignMessage(campaign) != campaignMessage(current)
+		if messageChanged && current.State == types.CampaignStateRunning && state == types.CampaignStateRunning {
+			return conflict("The message of a running campaign cannot be changed, pause it first")
+		}
+
+		campaignModel.FromEntity(campaign)
+		campaignModel.State = current.State
+		campaignModel.Active = current.Active
+		campaignModel.ApprovedBy = current.ApprovedBy
+		campaignModel.ApprovedAt = current.ApprovedAt
+		campaignModel.Revision = current.Revision
+		if campaignRevisionChanged(current, campaign) {
+			campaignModel.Revision++
+			if err := recordCampaignRevision(tx, campaignModel); err != nil {
+				return err
+			}
+		}
+		if messageChanged && state == current.State &&
+			(state == types.CampaignStatePendingApproval || state == types.CampaignStateApproved) {
+			//The approval does not cover the new message
+			state = types.CampaignStateDraft
+			campaignModel.ApprovedBy = ""
+			campaignModel.ApprovedAt = time.Time{}
+		}
+		if err := setCampaignState(tx, campaignModel, state); err != nil {
+			return err
+		}
+		//A running campaign which targets other users gets its recipient queue built anew
+		targetingChanged := campaign.FirstSource != current.FirstSource ||
+			campaign.LastSource != current.LastSource ||
+			campaign.ChatType != current.ChatType
+		if !targetingChanged || !current.Active || !campaignModel.Active {
+			return nil
+		}
+		if err := dequeueCampaign(tx, campaignModel.ID); err != nil {
+			return err
+		}
+		return enqueueCampaign(tx, campaignModel.ID, campaignModel.BotID)
+	}); err != nil {
 		return nil, err
 	}
+	resultingCampaign := &types.Campaign{}
+	campaignModel.ToEntity(resultingCampaign)
+	return resultingCampaign, nil
+}
 
-	campaignModel.FromEntity(campaign)
+func (dao *CampaignDaoImplGorm) SetState(botID int64, campaignID int64, state string) (*types.Campaign, error) {
+	return dao.changeState(botID, campaignID, func(tx *gorm.DB, campaignModel *database.Campaign, current *types.Campaign) error {
+		if state == current.State {
+			return nil
+		}
+		if err := checkCampaignTransition(tx, botID, current.State, state); err != nil {
+			return err
+		}
+		if state == types.CampaignStateDraft {
+			campaignModel.ApprovedBy = ""
+			campaignModel.ApprovedAt = time.Time{}
+		}
+		return setCampaignState(tx, campaignModel, state)
+	})
+}
 
-	if err := dao.db.Save(campaignModel).Error; err != nil {
+func (dao *CampaignDaoImplGorm) Approve(botID int64, campaignID int64, approvedBy string) (*types.Campaign, error) {
+	if approvedBy == "" {
+		return nil, errors.New("Approver missing")
+	}
+	return dao.changeState(botID, campaignID, func(tx *gorm.DB, campaignModel *database.Campaign, current *types.Campaign) error {
+		if current.State != types.CampaignStatePendingApproval {
+			return conflict("Only campaigns pending approval can be approved, the campaign is %s", current.State)
+		}
+		campaignModel.ApprovedBy = approvedBy
+		campaignModel.ApprovedAt = time.Now()
+		return setCampaignState(tx, campaignModel, types.CampaignStateApproved)
+	})
+}
+
+// changeState runs a change of the lifecycle state of a campaign in a transaction
+func (dao *CampaignDaoImplGorm) changeState(
+	botID int64,
+	campaignID int64,
+	change func(tx *gorm.DB, campaignModel *database.Campaign, current *types.Campaign) error,
+) (*types.Campaign, error) {
+	campaignModel := &database.Campaign{}
+	if err := dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND bot_id = ?", campaignID, botID).First(campaignModel).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("Campaign does not exist")
+			}
+			return err
+		}
+		current := &types.Campaign{}
+		campaignModel.ToEntity(current)
+		campaignModel.State = current.State
+		return change(tx, campaignModel, current)
+	}); err != nil {
 		return nil, err
 	}
 	resultingCampaign := &types.Campaign{}
@@ -71,6 +216,72 @@ func (dao *CampaignDaoImplGorm) Get(botID int64, ID int64) (*types.Campaign, err
 	return resultingCampaign, nil
 }
 
+func (dao *CampaignDaoImplGorm) ListRevisions(botID int64, campaignID int64) ([]types.CampaignRevision, error) {
+	revisionModelsList := []database.CampaignRevision{}
+	if err := dao.db.
+		Where("bot_id = ? AND campaign_id = ?", botID, campaignID).
+		Order("revision").
+		Find(&revisionModelsList).Error; err != nil {
+		return nil, err
+	}
+	revisions := make([]types.CampaignRevision, len(revisionModelsList))
+	for i := range revisionModelsList {
+		revisionModelsList[i].ToEntity(&revisions[i])
+	}
+	return revisions, nil
+}
+
+func (dao *CampaignDaoImplGorm) GetRevision(botID int64, campaignID int64, revision int64) (*types.CampaignRevision, error) {
+	revisionModel := &database.CampaignRevision{}
+	if err := dao.db.
+		Where("bot_id = ? AND campaign_id = ? AND revision = ?", botID, campaignID, revision).
+		First(revisionModel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	resultingRevision := &types.CampaignRevision{}
+	revisionModel.ToEntity(resultingRevision)
+	return resultingRevision, nil
+}
+
+func (dao *CampaignDaoImplGorm) DiffRevisions(botID int64, campaignID int64, from int64, to int64) (*types.CampaignRevisionDiff, error) {
+	fromRevision, err := dao.GetRevision(botID, campaignID, from)
+	if err != nil {
+		return nil, err
+	}
+	if fromRevision == nil {
+		return nil, nil
+	}
+	toRevision, err := dao.GetRevision(botID, campaignID, to)
+	if err != nil {
+		return nil, err
+	}
+	if toRevision == nil {
+		return nil, nil
+	}
+	return diffCampaignRevisions(fromRevision, toRevision), nil
+}
+
+func (dao *CampaignDaoImplGorm) GetDeliveredRevision(botID int64, campaignID int64, telegramID int64) (*types.CampaignRevision, error) {
+	deliveryModel := &database.Delivery{}
+	if err := dao.db.
+		Where("bot_id = ? AND campaign_id = ? AND telegram_id = ? AND state = ?",
+			botID, campaignID, telegramID, types.DeliveryStateSuccess).
+		First(deliveryModel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if deliveryModel.Revision == 0 {
+		//Delivered before revisions
+		return nil, nil
+	}
+	return dao.GetRevision(botID, campaignID, deliveryModel.Revision)
+}
+
 func (dao *CampaignDaoImplGorm) List(botID int64, pageRequest *types.PaginatorRequest) ([]types.Campaign, *types.PaginatorResponse, error) {
 	campaignModelsList := []database.Campaign{}
 	db := dao.db.Table("campaigns").
@@ -133,11 +344,244 @@ func (dao *CampaignDaoImplGorm) GetAggregatedStatistics(botID int64, campaignID
 		return nil, err
 	}
 
-	return &types.CampaignAggregatedStatistics{
+	statistics := &types.CampaignAggregatedStatistics{
 		Delivered: deliveredCount,
 		Errors:    errorsCount,
 		Pending:   pendingCount,
 		Users:     usersCount,
 		TimedOut:  0,
-	}, nil
+	}
+	if err := dao.fillClickStatistics(statistics, campaign); err != nil {
+		return nil, err
+	}
+	if err := dao.db.Model(&database.LinkClick{}).
+		Where("campaign_id = ?", campaignID).
+		Select("COUNT(*), COUNT(DISTINCT telegram_id)").
+		Row().
+		Scan(&statistics.LinkClicks, &statistics.UniqueLinkClicks); err != nil {
+		return nil, err
+	}
+	if err := dao.db.Model(&database.Conversion{}).
+		Where("campaign_id = ?", campaignID).
+		Select("COUNT(*), COALESCE(SUM(value), 0)").
+		Row().
+		Scan(&statistics.Conversions, &statistics.Revenue); err != nil {
+		return nil, err
+	}
+	return statistics, nil
+}
+
+func (dao *CampaignDaoImplGorm) fillClickStatistics(statistics *types.CampaignAggregatedStatistics, campaign *types.Campaign) error {
+	clicks := func() *gorm.DB {
+		return dao.db.Model(&database.Click{}).Where("campaign_id = ?", campaign.ID)
+	}
+
+	if err := clicks().
+		Select("COUNT(*), COUNT(DISTINCT telegram_id)").
+		Row().
+		Scan(&statistics.Clicks, &statistics.Clickers); err != nil {
+		return err
+	}
+
+	buttonRows := []struct {
+		Button   int
+		Clicks   int64
+		Clickers int64
+	}{}
+	if err := clicks().
+		Select("button, COUNT(*) AS clicks, COUNT(DISTINCT telegram_id) AS clickers").
+		Group("button").
+		Scan(&buttonRows).Error; err != nil {
+		return err
+	}
+
+	if len(campaign.Buttons) > 0 {
+		statistics.Buttons = make([]types.ButtonStatistics, len(campaign.Buttons))
+		for i, button := range campaign.Buttons {
+			statistics.Buttons[i] = types.ButtonStatistics{Button: i, Text: button.Text}
+		}
+	}
+	for _, row := range buttonRows {
+		//Clicks of buttons which have been removed from the campaign are counted only in the totals
+		if row.Button < len(statistics.Buttons) {
+			statistics.Buttons[row.Button].Clicks = row.Clicks
+			statistics.Buttons[row.Button].Clickers = row.Clickers
+		}
+	}
+
+	statistics.ClickThroughRate = clickThroughRate(statistics.Clickers, statistics.Delivered)
+	for i := range statistics.Buttons {
+		statistics.Buttons[i].ClickThroughRate = clickThroughRate(statistics.Buttons[i].Clickers, statistics.Delivered)
+	}
+	return nil
+}
+
+func (dao *CampaignDaoImplGorm) GetSourceStatistics(botID int64, campaignID int64, touch string) ([]types.CampaignSourceStatistics, error) {
+	var sourceColumn string
+	switch touch {
+	case types.SourceTouchFirst:
+		sourceColumn = "users.first_source"
+	case types.SourceTouchLast:
+		sourceColumn = "users.last_source"
+	default:
+		return nil, errors.New("Wrong source touch")
+	}
+	campaign, err := dao.Get(botID, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, errors.New("Campaign does not exist")
+	}
+
+	rows, err := dao.breakdown(campaignID, sourceColumn, func(table string) string {
+		return "INNER JOIN users ON users.bot_id = " + table + ".bot_id AND users.telegram_id = " + table + ".telegram_id"
+	})
+	if err != nil {
+		return nil, err
+	}
+	statisticsList := make([]types.CampaignSourceStatistics, len(rows))
+	for i, row := range rows {
+		statisticsList[i] = types.CampaignSourceStatistics{
+			Source:           row.Key,
+			Delivered:        row.Delivered,
+			Errors:           row.Errors,
+			Clickers:         row.Clickers,
+			ClickThroughRate: clickThroughRate(row.Clickers, row.Delivered),
+			Conversions:      row.Conversions,
+			Revenue:          row.Revenue,
+		}
+	}
+	return statisticsList, nil
+}
+
+func (dao *CampaignDaoImplGorm) GetLanguageStatistics(botID int64, campaignID int64) ([]types.CampaignLanguageStatistics, error) {
+	campaign, err := dao.Get(botID, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, errors.New("Campaign does not exist")
+	}
+
+	rows, err := dao.breakdown(campaignID, "deliveries.language", func(table string) string {
+		if table == "deliveries" {
+			return ""
+		}
+		return "INNER JOIN deliveries ON deliveries.campaign_id = " + table + ".campaign_id " +
+			"AND deliveries.bot_id = " + table + ".bot_id AND deliveries.telegram_id = " + table + ".telegram_id"
+	})
+	if err != nil {
+		return nil, err
+	}
+	statisticsList := make([]types.CampaignLanguageStatistics, len(rows))
+	for i, row := range rows {
+		statisticsList[i] = types.CampaignLanguageStatistics{
+			Language:         row.Key,
+			Delivered:        row.Delivered,
+			Errors:           row.Errors,
+			Clickers:         row.Clickers,
+			ClickThroughRate: clickThroughRate(row.Clickers, row.Delivered),
+			Conversions:      row.Conversions,
+			Revenue:          row.Revenue,
+		}
+	}
+	return statisticsList, nil
+}
+
+// breakdownRow is the performance of a campaign among the recipients with the same Key
+type breakdownRow struct {
+	Key         string
+	Delivered   int64
+	Errors      int64
+	Clickers    int64
+	Conversions int64
+	Revenue     float64
+}
+
+// breakdown groups deliveries, clicks and conversions of a campaign by keyColumn, ordered by key.
+// join returns the join which makes keyColumn available for a table of campaign events.
+func (dao *CampaignDaoImplGorm) breakdown(campaignID int64, keyColumn string, join func(table string) string) ([]breakdownRow, error) {
+	byKey := map[string]*breakdownRow{}
+	keys := []string{}
+	rowOf := func(key string) *breakdownRow {
+		row, ok := byKey[key]
+		if !ok {
+			row = &breakdownRow{Key: key}
+			byKey[key] = row
+			keys = append(keys, key)
+		}
+		return row
+	}
+	groupByKey := func(table string) *gorm.DB {
+		query := dao.db.Table(table)
+		if joins := join(table); joins != "" {
+			query = query.Joins(joins)
+		}
+		return query.
+			Where(table+".campaign_id = ?", campaignID).
+			Group(keyColumn)
+	}
+
+	deliveryRows := []struct {
+		BreakdownKey string
+		Delivered    int64
+		Errors       int64
+	}{}
+	if err := groupByKey("deliveries").
+		Select(keyColumn+" AS breakdown_key, "+
+			"SUM(CASE WHEN deliveries.state = ? THEN 1 ELSE 0 END) AS delivered, "+
+			"SUM(CASE WHEN deliveries.state = ? THEN 1 ELSE 0 END) AS errors",
+			types.DeliveryStateSuccess, types.DeliveryStateFail).
+		Scan(&deliveryRows).Error; err != nil {
+		return nil, err
+	}
+	for _, deliveryRow := range deliveryRows {
+		row := rowOf(deliveryRow.BreakdownKey)
+		row.Delivered = deliveryRow.Delivered
+		row.Errors = deliveryRow.Errors
+	}
+
+	clickRows := []struct {
+		BreakdownKey string
+		Clickers     int64
+	}{}
+	if err := groupByKey("clicks").
+		Select(keyColumn + " AS breakdown_key, COUNT(DISTINCT clicks.telegram_id) AS clickers").
+		Scan(&clickRows).Error; err != nil {
+		return nil, err
+	}
+	for _, clickRow := range clickRows {
+		rowOf(clickRow.BreakdownKey).Clickers = clickRow.Clickers
+	}
+
+	conversionRows := []struct {
+		BreakdownKey string
+		Conversions  int64
+		Revenue      float64
+	}{}
+	if err := groupByKey("conversions").
+		Select(keyColumn + " AS breakdown_key, COUNT(*) AS conversions, COALESCE(SUM(conversions.value), 0) AS revenue").
+		Scan(&conversionRows).Error; err != nil {
+		return nil, err
+	}
+	for _, conversionRow := range conversionRows {
+		row := rowOf(conversionRow.BreakdownKey)
+		row.Conversions = conversionRow.Conversions
+		row.Revenue = conversionRow.Revenue
+	}
+
+	sort.Strings(keys)
+	rows := make([]breakdownRow, len(keys))
+	for i, key := range keys {
+		rows[i] = *byKey[key]
+	}
+	return rows, nil
+}
+
+func clickThroughRate(clickers int64, delivered int64) float64 {
+	if delivered == 0 {
+		return 0
+	}
+	return float64(clickers) / float64(delivered)
 }
@@ -0,0 +1,102 @@
+package dbclient
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/database"
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+// campaignTransitions are the states a campaign may go to from each state.
+// An approval is the only way to approved, see CampaignDaoImplGorm.Approve.
+var campaignTransitions = map[string][]string{
+	types.CampaignStateDraft:           {types.CampaignStatePendingApproval, types.CampaignStateArchived},
+	types.CampaignStatePendingApproval: {types.CampaignStateDraft},
+	types.CampaignStateApproved:        {types.CampaignStateRunning, types.CampaignStateDraft, types.CampaignStateArchived},
+	types.CampaignStateRunning:         {types.CampaignStatePaused, types.CampaignStateCompleted},
+	types.CampaignStatePaused:          {types.CampaignStateRunning, types.CampaignStateCompleted},
+	types.CampaignStateCompleted:       {types.CampaignStateArchived},
+}
+
+// Initial states of new campaigns
+var campaignInitialStates = []string{
+	types.CampaignStateDraft,
+	types.CampaignStatePendingApproval,
+	types.CampaignStateRunning,
+}
+
+// checkCampaignTransition fails if a campaign of the bot cannot go from one state to another.
+// Bots which do not require approval can run drafts right away.
+func checkCampaignTransition(tx *gorm.DB, botID int64, from string, to string) error {
+	allowed := campaignTransitions[from]
+	if from == "" {
+		allowed = campaignInitialStates
+	}
+	if to == types.CampaignStateRunning && (from == "" || from == types.CampaignStateDraft) {
+		requireApproval, err := botRequiresApproval(tx, botID)
+		if err != nil {
+			return err
+		}
+		if requireApproval {
+			return conflict("Campaigns of this bot must be approved before they run")
+		}
+		return nil
+	}
+	for _, state := range allowed {
+		if state == to {
+			return nil
+		}
+	}
+	if from == "" {
+		return conflict("A campaign cannot be created in the %s state", to)
+	}
+	return conflict("A campaign cannot go from %s to %s", from, to)
+}
+
+// conflict reports a change which the current state of a campaign does not allow
+func conflict(format string, args ...interface{}) error {
+	return &dao.ConflictError{Message: fmt.Sprintf(format, args...)}
+}
+
+func botRequiresApproval(tx *gorm.DB, botID int64) (bool, error) {
+	botModel := &database.Bot{}
+	if err := tx.Select("require_approval").Where("id = ?", botID).First(botModel).Error; err != nil {
+		return false, err
+	}
+	return botModel.RequireApproval, nil
+}
+
+// campaignMessage is what an approval covers and what cannot change while a campaign is running
+func campaignMessage(campaign *types.Campaign) string {
+	message, _ := json.Marshal(&types.Campaign{
+		Message:       campaign.Message,
+		ParseMode:     campaign.ParseMode,
+		TrackLinks:    campaign.TrackLinks,
+		Buttons:       campaign.Buttons,
+		Kind:          campaign.Kind,
+		Options:       campaign.Options,
+		Localizations: campaign.Localizations,
+		MediaID:       campaign.MediaID,
+	})
+	return string(message)
+}
+
+// setCampaignState moves a campaign model to a state and keeps the queue of its recipients in step
+func setCampaignState(tx *gorm.DB, campaignModel *database.Campaign, state string) error {
+	wasActive := campaignModel.Active
+	campaignModel.State = state
+	campaignModel.Active = state == types.CampaignStateRunning
+	if err := tx.Save(campaignModel).Error; err != nil {
+		return err
+	}
+	if campaignModel.Active && !wasActive {
+		return enqueueCampaign(tx, campaignModel.ID, campaignModel.BotID)
+	}
+	if !campaignModel.Active && wasActive {
+		return dequeueCampaign(tx, campaignModel.ID)
+	}
+	return nil
+}
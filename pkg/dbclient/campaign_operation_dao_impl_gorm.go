@@ -0,0 +1,487 @@
+package dbclient
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/database"
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+// Reason of queued edits which are dropped because the campaign is recalled
+const reasonCancelledByRecall = "cancelled"
+
+type CampaignOperationDaoImplGorm struct {
+	db *gorm.DB
+}
+
+func NewCampaignOperationDaoImplGorm(db *gorm.DB) dao.CampaignOperationDao {
+	return &CampaignOperationDaoImplGorm{
+		db: db,
+	}
+}
+
+func (dao *CampaignOperationDaoImplGorm) Edit(botID int64, campaignID int64, text string) (*types.CampaignOperation, error) {
+	if text == "" {
+		return nil, errors.New("Text missing")
+	}
+	return dao.createOperation(botID, campaignID, types.CampaignOperationEdit, text, func(tx *gorm.DB, campaign *database.Campaign) error {
+		if campaign.Kind == types.CampaignKindPoll {
+			return conflict("Polls cannot be edited")
+		}
+		entity := &types.Campaign{}
+		campaign.ToEntity(entity)
+		if entity.State == types.CampaignStateArchived {
+			return conflict("Archived campaigns cannot be changed")
+		}
+		if campaign.Active {
+			return conflict("The message of a running campaign cannot be changed, pause it first")
+		}
+		if err := ensureCampaignRevision(tx, campaign); err != nil {
+			return err
+		}
+		campaign.Message = text
+		campaign.Revision++
+		updates := map[string]interface{}{
+			"message":  campaign.Message,
+			"revision": campaign.Revision,
+		}
+		if entity.State == types.CampaignStatePendingApproval || entity.State == types.CampaignStateApproved {
+			//The approval does not cover the new message
+			campaign.State = types.CampaignStateDraft
+			campaign.ApprovedBy = ""
+			campaign.ApprovedAt = time.Time{}
+			updates["state"] = campaign.State
+			updates["approved_by"] = campaign.ApprovedBy
+			updates["approved_at"] = campaign.ApprovedAt
+		}
+		if err := tx.Model(campaign).Updates(updates).Error; err != nil {
+			return err
+		}
+		return recordCampaignRevision(tx, campaign)
+	}, queueDeliveredJobs)
+}
+
+func (dao *CampaignOperationDaoImplGorm) Recall(botID int64, campaignID int64) (*types.CampaignOperation, error) {
+	return dao.createOperation(botID, campaignID, types.CampaignOperationRecall, "", func(tx *gorm.DB, campaign *database.Campaign) error {
+		entity := &types.Campaign{}
+		campaign.ToEntity(entity)
+		state := entity.State
+		if state == types.CampaignStateRunning || state == types.CampaignStatePaused {
+			state = types.CampaignStateCompleted
+		}
+		if err := tx.Model(campaign).Updates(map[string]interface{}{
+			"active": false,
+			"state":  state,
+		}).Error; err != nil {
+			return err
+		}
+		if err := dequeueCampaign(tx, campaign.ID); err != nil {
+			return err
+		}
+		return tx.Model(&database.MessageJob{}).
+			Where("campaign_id = ? AND kind = ? AND state = ?",
+				campaign.ID,
+				types.CampaignOperationEdit,
+				types.MessageJobStateQueued).
+			Updates(map[string]interface{}{
+				"state":  types.MessageJobStateFail,
+				"reason": reasonCancelledByRecall,
+			}).Error
+	}, queueDeliveredJobs)
+}
+
+func (dao *CampaignOperationDaoImplGorm) Test(botID int64, campaignID int64) (*types.CampaignOperation, error) {
+	return dao.createOperation(botID, campaignID, types.CampaignOperationTest, "", func(tx *gorm.DB, campaign *database.Campaign) error {
+		return nil
+	}, queueTesterJobs)
+}
+
+// createOperation changes the campaign and queues the jobs of the operation
+func (dao *CampaignOperationDaoImplGorm) createOperation(
+	botID int64,
+	campaignID int64,
+	kind string,
+	text string,
+	changeCampaign func(tx *gorm.DB, campaign *database.Campaign) error,
+	queueJobs func(tx *gorm.DB, operation *database.CampaignOperation, campaign *database.Campaign) error,
+) (*types.CampaignOperation, error) {
+	operation := &types.CampaignOperation{}
+
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		campaign := &database.Campaign{}
+		if err := tx.Where("bot_id = ? AND id = ?", botID, campaignID).First(campaign).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("Campaign does not exist")
+			}
+			return err
+		}
+		if err := changeCampaign(tx, campaign); err != nil {
+			return err
+		}
+
+		operationModel := &database.CampaignOperation{
+			BotID:      botID,
+			CampaignID: campaignID,
+			Kind:       kind,
+			Text:       text,
+		}
+		if kind == types.CampaignOperationEdit {
+			operationModel.Revision = campaign.Revision
+		}
+		if err := tx.Create(operationModel).Error; err != nil {
+			return err
+		}
+		if err := queueJobs(tx, operationModel, campaign); err != nil {
+			return err
+		}
+		if err := markBotsNotEmpty(tx, []int64{botID}); err != nil {
+			return err
+		}
+
+		operationModel.ToEntity(operation)
+		return fillOperationCounts(tx, []*types.CampaignOperation{operation})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
+
+// queueDeliveredJobs queues a job for every delivered message of the campaign
+func queueDeliveredJobs(tx *gorm.DB, operation *database.CampaignOperation, campaign *database.Campaign) error {
+	now := time.Now()
+	return tx.Exec(
+		"INSERT INTO message_jobs "+
+			"(created_at, updated_at, operation_id, bot_id, campaign_id, telegram_id, message_id, kind, text, state, reason, worker_id) "+
+			"SELECT ?, ?, ?, deliveries.bot_id, deliveries.campaign_id, deliveries.telegram_id, deliveries.message_id, ?, ?, ?, '', '' "+
+			"FROM deliveries "+
+			"WHERE deliveries.bot_id = ? "+
+			"AND deliveries.campaign_id = ? "+
+			"AND deliveries.state = ? "+
+			"AND deliveries.message_id <> 0 "+
+			"AND deliveries.deleted_at IS NULL "+
+			//Edits change the default message, messages sent in other languages keep their text
+			"AND (deliveries.language = '' OR ? <> ?) "+
+			//A message which is being deleted or is already deleted cannot be changed
+			"AND NOT EXISTS (SELECT 1 FROM message_jobs WHERE "+
+			"message_jobs.campaign_id = deliveries.campaign_id "+
+			"AND message_jobs.bot_id = deliveries.bot_id "+
+			"AND message_jobs.telegram_id = deliveries.telegram_id "+
+			"AND message_jobs.kind = ? "+
+			"AND message_jobs.state <> ?) "+
+			"ORDER BY deliveries.id",
+		now, now, operation.ID, operation.Kind, operation.Text, types.MessageJobStateQueued,
+		operation.BotID, operation.CampaignID, types.DeliveryStateSuccess,
+		operation.Kind, types.CampaignOperationEdit,
+		types.CampaignOperationRecall, types.MessageJobStateFail,
+	).Error
+}
+
+// queueTesterJobs queues a test send of the campaign to every tester of the bot, in the language of the tester
+func queueTesterJobs(tx *gorm.DB, operation *database.CampaignOperation, campaignModel *database.Campaign) error {
+	testers := []struct {
+		TelegramID   int64
+		LanguageCode string
+	}{}
+	if err := tx.Model(&database.Tester{}).
+		Select("testers.telegram_id, users.language_code").
+		Joins("JOIN users ON users.bot_id = testers.bot_id AND users.telegram_id = testers.telegram_id AND users.deleted_at IS NULL").
+		Where("testers.bot_id = ?", operation.BotID).
+		Order("testers.id").
+		Scan(&testers).Error; err != nil {
+		return err
+	}
+	if len(testers) == 0 {
+		return &dao.ConflictError{Message: "The bot has no testers"}
+	}
+	campaign := &types.Campaign{}
+	campaignModel.ToEntity(campaign)
+	jobModelsList := make([]database.MessageJob, len(testers))
+	for i, tester := range testers {
+		localizedCampaign, _ := campaign.Localize(tester.LanguageCode)
+		jobModelsList[i] = database.MessageJob{
+			OperationID: operation.ID,
+			BotID:       operation.BotID,
+			CampaignID:  operation.CampaignID,
+			TelegramID:  tester.TelegramID,
+			Kind:        types.CampaignOperationTest,
+			Text:        localizedCampaign.Message,
+			State:       types.MessageJobStateQueued,
+		}
+	}
+	return tx.Create(&jobModelsList).Error
+}
+
+func (dao *CampaignOperationDaoImplGorm) Get(botID int64, campaignID int64, operationID int64) (*types.CampaignOperation, error) {
+	operationModel := &database.CampaignOperation{}
+	if err := dao.db.
+		Where("bot_id = ? AND campaign_id = ? AND id = ?", botID, campaignID, operationID).
+		First(operationModel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	operation := &types.CampaignOperation{}
+	operationModel.ToEntity(operation)
+	if err := fillOperationCounts(dao.db, []*types.CampaignOperation{operation}); err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
+
+func (dao *CampaignOperationDaoImplGorm) List(botID int64, campaignID int64) ([]types.CampaignOperation, error) {
+	operationModelsList := []database.CampaignOperation{}
+	if err := dao.db.
+		Where("bot_id = ? AND campaign_id = ?", botID, campaignID).
+		Order("id DESC").
+		Find(&operationModelsList).Error; err != nil {
+		return nil, err
+	}
+	operationsList := make([]types.CampaignOperation, len(operationModelsList))
+	operations := make([]*types.CampaignOperation, len(operationModelsList))
+	for i, model := range operationModelsList {
+		model.ToEntity(&operationsList[i])
+		operations[i] = &operationsList[i]
+	}
+	if err := fillOperationCounts(dao.db, operations); err != nil {
+		return nil, err
+	}
+	return operationsList, nil
+}
+
+func (this *CampaignOperationDaoImplGorm) TakeJobs(workerID string, botID int64, size int) ([]types.MessageJob, error) {
+	if botID == 0 {
+		return nil, errors.New("Bot ID missing")
+	}
+	if size < 1 || size > dao.MaxTakeBatchSize {
+		return nil, fmt.Errorf("Batch size must be between 1 and %d", dao.MaxTakeBatchSize)
+	}
+	jobModelsList := []database.MessageJob{}
+
+	err := this.db.Transaction(func(tx *gorm.DB) error {
+		queuedList := []database.MessageJob{}
+		if err := tx.
+			Where("bot_id = ? AND state = ?", botID, types.MessageJobStateQueued).
+			//Jobs queued before delays existed have no time set
+			Where("send_after IS NULL OR send_after <= ?", time.Now()).
+			Order("id ASC").
+			Limit(size).
+			Find(&queuedList).Error; err != nil {
+			return err
+		}
+		for _, model := range queuedList {
+			taken := tx.Model(&database.MessageJob{}).
+				Where("id = ? AND state = ?", model.ID, types.MessageJobStateQueued).
+				Updates(map[string]interface{}{
+					"state":     types.MessageJobStateProgress,
+					"worker_id": workerID,
+				})
+			if taken.Error != nil {
+				return taken.Error
+			}
+			if taken.RowsAffected == 0 {
+				//Taken by another worker meanwhile
+				continue
+			}
+			model.State = types.MessageJobStateProgress
+			model.WorkerID = workerID
+			jobModelsList = append(jobModelsList, model)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	jobsList := make([]types.MessageJob, len(jobModelsList))
+	for i, model := range jobModelsList {
+		model.ToEntity(&jobsList[i])
+	}
+	if err := this.fillJobCampaigns(jobsList); err != nil {
+		return nil, err
+	}
+	return jobsList, nil
+}
+
+// fillJobCampaigns adds what the sender needs from the campaign: the keyboard and the formatting of edits,
+// the whole campaign and its photo for test sends
+func (this *CampaignOperationDaoImplGorm) fillJobCampaigns(jobs []types.MessageJob) error {
+	campaignIDs := []int64{}
+	for _, job := range jobs {
+		if job.Kind == types.CampaignOperationEdit || job.Kind == types.CampaignOperationTest {
+			campaignIDs = append(campaignIDs, job.CampaignID)
+		}
+	}
+	if len(campaignIDs) == 0 {
+		return nil
+	}
+	campaignModelsList := []database.Campaign{}
+	if err := this.db.Where("id IN ?", campaignIDs).Find(&campaignModelsList).Error; err != nil {
+		return err
+	}
+	campaigns := map[int64]*types.Campaign{}
+	for _, model := range campaignModelsList {
+		campaign := &types.Campaign{}
+		model.ToEntity(campaign)
+		campaigns[campaign.ID] = campaign
+	}
+	medias := map[int64]*deliveryMedia{}
+	for i := range jobs {
+		campaign, ok := campaigns[jobs[i].CampaignID]
+		if !ok {
+			continue
+		}
+		if jobs[i].Kind == types.CampaignOperationTest {
+			testCampaign := *campaign
+			testCampaign.Message = jobs[i].Text
+			jobs[i].Campaign = &testCampaign
+			if campaign.MediaID == 0 {
+				continue
+			}
+			media, ok := medias[campaign.ID]
+			if !ok {
+				var err error
+				if media, err = findDeliveryMedia(this.db, campaign); err != nil {
+					return err
+				}
+				medias[campaign.ID] = media
+			}
+			jobs[i].FileID = media.fileID
+			jobs[i].Media = media.asset
+			continue
+		}
+		if jobs[i].Kind != types.CampaignOperationEdit {
+			continue
+		}
+		jobs[i].Buttons = campaign.Buttons
+		jobs[i].Caption = campaign.MediaID != 0
+		jobs[i].ParseMode = campaign.ParseMode
+		if campaign.Kind == types.CampaignKindSurvey {
+			jobs[i].SurveyOptions = campaign.Options
+		}
+	}
+	return nil
+}
+
+func (dao *CampaignOperationDaoImplGorm) SetJobStates(jobs []types.MessageJob) error {
+	for _, job := range jobs {
+		if job.State != types.MessageJobStateSuccess &&
+			job.State != types.MessageJobStateFail {
+			return errors.New("Wrong message job state")
+		}
+	}
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		for _, job := range jobs {
+			jobModel := &database.MessageJob{}
+			if err := tx.Where("id = ? AND bot_id = ?", job.ID, job.BotID).First(jobModel).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					continue
+				}
+				return err
+			}
+			updates := map[string]interface{}{
+				"state":  job.State,
+				"reason": job.Reason,
+			}
+			//A reply, a step of a sequence or a test send is a new message, its ID is known once it is sent.
+			//Edits and recalls keep the ID of the message they change.
+			if (jobModel.Kind == types.MessageJobReply ||
+				jobModel.Kind == types.MessageJobSequence ||
+				jobModel.Kind == types.CampaignOperationTest) && job.MessageID != 0 {
+				updates["message_id"] = job.MessageID
+			}
+			if err := tx.Model(&database.MessageJob{}).Where("id = ?", jobModel.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+			if job.State == types.MessageJobStateSuccess {
+				if err := setEditedDeliveryRevision(tx, jobModel); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (dao *CampaignOperationDaoImplGorm) ReleaseJobs(jobs []types.MessageJob) error {
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		botIDs := []int64{}
+		for _, job := range jobs {
+			released := tx.Model(&database.MessageJob{}).
+				Where("id = ? AND bot_id = ? AND state = ?", job.ID, job.BotID, types.MessageJobStateProgress).
+				Updates(map[string]interface{}{
+					"state":     types.MessageJobStateQueued,
+					"worker_id": "",
+				})
+			if released.Error != nil {
+				return released.Error
+			}
+			if released.RowsAffected > 0 {
+				botIDs = append(botIDs, job.BotID)
+			}
+		}
+		return markBotsNotEmpty(tx, botIDs)
+	})
+}
+
+// setEditedDeliveryRevision records that the user of a successful edit has got the revision made by the edit
+func setEditedDeliveryRevision(tx *gorm.DB, jobModel *database.MessageJob) error {
+	if jobModel.Kind != types.CampaignOperationEdit {
+		return nil
+	}
+	operationModel := &database.CampaignOperation{}
+	if err := tx.Select("revision").Where("id = ?", jobModel.OperationID).First(operationModel).Error; err != nil {
+		return err
+	}
+	if operationModel.Revision == 0 {
+		return nil
+	}
+	//The delivery itself is not updated, its updated_at keeps telling when its state was reported
+	return tx.Model(&database.Delivery{}).
+		Where("bot_id = ? AND campaign_id = ? AND telegram_id = ?", jobModel.BotID, jobModel.CampaignID, jobModel.TelegramID).
+		UpdateColumn("revision", operationModel.Revision).Error
+}
+
+// fillOperationCounts counts message jobs of operations by state
+func fillOperationCounts(db *gorm.DB, operations []*types.CampaignOperation) error {
+	if len(operations) == 0 {
+		return nil
+	}
+	byID := map[int64]*types.CampaignOperation{}
+	ids := make([]int64, len(operations))
+	for i, operation := range operations {
+		byID[operation.ID] = operation
+		ids[i] = operation.ID
+	}
+	rows := []struct {
+		OperationID int64
+		State       types.MessageJobState
+		Count       int64
+	}{}
+	if err := db.Model(&database.MessageJob{}).
+		Select("operation_id, state, count(*) as count").
+		Where("operation_id IN ?", ids).
+		Group("operation_id, state").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		operation := byID[row.OperationID]
+		switch row.State {
+		case types.MessageJobStateQueued:
+			operation.Queued = row.Count
+		case types.MessageJobStateProgress:
+			operation.Progress = row.Count
+		case types.MessageJobStateSuccess:
+			operation.Success = row.Count
+		case types.MessageJobStateFail:
+			operation.Fail = row.Count
+		}
+	}
+	return nil
+}
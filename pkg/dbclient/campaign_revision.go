@@ -0,0 +1,92 @@
+package dbclient
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/corporateanon/barker/pkg/database"
+	"github.com/corporateanon/barker/pkg/textdiff"
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+// campaignRevisionChanged tells if an update of a campaign needs a new revision
+func campaignRevisionChanged(current *types.Campaign, next *types.Campaign) bool {
+	return current.Title != next.Title || campaignMessage(current) != campaignMessage(next)
+}
+
+// recordCampaignRevision saves the title and the message of a campaign model as its current revision
+func recordCampaignRevision(tx *gorm.DB, campaignModel *database.Campaign) error {
+	revisionModel := &database.CampaignRevision{}
+	revisionModel.FromCampaign(campaignModel)
+	return tx.Create(revisionModel).Error
+}
+
+// ensureCampaignRevision records the first revision of a campaign created before revisions,
+// so that its original message is kept when it changes
+func ensureCampaignRevision(tx *gorm.DB, campaignModel *database.Campaign) error {
+	if campaignModel.Revision != 0 {
+		return nil
+	}
+	campaignModel.Revision = 1
+	if err := tx.Model(campaignModel).Update("revision", campaignModel.Revision).Error; err != nil {
+		return err
+	}
+	return recordCampaignRevision(tx, campaignModel)
+}
+
+// diffCampaignRevisions lists the fields changed between two revisions
+func diffCampaignRevisions(from *types.CampaignRevision, to *types.CampaignRevision) *types.CampaignRevisionDiff {
+	diff := &types.CampaignRevisionDiff{
+		CampaignID: to.CampaignID,
+		From:       from.Revision,
+		To:         to.Revision,
+		Changes:    []types.CampaignRevisionChange{},
+	}
+	addChange := func(field string, fromValue string, toValue string, text bool) {
+		if fromValue == toValue {
+			return
+		}
+		change := types.CampaignRevisionChange{Field: field, From: fromValue, To: toValue}
+		if text {
+			for _, line := range textdiff.Lines(fromValue, toValue) {
+				change.Lines = append(change.Lines, types.DiffLine{Op: line.Op, Text: line.Text})
+			}
+		}
+		diff.Changes = append(diff.Changes, change)
+	}
+
+	addChange("Title", from.Title, to.Title, false)
+	addChange("Message", from.Message, to.Message, true)
+	addChange("ParseMode", from.ParseMode, to.ParseMode, false)
+	addChange("TrackLinks", strconv.FormatBool(from.TrackLinks), strconv.FormatBool(to.TrackLinks), false)
+	addChange("Buttons", encodeRevisionList(from.Buttons), encodeRevisionList(to.Buttons), false)
+	addChange("Kind", from.Kind, to.Kind, false)
+	addChange("Options", encodeRevisionList(from.Options), encodeRevisionList(to.Options), false)
+	addChange("MediaID", strconv.FormatInt(from.MediaID, 10), strconv.FormatInt(to.MediaID, 10), false)
+
+	//Localizations are compared by language, in the order of the newer revision
+	fromLocalizations := map[string]string{}
+	for _, localization := range from.Localizations {
+		fromLocalizations[localization.Language] = localization.Message
+	}
+	toLanguages := map[string]bool{}
+	for _, localization := range to.Localizations {
+		toLanguages[localization.Language] = true
+		addChange("Localizations."+localization.Language, fromLocalizations[localization.Language], localization.Message, true)
+	}
+	for _, localization := range from.Localizations {
+		if !toLanguages[localization.Language] {
+			addChange("Localizations."+localization.Language, localization.Message, "", true)
+		}
+	}
+	return diff
+}
+
+func encodeRevisionList(list interface{}) string {
+	encoded, _ := json.Marshal(list)
+	if string(encoded) == "null" {
+		return "[]"
+	}
+	return string(encoded)
+}
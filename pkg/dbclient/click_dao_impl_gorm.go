@@ -0,0 +1,52 @@
+package dbclient
+
+import (
+	"errors"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/database"
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ClickDaoImplGorm struct {
+	db *gorm.DB
+}
+
+func NewClickDaoImplGorm(db *gorm.DB) dao.ClickDao {
+	return &ClickDaoImplGorm{
+		db: db,
+	}
+}
+
+func (dao *ClickDaoImplGorm) Add(click *types.Click) (*types.Click, error) {
+	if click.CallbackQueryID == "" {
+		return nil, errors.New("Callback query ID missing")
+	}
+	clickModel := &database.Click{}
+	clickModel.FromEntity(click)
+
+	var delivered int64
+	if err := dao.db.Model(&database.Delivery{}).
+		Where("bot_id = ? AND campaign_id = ? AND telegram_id = ?", click.BotID, click.CampaignID, click.TelegramID).
+		Where("state = ?", types.DeliveryStateSuccess).
+		Count(&delivered).Error; err != nil {
+		return nil, err
+	}
+	if delivered == 0 {
+		return nil, nil
+	}
+
+	created := dao.db.Clauses(clause.OnConflict{DoNothing: true}).Create(clickModel)
+	if err := created.Error; err != nil {
+		return nil, err
+	}
+	if created.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	resultingClick := &types.Click{}
+	clickModel.ToEntity(resultingClick)
+	return resultingClick, nil
+}
@@ -0,0 +1,63 @@
+package dbclient
+
+import (
+	"errors"
+	"time"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/database"
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+type ConversionDaoImplGorm struct {
+	db *gorm.DB
+}
+
+func NewConversionDaoImplGorm(db *gorm.DB) dao.ConversionDao {
+	return &ConversionDaoImplGorm{
+		db: db,
+	}
+}
+
+func (dao *ConversionDaoImplGorm) Create(conversion *types.Conversion) (*types.Conversion, error) {
+	botModel := &database.Bot{}
+	if err := dao.db.Where("id = ?", conversion.BotID).First(botModel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("Bot does not exist")
+		}
+		return nil, err
+	}
+	windowHours := botModel.ConversionWindowHours
+	if windowHours == 0 {
+		windowHours = types.DefaultConversionWindowHours
+	}
+
+	now := time.Now()
+	campaignIDs := []int64{}
+	if err := dao.db.Model(&database.Delivery{}).
+		Where("bot_id = ? AND telegram_id = ?", conversion.BotID, conversion.TelegramID).
+		Where("state = ?", types.DeliveryStateSuccess).
+		Where("delivered_at >= ?", now.Add(-time.Duration(windowHours)*time.Hour)).
+		Order("delivered_at DESC").
+		Order("id DESC").
+		Limit(1).
+		Pluck("campaign_id", &campaignIDs).Error; err != nil {
+		return nil, err
+	}
+
+	conversionModel := &database.Conversion{}
+	conversionModel.FromEntity(conversion)
+	conversionModel.ID = 0
+	conversionModel.CampaignID = 0
+	if len(campaignIDs) > 0 {
+		conversionModel.CampaignID = campaignIDs[0]
+	}
+	if err := dao.db.Create(conversionModel).Error; err != nil {
+		return nil, err
+	}
+
+	resultingConversion := &types.Conversion{}
+	conversionModel.ToEntity(resultingConversion)
+	return resultingConversion, nil
+}
@@ -2,117 +2,321 @@ package dbclient
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/corporateanon/barker/pkg/dao"
 	"github.com/corporateanon/barker/pkg/database"
 	"github.com/corporateanon/barker/pkg/types"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecipientSelection is a strategy of finding recipients which are yet to get a campaign
+type RecipientSelection int
+
+const (
+	// RecipientSelectionQueue pops recipients from the materialized recipient queue
+	RecipientSelectionQueue RecipientSelection = iota
+	// RecipientSelectionJoin searches for users without deliveries with an anti-join.
+	// It gets slower as the deliveries table grows.
+	RecipientSelectionJoin
 )
 
 type DeliveryDaoImplGorm struct {
 	db          *gorm.DB
 	campaignDao dao.CampaignDao
 	userDao     dao.UserDao
+	selection   RecipientSelection
 }
 
 func NewDeliveryDaoImplGorm(
 	db *gorm.DB,
 	campaignDao dao.CampaignDao,
 	userDao dao.UserDao,
+) dao.DeliveryDao {
+	return NewDeliveryDaoImplGormWithSelection(db, campaignDao, userDao, RecipientSelectionQueue)
+}
+
+func NewDeliveryDaoImplGormWithSelection(
+	db *gorm.DB,
+	campaignDao dao.CampaignDao,
+	userDao dao.UserDao,
+	selection RecipientSelection,
 ) dao.DeliveryDao {
 	return &DeliveryDaoImplGorm{
 		db:          db,
 		campaignDao: campaignDao,
 		userDao:     userDao,
+		selection:   selection,
 	}
 }
 
 func (this *DeliveryDaoImplGorm) Take(botID int64, campaignID int64, telegramID int64) (*dao.DeliveryTakeResult, error) {
-	result := &dao.DeliveryTakeResult{
-		Delivery: &types.Delivery{},
-		User:     &types.User{},
-		Campaign: &types.Campaign{},
+	return this.TakeForWorker("", botID, campaignID, telegramID)
+}
+
+func (this *DeliveryDaoImplGorm) TakeForWorker(workerID string, botID int64, campaignID int64, telegramID int64) (*dao.DeliveryTakeResult, error) {
+	if botID == 0 {
+		return nil, errors.New("Bot ID missing")
 	}
+	return this.takeOne(workerID, botID, campaignID, telegramID)
+}
 
-	recipientsNotFound := false
+func (this *DeliveryDaoImplGorm) NextJob(workerID string) (*dao.DeliveryTakeResult, error) {
+	return this.takeOne(workerID, 0, 0, 0)
+}
+
+func (this *DeliveryDaoImplGorm) TakeBatch(workerID string, botID int64, size int) ([]dao.DeliveryTakeResult, error) {
+	if botID == 0 {
+		return nil, errors.New("Bot ID missing")
+	}
+	if size < 1 || size > dao.MaxTakeBatchSize {
+		return nil, fmt.Errorf("Batch size must be between 1 and %d", dao.MaxTakeBatchSize)
+	}
+	var results []dao.DeliveryTakeResult
 
 	err := this.db.Transaction(func(tx *gorm.DB) error {
-		type resultWrapper struct {
-			database.User
-			CampaignID int64
-		}
-
-		resultModel := &resultWrapper{}
-
-		query := tx.
-			Table("users").
-			Select("users.*", "campaigns.id as campaign_id").
-			Joins("inner join campaigns on "+
-				"campaigns.bot_id = users.bot_id "+
-				"AND (campaigns.id = ? OR 0 = ?)", campaignID, campaignID).
-			Joins(
-				"left outer join deliveries on "+
-					"deliveries.telegram_id = users.telegram_id "+
-					"AND deliveries.bot_id = users.bot_id "+
-					"AND deliveries.campaign_id = campaigns.id",
-			).
-			Where("deliveries.telegram_id IS NULL").
-			Where("users.deleted_at IS NULL").
-			Where("campaigns.deleted_at IS NULL").
-			Where("campaigns.active = true").
-			Where("users.bot_id = ?", botID).
-			Order("campaigns.created_at DESC").
-			Limit(1)
-		if telegramID != 0 {
-			query = query.Where("users.telegram_id = ?", telegramID)
-		}
-		query = query.Scan(resultModel)
-
-		if err := query.Error; err != nil {
-			return err
+		var err error
+		results, err = this.takeInTx(tx, workerID, botID, 0, 0, size)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (this *DeliveryDaoImplGorm) takeOne(workerID string, botID int64, campaignID int64, telegramID int64) (*dao.DeliveryTakeResult, error) {
+	var results []dao.DeliveryTakeResult
+
+	err := this.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		results, err = this.takeInTx(tx, workerID, botID, campaignID, telegramID, 1)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	return &results[0], nil
+}
+
+type recipient struct {
+	database.User
+	CampaignID int64
+	//ID of the recipient queue entry, if the recipient is taken from the queue
+	QueueID int64
+}
+
+// takeInTx creates deliveries for up to limit pending recipients.
+// Zero botID means any bot: bots which were served least recently go first.
+func (this *DeliveryDaoImplGorm) takeInTx(tx *gorm.DB, workerID string, botID int64, campaignID int64, telegramID int64, limit int) ([]dao.DeliveryTakeResult, error) {
+	var recipients []recipient
+	var err error
+	if this.selection == RecipientSelectionJoin {
+		recipients, err = this.selectRecipientsByJoin(tx, botID, campaignID, telegramID, limit)
+	} else {
+		recipients, err = this.selectRecipientsFromQueue(tx, botID, campaignID, telegramID, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results := []dao.DeliveryTakeResult{}
+	campaigns := map[int64]*types.Campaign{}
+	medias := map[int64]*deliveryMedia{}
+	bots := map[int64]*types.Bot{}
+
+	for _, recipient := range recipients {
+		popped := tx.Where(
+			"campaign_id = ? AND bot_id = ? AND telegram_id = ?",
+			recipient.CampaignID,
+			recipient.BotID,
+			recipient.TelegramID,
+		).Delete(&database.QueuedRecipient{})
+		if err := popped.Error; err != nil {
+			return nil, err
 		}
-		if resultModel.ID == 0 {
-			recipientsNotFound = true
-			if err := this.updateBotPossiblyEmptyStatus(tx, botID, true); err != nil {
-				return err
+		if recipient.QueueID != 0 && popped.RowsAffected == 0 {
+			//Someone else has just taken this recipient
+			continue
+		}
+
+		campaign, ok := campaigns[recipient.CampaignID]
+		if !ok {
+			campaignModel := &database.Campaign{}
+			if err := tx.Where("id = ?", recipient.CampaignID).Find(campaignModel).Error; err != nil {
+				return nil, err
 			}
-			return nil
+			campaign = &types.Campaign{}
+			campaignModel.ToEntity(campaign)
+			campaigns[recipient.CampaignID] = campaign
 		}
 
+		//The recipient gets the message in their language, if the campaign has it
+		localizedCampaign, language := campaign.Localize(recipient.LanguageCode)
+
 		deliveryModel := &database.Delivery{
-			CampaignID: resultModel.CampaignID,
-			BotID:      botID,
-			TelegramID: resultModel.TelegramID,
+			CampaignID: recipient.CampaignID,
+			BotID:      recipient.BotID,
+			TelegramID: recipient.TelegramID,
 			State:      types.DeliveryStateProgress,
+			WorkerID:   workerID,
+			Language:   language,
+			Revision:   campaign.Revision,
 		}
-		if err := tx.Create(deliveryModel).Error; err != nil {
-			return err
+		created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(deliveryModel)
+		if err := created.Error; err != nil {
+			return nil, err
+		}
+		if created.RowsAffected == 0 {
+			//The recipient has already got this campaign
+			continue
 		}
 
-		campaignModel := &database.Campaign{}
-		if err := tx.Where("id = ?", resultModel.CampaignID).Find(campaignModel).Error; err != nil {
-			return err
+		bot, ok := bots[recipient.BotID]
+		if !ok {
+			if err := this.updateBotPossiblyEmptyStatus(tx, recipient.BotID, false); err != nil {
+				return nil, err
+			}
+			botModel := &database.Bot{}
+			if err := tx.Where("id = ?", recipient.BotID).Find(botModel).Error; err != nil {
+				return nil, err
+			}
+			bot = &types.Bot{}
+			botModel.ToEntity(bot)
+			bots[recipient.BotID] = bot
+		}
+
+		result := dao.DeliveryTakeResult{
+			Delivery: &types.Delivery{},
+			User:     &types.User{},
+			Campaign: localizedCampaign,
+			Bot:      bot,
 		}
-		campaignModel.ToEntity(result.Campaign)
 		deliveryModel.ToEntity(result.Delivery)
-		resultModel.ToEntity(result.User)
+		recipient.ToEntity(result.User)
+		if campaign.TrackLinks {
+			if result.Links, err = createTrackedLinks(tx, result.Delivery, localizedCampaign); err != nil {
+				return nil, err
+			}
+		}
+		if campaign.MediaID != 0 {
+			media, ok := medias[campaign.ID]
+			if !ok {
+				if media, err = findDeliveryMedia(tx, campaign); err != nil {
+					return nil, err
+				}
+				medias[campaign.ID] = media
+			}
+			result.FileID = media.fileID
+			result.Media = media.asset
+		}
+		results = append(results, result)
+	}
 
-		if err := this.updateBotPossiblyEmptyStatus(tx, botID, false); err != nil {
-			return err
+	if len(results) == 0 && botID != 0 {
+		if err := this.updateBotPossiblyEmptyStatus(tx, botID, true); err != nil {
+			return nil, err
 		}
+	}
 
-		return nil
-	})
+	return results, nil
+}
 
-	if err != nil {
-		return nil, err
+func (this *DeliveryDaoImplGorm) selectRecipientsFromQueue(tx *gorm.DB, botID int64, campaignID int64, telegramID int64, limit int) ([]recipient, error) {
+	if botID == 0 {
+		botIDs := []int64{}
+		if err := tx.
+			Table("bots").
+			Select("bots.id").
+			Where("bots.deleted_at IS NULL").
+			Where("bots.token_revoked = false").
+			//A bot with stale queue entries only would win and yield nothing, starving the others
+			Where("EXISTS (SELECT 1 FROM queued_recipients "+queuedRecipientJoins+" "+
+				"WHERE queued_recipients.bot_id = bots.id AND "+queuedRecipientCondition+")").
+			Order("bots.rr_access_time ASC").
+			Limit(1).
+			Pluck("id", &botIDs).Error; err != nil {
+			return nil, err
+		}
+		if len(botIDs) == 0 {
+			return nil, nil
+		}
+		botID = botIDs[0]
 	}
-	if recipientsNotFound {
-		return nil, nil
+
+	recipients := []recipient{}
+	query := tx.
+		Table("queued_recipients").
+		Select(
+			"users.*",
+			"queued_recipients.campaign_id as campaign_id",
+			"queued_recipients.id as queue_id",
+		).
+		Joins(queuedRecipientJoins).
+		Where("queued_recipients.bot_id = ?", botID).
+		Where(queuedRecipientCondition).
+		Order("queued_recipients.campaign_id DESC").
+		Order("queued_recipients.id ASC").
+		Limit(limit)
+	if campaignID != 0 {
+		query = query.Where("queued_recipients.campaign_id = ?", campaignID)
 	}
+	if telegramID != 0 {
+		query = query.Where("queued_recipients.telegram_id = ?", telegramID)
+	}
+	if err := query.Scan(&recipients).Error; err != nil {
+		return nil, err
+	}
+	return recipients, nil
+}
 
-	return result, nil
+func (this *DeliveryDaoImplGorm) selectRecipientsByJoin(tx *gorm.DB, botID int64, campaignID int64, telegramID int64, limit int) ([]recipient, error) {
+	recipients := []recipient{}
+
+	query := tx.
+		Table("users").
+		Select("users.*", "campaigns.id as campaign_id").
+		Joins("inner join bots on bots.id = users.bot_id").
+		Joins("inner join campaigns on "+
+			"campaigns.bot_id = users.bot_id "+
+			"AND (campaigns.id = ? OR 0 = ?)", campaignID, campaignID).
+		Joins(
+			"left outer join deliveries on " +
+				"deliveries.telegram_id = users.telegram_id " +
+				"AND deliveries.bot_id = users.bot_id " +
+				"AND deliveries.campaign_id = campaigns.id",
+		).
+		Where("deliveries.telegram_id IS NULL").
+		Where(audienceCondition).
+		Where("users.deleted_at IS NULL").
+		Where("users.blocked = false").
+		Where("users.unsubscribed = false").
+		Where("bots.deleted_at IS NULL").
+		Where("campaigns.deleted_at IS NULL").
+		Where("campaigns.active = true").
+		Limit(limit)
+	if botID != 0 {
+		query = query.Where("users.bot_id = ?", botID)
+	} else {
+		query = query.
+			Where("bots.token_revoked = false").
+			Order("bots.rr_access_time ASC")
+	}
+	query = query.Order("campaigns.created_at DESC")
+	if telegramID != 0 {
+		query = query.Where("users.telegram_id = ?", telegramID)
+	}
+	if err := query.Scan(&recipients).Error; err != nil {
+		return nil, err
+	}
+	return recipients, nil
 }
 
 func (dao *DeliveryDaoImplGorm) SetState(delivery *types.Delivery, state types.DeliveryState) error {
@@ -123,12 +327,7 @@ func (dao *DeliveryDaoImplGorm) SetState(delivery *types.Delivery, state types.D
 	}
 
 	return dao.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Model(&database.Delivery{}).
-			Where("bot_id = ? AND campaign_id = ? AND telegram_id = ?",
-				delivery.BotID,
-				delivery.CampaignID,
-				delivery.TelegramID).
-			Update("state", state).Error; err != nil {
+		if err := setDeliveryState(tx, delivery, state); err != nil {
 			return err
 		}
 		if state == types.DeliveryStateProgress {
@@ -141,6 +340,69 @@ func (dao *DeliveryDaoImplGorm) SetState(delivery *types.Delivery, state types.D
 	})
 }
 
+func (dao *DeliveryDaoImplGorm) SetStates(deliveries []types.Delivery) error {
+	for _, delivery := range deliveries {
+		if delivery.State != types.DeliveryStateProgress &&
+			delivery.State != types.DeliveryStateSuccess &&
+			delivery.State != types.DeliveryStateFail {
+			return errors.New("Wrong delivery state")
+		}
+	}
+
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		possiblyEmptyBots := map[int64]bool{}
+		for _, delivery := range deliveries {
+			if err := setDeliveryState(tx, &delivery, delivery.State); err != nil {
+				return err
+			}
+			if delivery.State == types.DeliveryStateProgress {
+				continue
+			}
+			possiblyEmptyBots[delivery.BotID] = possiblyEmptyBots[delivery.BotID] ||
+				delivery.State == types.DeliveryStateFail
+		}
+		for botID, isPossiblyEmpty := range possiblyEmptyBots {
+			if err := dao.updateBotPossiblyEmptyStatus(tx, botID, isPossiblyEmpty); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// setDeliveryState stores a state report.
+// Message and poll IDs are written only when reported, so that a repeated report does not erase them.
+// The delivery time is set once, when the delivery becomes successful, since updated_at changes
+// with every report and edit.
+func setDeliveryState(tx *gorm.DB, delivery *types.Delivery, state types.DeliveryState) error {
+	query := func() *gorm.DB {
+		return tx.Model(&database.Delivery{}).
+			Where("bot_id = ? AND campaign_id = ? AND telegram_id = ?",
+				delivery.BotID,
+				delivery.CampaignID,
+				delivery.TelegramID)
+	}
+	if state == types.DeliveryStateSuccess {
+		if err := query().
+			Where("state <> ?", types.DeliveryStateSuccess).
+			UpdateColumn("delivered_at", time.Now()).Error; err != nil {
+			return err
+		}
+	}
+
+	updates := map[string]interface{}{
+		"state":  state,
+		"reason": delivery.Reason,
+	}
+	if delivery.MessageID != 0 {
+		updates["message_id"] = delivery.MessageID
+	}
+	if delivery.PollID != "" {
+		updates["poll_id"] = delivery.PollID
+	}
+	return query().Updates(updates).Error
+}
+
 func (dao *DeliveryDaoImplGorm) GetState(delivery *types.Delivery) (types.DeliveryState, error) {
 	result := &struct{ State types.DeliveryState }{}
 	query := dao.db.Model(&database.Delivery{}).
@@ -155,6 +417,39 @@ func (dao *DeliveryDaoImplGorm) GetState(delivery *types.Delivery) (types.Delive
 	return result.State, nil
 }
 
+func (dao *DeliveryDaoImplGorm) Release(deliveries []types.Delivery) error {
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		botIDs := []int64{}
+		for _, delivery := range deliveries {
+			//Deliveries are removed permanently, otherwise they would still
+			//prevent their recipients from being taken again
+			deleted := tx.Unscoped().
+				Where("bot_id = ? AND campaign_id = ? AND telegram_id = ? AND state = ?",
+					delivery.BotID,
+					delivery.CampaignID,
+					delivery.TelegramID,
+					types.DeliveryStateProgress).
+				Delete(&database.Delivery{})
+			if deleted.Error != nil {
+				return deleted.Error
+			}
+			if deleted.RowsAffected == 0 {
+				//Already reported or released
+				continue
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&database.QueuedRecipient{
+				CampaignID: delivery.CampaignID,
+				BotID:      delivery.BotID,
+				TelegramID: delivery.TelegramID,
+			}).Error; err != nil {
+				return err
+			}
+			botIDs = append(botIDs, delivery.BotID)
+		}
+		return markBotsNotEmpty(tx, botIDs)
+	})
+}
+
 func (dao *DeliveryDaoImplGorm) updateBotPossiblyEmptyStatus(tx *gorm.DB, botID int64, isPossiblyEmpty bool) error {
 	if err := tx.
 		Table("bots").
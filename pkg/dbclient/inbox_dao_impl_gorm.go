@@ -0,0 +1,307 @@
+package dbclient
+
+import (
+	"errors"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/database"
+	"github.com/corporateanon/barker/pkg/pagination"
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InboxDaoImplGorm struct {
+	db *gorm.DB
+}
+
+func NewInboxDaoImplGorm(db *gorm.DB) dao.InboxDao {
+	return &InboxDaoImplGorm{
+		db: db,
+	}
+}
+
+func (dao *InboxDaoImplGorm) Receive(message *types.InboxMessage) (*types.InboxMessage, error) {
+	if message.MessageID == 0 {
+		return nil, errors.New("Message ID missing")
+	}
+	campaignIDs := []int64{}
+	if err := dao.db.Model(&database.Delivery{}).
+		Where("bot_id = ? AND telegram_id = ?", message.BotID, message.TelegramID).
+		Where("state = ?", types.DeliveryStateSuccess).
+		Order("delivered_at DESC").
+		Order("id DESC").
+		Limit(1).
+		Pluck("campaign_id", &campaignIDs).Error; err != nil {
+		return nil, err
+	}
+
+	messageModel := &database.InboxMessage{}
+	messageModel.FromEntity(message)
+	messageModel.ID = 0
+	messageModel.JobID = 0
+	messageModel.Direction = types.InboxDirectionIncoming
+	messageModel.CampaignID = 0
+	if len(campaignIDs) > 0 {
+		messageModel.CampaignID = campaignIDs[0]
+	}
+
+	received := false
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(messageModel)
+		if err := created.Error; err != nil {
+			return err
+		}
+		if created.RowsAffected == 0 {
+			//Telegram redelivers an update
+			return nil
+		}
+		received = true
+		return touchConversation(tx, messageModel)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !received {
+		return nil, nil
+	}
+
+	resultingMessage := &types.InboxMessage{}
+	messageModel.ToEntity(resultingMessage)
+	return resultingMessage, nil
+}
+
+func (dao *InboxDaoImplGorm) Reply(botID int64, telegramID int64, text string) (*types.InboxMessage, error) {
+	return dao.reply(botID, telegramID, 0, text)
+}
+
+func (dao *InboxDaoImplGorm) AutoReply(botID int64, telegramID int64, replyTo int64, text string) (*types.InboxMessage, error) {
+	if replyTo == 0 {
+		return nil, errors.New("Incoming message ID missing")
+	}
+	return dao.reply(botID, telegramID, replyTo, text)
+}
+
+func (dao *InboxDaoImplGorm) reply(botID int64, telegramID int64, replyTo int64, text string) (*types.InboxMessage, error) {
+	if text == "" {
+		return nil, errors.New("Text missing")
+	}
+	messageModel := &database.InboxMessage{}
+	queued := true
+
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("bot_id = ? AND telegram_id = ?", botID, telegramID).
+			First(&database.User{}).Error; err != nil {
+			return err
+		}
+		if replyTo != 0 {
+			found := tx.
+				Where("bot_id = ? AND telegram_id = ? AND direction = ? AND reply_to = ?",
+					botID, telegramID, types.InboxDirectionOutgoing, replyTo).
+				Limit(1).
+				Find(messageModel)
+			if found.Error != nil {
+				return found.Error
+			}
+			if found.RowsAffected > 0 {
+				queued = false
+				return nil
+			}
+		}
+
+		jobModel := &database.MessageJob{
+			BotID:      botID,
+			TelegramID: telegramID,
+			Kind:       types.MessageJobReply,
+			Text:       text,
+			State:      types.MessageJobStateQueued,
+		}
+		if err := tx.Create(jobModel).Error; err != nil {
+			return err
+		}
+
+		messageModel.BotID = botID
+		messageModel.TelegramID = telegramID
+		messageModel.Direction = types.InboxDirectionOutgoing
+		messageModel.Text = text
+		messageModel.JobID = jobModel.ID
+		messageModel.ReplyTo = replyTo
+		if err := tx.Create(messageModel).Error; err != nil {
+			return err
+		}
+		if err := touchConversation(tx, messageModel); err != nil {
+			return err
+		}
+		return markBotsNotEmpty(tx, []int64{botID})
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	resultingMessage := &types.InboxMessage{}
+	messageModel.ToEntity(resultingMessage)
+	if !queued {
+		//Queued before, it may have been sent already
+		messages := []types.InboxMessage{*resultingMessage}
+		if err := fillReplyStates(dao.db, messages); err != nil {
+			return nil, err
+		}
+		return &messages[0], nil
+	}
+	resultingMessage.State = types.MessageJobStateQueued
+	return resultingMessage, nil
+}
+
+func (dao *InboxDaoImplGorm) List(botID int64, pageRequest *types.PaginatorRequest) ([]types.Conversation, *types.PaginatorResponse, error) {
+	conversationModelsList := []database.Conversation{}
+	db := dao.db.Model(&database.Conversation{}).
+		Order("last_message_at DESC").
+		Order("id DESC").
+		Where("bot_id = ?", botID)
+	resp := pagination.Paging(&pagination.Param{
+		DB:    db,
+		Page:  int(pageRequest.Page),
+		Limit: int(pageRequest.Size),
+	}, &conversationModelsList)
+
+	if err := db.Error; err != nil {
+		return nil, nil, err
+	}
+
+	telegramIDs := make([]int64, len(conversationModelsList))
+	messageIDs := make([]int64, len(conversationModelsList))
+	for i, model := range conversationModelsList {
+		telegramIDs[i] = model.TelegramID
+		messageIDs[i] = model.LastMessageID
+	}
+
+	userModelsList := []database.User{}
+	if err := dao.db.Where("bot_id = ? AND telegram_id IN ?", botID, telegramIDs).
+		Find(&userModelsList).Error; err != nil {
+		return nil, nil, err
+	}
+	displayNames := map[int64]string{}
+	for _, model := range userModelsList {
+		displayNames[model.TelegramID] = model.DisplayName
+	}
+
+	messageModelsList := []database.InboxMessage{}
+	if err := dao.db.Where("id IN ?", messageIDs).Find(&messageModelsList).Error; err != nil {
+		return nil, nil, err
+	}
+	messagesList := make([]types.InboxMessage, len(messageModelsList))
+	for i, model := range messageModelsList {
+		model.ToEntity(&messagesList[i])
+	}
+	if err := fillReplyStates(dao.db, messagesList); err != nil {
+		return nil, nil, err
+	}
+	messages := map[int64]*types.InboxMessage{}
+	for i := range messagesList {
+		messages[messagesList[i].ID] = &messagesList[i]
+	}
+
+	conversationsList := make([]types.Conversation, len(conversationModelsList))
+	for i, model := range conversationModelsList {
+		conversationsList[i] = types.Conversation{
+			BotID:       model.BotID,
+			TelegramID:  model.TelegramID,
+			DisplayName: displayNames[model.TelegramID],
+			Messages:    model.Messages,
+			LastMessage: messages[model.LastMessageID],
+		}
+	}
+	return conversationsList,
+		&types.PaginatorResponse{
+			Page:       resp.Page,
+			Size:       resp.Limit,
+			Total:      resp.TotalPage,
+			TotalItems: resp.TotalRecord,
+		},
+		nil
+}
+
+func (dao *InboxDaoImplGorm) Conversation(botID int64, telegramID int64, pageRequest *types.PaginatorRequest) ([]types.InboxMessage, *types.PaginatorResponse, error) {
+	messageModelsList := []database.InboxMessage{}
+	db := dao.db.Model(&database.InboxMessage{}).
+		Order("id DESC").
+		Where("bot_id = ? AND telegram_id = ?", botID, telegramID)
+	resp := pagination.Paging(&pagination.Param{
+		DB:    db,
+		Page:  int(pageRequest.Page),
+		Limit: int(pageRequest.Size),
+	}, &messageModelsList)
+
+	if err := db.Error; err != nil {
+		return nil, nil, err
+	}
+
+	messagesList := make([]types.InboxMessage, len(messageModelsList))
+	for i, model := range messageModelsList {
+		model.ToEntity(&messagesList[i])
+	}
+	if err := fillReplyStates(dao.db, messagesList); err != nil {
+		return nil, nil, err
+	}
+	return messagesList,
+		&types.PaginatorResponse{
+			Page:       resp.Page,
+			Size:       resp.Limit,
+			Total:      resp.TotalPage,
+			TotalItems: resp.TotalRecord,
+		},
+		nil
+}
+
+// touchConversation counts the message in the conversation with its user and makes it the last one
+func touchConversation(tx *gorm.DB, message *database.InboxMessage) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "bot_id"}, {Name: "telegram_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"messages":        gorm.Expr("messages + 1"),
+			"last_message_id": message.ID,
+			"last_message_at": message.CreatedAt,
+			"updated_at":      message.CreatedAt,
+		}),
+	}).Create(&database.Conversation{
+		BotID:         message.BotID,
+		TelegramID:    message.TelegramID,
+		Messages:      1,
+		LastMessageID: message.ID,
+		LastMessageAt: message.CreatedAt,
+	}).Error
+}
+
+// fillReplyStates copies the state of message jobs to outgoing messages, and the ID of a sent message
+func fillReplyStates(db *gorm.DB, messages []types.InboxMessage) error {
+	jobIDs := []int64{}
+	for _, message := range messages {
+		if message.JobID != 0 {
+			jobIDs = append(jobIDs, message.JobID)
+		}
+	}
+	if len(jobIDs) == 0 {
+		return nil
+	}
+	jobModelsList := []database.MessageJob{}
+	if err := db.Where("id IN ?", jobIDs).Find(&jobModelsList).Error; err != nil {
+		return err
+	}
+	jobs := map[int64]*database.MessageJob{}
+	for i := range jobModelsList {
+		jobs[jobModelsList[i].ID] = &jobModelsList[i]
+	}
+	for i := range messages {
+		job, ok := jobs[messages[i].JobID]
+		if !ok {
+			continue
+		}
+		messages[i].State = job.State
+		messages[i].Reason = job.Reason
+		messages[i].MessageID = job.MessageID
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package dbclient
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/database"
+	"github.com/corporateanon/barker/pkg/links"
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+type LinkDaoImplGorm struct {
+	db *gorm.DB
+}
+
+func NewLinkDaoImplGorm(db *gorm.DB) dao.LinkDao {
+	return &LinkDaoImplGorm{
+		db: db,
+	}
+}
+
+func (dao *LinkDaoImplGorm) Open(code string) (*types.TrackedLink, error) {
+	linkModel := &database.TrackedLink{}
+	if err := dao.db.Where("code = ?", code).First(linkModel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := dao.db.Create(&database.LinkClick{
+		LinkID:     linkModel.Model.ID,
+		BotID:      linkModel.BotID,
+		CampaignID: linkModel.CampaignID,
+		TelegramID: linkModel.TelegramID,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	link := &types.TrackedLink{}
+	linkModel.ToEntity(link)
+	return link, nil
+}
+
+// createTrackedLinks gives every URL of the campaign message a code unique to the delivery
+func createTrackedLinks(tx *gorm.DB, delivery *types.Delivery, campaign *types.Campaign) ([]types.TrackedLink, error) {
+	urls := links.Find(campaign.Message)
+	if len(urls) == 0 {
+		return nil, nil
+	}
+	linkModelsList := make([]database.TrackedLink, len(urls))
+	for i, url := range urls {
+		code, err := newLinkCode()
+		if err != nil {
+			return nil, err
+		}
+		linkModelsList[i] = database.TrackedLink{
+			Code:       code,
+			BotID:      delivery.BotID,
+			CampaignID: delivery.CampaignID,
+			TelegramID: delivery.TelegramID,
+			URL:        url,
+		}
+	}
+	if err := tx.Create(&linkModelsList).Error; err != nil {
+		return nil, err
+	}
+
+	trackedLinks := make([]types.TrackedLink, len(linkModelsList))
+	for i, model := range linkModelsList {
+		model.ToEntity(&trackedLinks[i])
+	}
+	return trackedLinks, nil
+}
+
+func newLinkCode() (string, error) {
+	random := make([]byte, 6)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
@@ -0,0 +1,136 @@
+package dbclient
+
+import (
+	"errors"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/database"
+	"github.com/corporateanon/barker/pkg/pagination"
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MediaDaoImplGorm struct {
+	db *gorm.DB
+}
+
+func NewMediaDaoImplGorm(db *gorm.DB) dao.MediaDao {
+	return &MediaDaoImplGorm{
+		db: db,
+	}
+}
+
+func (dao *MediaDaoImplGorm) Create(asset *types.MediaAsset, content []byte) (*types.MediaAsset, error) {
+	if len(content) == 0 {
+		return nil, errors.New("Content missing")
+	}
+	if len(content) > types.MaxMediaSize {
+		return nil, errors.New("Media is too large")
+	}
+	assetModel := &database.MediaAsset{}
+	assetModel.FromEntity(asset)
+	assetModel.ID = 0
+	assetModel.Size = int64(len(content))
+	assetModel.Content = content
+	if err := dao.db.Create(assetModel).Error; err != nil {
+		return nil, err
+	}
+	resultingAsset := &types.MediaAsset{}
+	assetModel.ToEntity(resultingAsset)
+	return resultingAsset, nil
+}
+
+func (dao *MediaDaoImplGorm) Get(mediaID int64) (*types.MediaAsset, error) {
+	assetModel := &database.MediaAsset{}
+	if err := dao.db.Omit("content").Where("id = ?", mediaID).First(assetModel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	asset := &types.MediaAsset{}
+	assetModel.ToEntity(asset)
+	return asset, nil
+}
+
+func (dao *MediaDaoImplGorm) GetContent(mediaID int64) ([]byte, error) {
+	assetModel := &database.MediaAsset{}
+	if err := dao.db.Where("id = ?", mediaID).First(assetModel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return assetModel.Content, nil
+}
+
+func (dao *MediaDaoImplGorm) List(pageRequest *types.PaginatorRequest) ([]types.MediaAsset, *types.PaginatorResponse, error) {
+	assetModelsList := []database.MediaAsset{}
+	db := dao.db.Model(&database.MediaAsset{}).
+		Omit("content").
+		Order("id DESC")
+	resp := pagination.Paging(&pagination.Param{
+		DB:    db,
+		Page:  int(pageRequest.Page),
+		Limit: int(pageRequest.Size),
+	}, &assetModelsList)
+
+	if err := db.Error; err != nil {
+		return nil, nil, err
+	}
+
+	assetsList := make([]types.MediaAsset, len(assetModelsList))
+	for i, model := range assetModelsList {
+		model.ToEntity(&assetsList[i])
+	}
+	return assetsList,
+		&types.PaginatorResponse{
+			Page:       resp.Page,
+			Size:       resp.Limit,
+			Total:      resp.TotalPage,
+			TotalItems: resp.TotalRecord,
+		},
+		nil
+}
+
+func (dao *MediaDaoImplGorm) SetFileID(botID int64, mediaID int64, fileID string) error {
+	if fileID == "" {
+		return errors.New("File ID missing")
+	}
+	//A file_id reported later replaces the cached one, both are valid
+	return dao.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "media_id"}, {Name: "bot_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"file_id", "updated_at"}),
+	}).Create(&database.MediaFile{
+		MediaID: mediaID,
+		BotID:   botID,
+		FileID:  fileID,
+	}).Error
+}
+
+// deliveryMedia is the media of a campaign as its deliveries carry it
+type deliveryMedia struct {
+	fileID string
+	asset  *types.MediaAsset
+}
+
+// findDeliveryMedia returns the file_id of the campaign media for the bot of the campaign,
+// or the asset if the bot has not uploaded it yet
+func findDeliveryMedia(tx *gorm.DB, campaign *types.Campaign) (*deliveryMedia, error) {
+	fileModel := &database.MediaFile{}
+	err := tx.Where("media_id = ? AND bot_id = ?", campaign.MediaID, campaign.BotID).First(fileModel).Error
+	if err == nil {
+		return &deliveryMedia{fileID: fileModel.FileID}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	assetModel := &database.MediaAsset{}
+	if err := tx.Omit("content").Where("id = ?", campaign.MediaID).First(assetModel).Error; err != nil {
+		return nil, err
+	}
+	media := &deliveryMedia{asset: &types.MediaAsset{}}
+	assetModel.ToEntity(media.asset)
+	return media, nil
+}
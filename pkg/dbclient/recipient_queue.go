@@ -0,0 +1,160 @@
+package dbclient
+
+import (
+	"time"
+
+	"github.com/corporateanon/barker/pkg/database"
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+// audienceCondition limits the recipients of a campaign to users it targets.
+// It is used in queries which have both users and campaigns tables.
+const audienceCondition = "(campaigns.first_source = '' OR campaigns.first_source = users.first_source) " +
+	"AND (campaigns.last_source = '' OR campaigns.last_source = users.last_source) " +
+	"AND (campaigns.chat_type = 'all' " +
+	"OR (campaigns.chat_type IN ('', 'private') AND users.chat_type = 'private') " +
+	"OR (campaigns.chat_type = 'group' AND users.chat_type IN ('group', 'supergroup')) " +
+	"OR (campaigns.chat_type = 'channel' AND users.chat_type = 'channel'))"
+
+// queuedRecipientJoins and queuedRecipientCondition select queued recipients who can still get their campaign.
+// The queue may hold stale entries, e.g. of campaigns which have been deleted,
+// so the audience of the campaign is checked again too.
+const queuedRecipientJoins = "INNER JOIN users ON users.bot_id = queued_recipients.bot_id " +
+	"AND users.telegram_id = queued_recipients.telegram_id " +
+	"INNER JOIN campaigns ON campaigns.id = queued_recipients.campaign_id"
+
+const queuedRecipientCondition = "users.deleted_at IS NULL " +
+	"AND users.blocked = false " +
+	"AND users.unsubscribed = false " +
+	"AND campaigns.deleted_at IS NULL " +
+	"AND campaigns.active = true " +
+	"AND " + audienceCondition
+
+// enqueueCampaign puts all users of a bot who have not got the campaign yet into the recipient queue
+func enqueueCampaign(tx *gorm.DB, campaignID int64, botID int64) error {
+	if err := markBotsNotEmpty(tx, []int64{botID}); err != nil {
+		return err
+	}
+	return tx.Exec(
+		"INSERT INTO queued_recipients (campaign_id, bot_id, telegram_id, created_at) "+
+			"SELECT ?, users.bot_id, users.telegram_id, ? "+
+			"FROM users "+
+			"INNER JOIN campaigns ON campaigns.id = ? "+
+			"WHERE users.bot_id = ? "+
+			"AND users.deleted_at IS NULL "+
+			"AND users.blocked = false "+
+			"AND users.unsubscribed = false "+
+			"AND "+audienceCondition+" "+
+			"AND NOT EXISTS (SELECT 1 FROM deliveries WHERE "+
+			"deliveries.campaign_id = ? "+
+			"AND deliveries.bot_id = users.bot_id "+
+			"AND deliveries.telegram_id = users.telegram_id) "+
+			"AND NOT EXISTS (SELECT 1 FROM queued_recipients WHERE "+
+			"queued_recipients.campaign_id = ? "+
+			"AND queued_recipients.bot_id = users.bot_id "+
+			"AND queued_recipients.telegram_id = users.telegram_id) "+
+			"ORDER BY users.id",
+		campaignID, time.Now(), campaignID, botID, campaignID, campaignID,
+	).Error
+}
+
+// dequeueCampaign removes recipients of a campaign which is not active anymore
+func dequeueCampaign(tx *gorm.DB, campaignID int64) error {
+	return tx.
+		Where("campaign_id = ?", campaignID).
+		Delete(&database.QueuedRecipient{}).Error
+}
+
+// enqueueUser puts a new user into the recipient queues of all active campaigns of the bot
+func enqueueUser(tx *gorm.DB, botID int64, telegramID int64) error {
+	if err := markBotsNotEmpty(tx, []int64{botID}); err != nil {
+		return err
+	}
+	return tx.Exec(
+		"INSERT INTO queued_recipients (campaign_id, bot_id, telegram_id, created_at) "+
+			"SELECT campaigns.id, campaigns.bot_id, ?, ? "+
+			"FROM campaigns "+
+			"INNER JOIN users ON users.bot_id = campaigns.bot_id AND users.telegram_id = ? "+
+			"WHERE campaigns.bot_id = ? "+
+			"AND campaigns.active = true "+
+			"AND campaigns.deleted_at IS NULL "+
+			"AND "+audienceCondition+" "+
+			"AND NOT EXISTS (SELECT 1 FROM deliveries WHERE "+
+			"deliveries.campaign_id = campaigns.id "+
+			"AND deliveries.bot_id = campaigns.bot_id "+
+			"AND deliveries.telegram_id = ?) "+
+			"AND NOT EXISTS (SELECT 1 FROM queued_recipients WHERE "+
+			"queued_recipients.campaign_id = campaigns.id "+
+			"AND queued_recipients.bot_id = campaigns.bot_id "+
+			"AND queued_recipients.telegram_id = ?) "+
+			"ORDER BY campaigns.id",
+		telegramID, time.Now(), telegramID, botID, telegramID, telegramID,
+	).Error
+}
+
+// dequeueUser removes a user from the recipient queues of all campaigns of the bot
+func dequeueUser(tx *gorm.DB, botID int64, telegramID int64) error {
+	return tx.
+		Where("bot_id = ? AND telegram_id = ?", botID, telegramID).
+		Delete(&database.QueuedRecipient{}).Error
+}
+
+// requeueWorkerDeliveries puts recipients of in-progress deliveries of workers back into the queue.
+// The deliveries themselves are to be removed by the caller.
+func requeueWorkerDeliveries(tx *gorm.DB, workerIDs []string) error {
+	botIDs := []int64{}
+	if err := tx.Model(&database.Delivery{}).
+		Where("worker_id IN ? AND state = ?", workerIDs, types.DeliveryStateProgress).
+		Distinct().
+		Pluck("bot_id", &botIDs).Error; err != nil {
+		return err
+	}
+	if err := markBotsNotEmpty(tx, botIDs); err != nil {
+		return err
+	}
+	return tx.Exec(
+		"INSERT INTO queued_recipients (campaign_id, bot_id, telegram_id, created_at) "+
+			"SELECT deliveries.campaign_id, deliveries.bot_id, deliveries.telegram_id, ? "+
+			"FROM deliveries "+
+			"WHERE deliveries.worker_id IN ? "+
+			"AND deliveries.state = ? "+
+			"AND NOT EXISTS (SELECT 1 FROM queued_recipients WHERE "+
+			"queued_recipients.campaign_id = deliveries.campaign_id "+
+			"AND queued_recipients.bot_id = deliveries.bot_id "+
+			"AND queued_recipients.telegram_id = deliveries.telegram_id) "+
+			"ORDER BY deliveries.id",
+		time.Now(), workerIDs, types.DeliveryStateProgress,
+	).Error
+}
+
+// markBotsNotEmpty lets round-robin pick bots which have got new recipients right away
+func markBotsNotEmpty(tx *gorm.DB, botIDs []int64) error {
+	if len(botIDs) == 0 {
+		return nil
+	}
+	return tx.
+		Table("bots").
+		Where("id IN ?", botIDs).
+		Update("rr_possibly_empty", false).Error
+}
+
+// requeueWorkerMessageJobs returns message jobs taken by workers back to the queue
+func requeueWorkerMessageJobs(tx *gorm.DB, workerIDs []string) error {
+	botIDs := []int64{}
+	if err := tx.Model(&database.MessageJob{}).
+		Where("worker_id IN ? AND state = ?", workerIDs, types.MessageJobStateProgress).
+		Distinct().
+		Pluck("bot_id", &botIDs).Error; err != nil {
+		return err
+	}
+	if err := markBotsNotEmpty(tx, botIDs); err != nil {
+		return err
+	}
+	return tx.Model(&database.MessageJob{}).
+		Where("worker_id IN ? AND state = ?", workerIDs, types.MessageJobStateProgress).
+		Updates(map[string]interface{}{
+			"state":     types.MessageJobStateQueued,
+			"worker_id": "",
+		}).Error
+}
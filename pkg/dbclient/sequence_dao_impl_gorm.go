@@ -0,0 +1,175 @@
+package dbclient
+
+import (
+	"errors"
+	"time"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/database"
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SequenceDaoImplGorm struct {
+	db *gorm.DB
+}
+
+func NewSequenceDaoImplGorm(db *gorm.DB) dao.SequenceDao {
+	return &SequenceDaoImplGorm{
+		db: db,
+	}
+}
+
+func (dao *SequenceDaoImplGorm) Create(sequence *types.Sequence) (*types.Sequence, error) {
+	sequenceModel := &database.Sequence{}
+	sequenceModel.FromEntity(sequence)
+	sequenceModel.ID = 0
+	if err := dao.db.Create(sequenceModel).Error; err != nil {
+		return nil, err
+	}
+	resultingSequence := &types.Sequence{}
+	sequenceModel.ToEntity(resultingSequence)
+	return resultingSequence, nil
+}
+
+func (dao *SequenceDaoImplGorm) Update(sequence *types.Sequence) (*types.Sequence, error) {
+	if sequence.ID == 0 {
+		return nil, errors.New("ID missing")
+	}
+	sequenceModel := &database.Sequence{}
+	if err := dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("id = ? AND bot_id = ?", sequence.ID, sequence.BotID).
+			First(sequenceModel).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("Sequence does not exist")
+			}
+			return err
+		}
+		//Steps already queued for enrolled users are kept
+		sequenceModel.FromEntity(sequence)
+		return tx.Save(sequenceModel).Error
+	}); err != nil {
+		return nil, err
+	}
+	resultingSequence := &types.Sequence{}
+	sequenceModel.ToEntity(resultingSequence)
+	return resultingSequence, nil
+}
+
+func (dao *SequenceDaoImplGorm) Get(botID int64, sequenceID int64) (*types.Sequence, error) {
+	sequenceModel := &database.Sequence{}
+	if err := dao.db.Where("id = ? AND bot_id = ?", sequenceID, botID).First(sequenceModel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sequence := &types.Sequence{}
+	sequenceModel.ToEntity(sequence)
+	return sequence, nil
+}
+
+func (dao *SequenceDaoImplGorm) Delete(botID int64, sequenceID int64) error {
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("id = ? AND bot_id = ?", sequenceID, botID).
+			Delete(&database.Sequence{}).Error; err != nil {
+			return err
+		}
+		return tx.
+			Where("sequence_id = ? AND bot_id = ? AND kind = ? AND state = ?",
+				sequenceID, botID, types.MessageJobSequence, types.MessageJobStateQueued).
+			Delete(&database.MessageJob{}).Error
+	})
+}
+
+func (dao *SequenceDaoImplGorm) List(botID int64) ([]types.Sequence, error) {
+	sequenceModelsList := []database.Sequence{}
+	if err := dao.db.
+		Where("bot_id = ?", botID).
+		Order("id ASC").
+		Find(&sequenceModelsList).Error; err != nil {
+		return nil, err
+	}
+	sequencesList := make([]types.Sequence, len(sequenceModelsList))
+	for i, model := range sequenceModelsList {
+		model.ToEntity(&sequencesList[i])
+	}
+	return sequencesList, nil
+}
+
+func (dao *SequenceDaoImplGorm) Enroll(botID int64, sequenceID int64, telegramID int64) (*types.Sequence, error) {
+	sequence := &types.Sequence{}
+	errNotFound := errors.New("not found")
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		sequenceModel := &database.Sequence{}
+		if err := tx.Where("id = ? AND bot_id = ?", sequenceID, botID).First(sequenceModel).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errNotFound
+			}
+			return err
+		}
+		sequenceModel.ToEntity(sequence)
+
+		userModel := &database.User{}
+		if err := tx.Where("bot_id = ? AND telegram_id = ?", botID, telegramID).First(userModel).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errNotFound
+			}
+			return err
+		}
+		if userModel.Blocked || userModel.Unsubscribed {
+			return nil
+		}
+
+		enrolled := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&database.SequenceEnrollment{
+			SequenceID: sequenceID,
+			BotID:      botID,
+			TelegramID: telegramID,
+		})
+		if enrolled.Error != nil {
+			return enrolled.Error
+		}
+		if enrolled.RowsAffected == 0 {
+			//Already enrolled
+			return nil
+		}
+
+		sendAfter := time.Now()
+		jobModels := make([]database.MessageJob, len(sequence.Steps))
+		for i, step := range sequence.Steps {
+			sendAfter = sendAfter.Add(time.Duration(step.DelayMinutes) * time.Minute)
+			jobModels[i] = database.MessageJob{
+				BotID:      botID,
+				SequenceID: sequenceID,
+				TelegramID: telegramID,
+				Kind:       types.MessageJobSequence,
+				Text:       step.Message,
+				State:      types.MessageJobStateQueued,
+				SendAfter:  sendAfter,
+			}
+		}
+		if err := tx.Create(&jobModels).Error; err != nil {
+			return err
+		}
+		return markBotsNotEmpty(tx, []int64{botID})
+	})
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return sequence, nil
+}
+
+// cancelSequences drops steps of sequences which are not sent yet to a user who is excluded from campaigns.
+// The user stays enrolled, so that a rule does not start the sequence over.
+func cancelSequences(tx *gorm.DB, botID int64, telegramID int64) error {
+	return tx.
+		Where("bot_id = ? AND telegram_id = ? AND kind = ? AND state = ?",
+			botID, telegramID, types.MessageJobSequence, types.MessageJobStateQueued).
+		Delete(&database.MessageJob{}).Error
+}
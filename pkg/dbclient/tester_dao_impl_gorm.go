@@ -0,0 +1,69 @@
+package dbclient
+
+import (
+	"errors"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/database"
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TesterDaoImplGorm struct {
+	db *gorm.DB
+}
+
+func NewTesterDaoImplGorm(db *gorm.DB) dao.TesterDao {
+	return &TesterDaoImplGorm{
+		db: db,
+	}
+}
+
+func (dao *TesterDaoImplGorm) Add(botID int64, telegramID int64) (*types.Tester, error) {
+	testerModel := &database.Tester{}
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("bot_id = ? AND telegram_id = ?", botID, telegramID).
+			First(&database.User{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&database.Tester{
+			BotID:      botID,
+			TelegramID: telegramID,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("bot_id = ? AND telegram_id = ?", botID, telegramID).First(testerModel).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	tester := &types.Tester{}
+	testerModel.ToEntity(tester)
+	return tester, nil
+}
+
+func (dao *TesterDaoImplGorm) Remove(botID int64, telegramID int64) error {
+	//Removed for good, so that the tester can be added again
+	return dao.db.Unscoped().
+		Where("bot_id = ? AND telegram_id = ?", botID, telegramID).
+		Delete(&database.Tester{}).Error
+}
+
+func (dao *TesterDaoImplGorm) List(botID int64) ([]types.Tester, error) {
+	testerModelsList := []database.Tester{}
+	if err := dao.db.
+		Where("bot_id = ?", botID).
+		Order("id").
+		Find(&testerModelsList).Error; err != nil {
+		return nil, err
+	}
+	testersList := make([]types.Tester, len(testerModelsList))
+	for i, model := range testerModelsList {
+		model.ToEntity(&testersList[i])
+	}
+	return testersList, nil
+}
@@ -2,12 +2,14 @@ package dbclient
 
 import (
 	"errors"
+	"sort"
 
 	"github.com/corporateanon/barker/pkg/dao"
 	"github.com/corporateanon/barker/pkg/database"
 	"github.com/corporateanon/barker/pkg/pagination"
 	"github.com/corporateanon/barker/pkg/types"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserDaoImplGorm struct {
@@ -25,6 +27,9 @@ func (dao *UserDaoImplGorm) Put(user *types.User) (*types.User, error) {
 	resultingUser := &types.User{}
 	userModel := &database.User{}
 	userModel.FromEntity(user)
+	if userModel.FirstSource == "" {
+		userModel.FirstSource = userModel.LastSource
+	}
 
 	err := dao.db.Transaction(func(tx *gorm.DB) error {
 		existingUser := &database.User{}
@@ -39,18 +44,43 @@ func (dao *UserDaoImplGorm) Put(user *types.User) (*types.User, error) {
 			}
 			//Else: user not found (is not actually an error)
 			//We just need to create a user
+			if userModel.ChatType == "" {
+				userModel.ChatType = types.ChatTypePrivate
+			}
 			if err := tx.Create(userModel).Error; err != nil {
 				return err
 			}
 			userModel.ToEntity(resultingUser)
-			return nil
+			if userModel.Blocked || userModel.Unsubscribed {
+				return nil
+			}
+			return enqueueUser(tx, userModel.BotID, userModel.TelegramID)
 		}
-		//A user is found
-		if err := tx.Model(existingUser).Updates(userModel).Error; err != nil {
+		//A user is found. Whether they are blocked or unsubscribed is changed by SetBlocked and SetUnsubscribed only.
+		omitted := []string{"blocked", "unsubscribed"}
+		//The first source is never replaced. Empty fields, e.g. the last source of a user who comes without a deep link
+		//or the chat type of a recipient put by a client which does not know it, are not updated.
+		if existingUser.FirstSource != "" {
+			omitted = append(omitted, "first_source")
+		}
+		targeted := *existingUser
+		if err := tx.Model(existingUser).Omit(omitted...).Updates(userModel).Error; err != nil {
 			return err
 		}
 		existingUser.ToEntity(resultingUser)
-		return nil
+		if existingUser.Blocked || existingUser.Unsubscribed {
+			return nil
+		}
+		//Campaigns target users by their sources and chat type, so the user is queued anew when any of them changes
+		if targeted.FirstSource == existingUser.FirstSource &&
+			targeted.LastSource == existingUser.LastSource &&
+			targeted.ChatType == existingUser.ChatType {
+			return nil
+		}
+		if err := dequeueUser(tx, existingUser.BotID, existingUser.TelegramID); err != nil {
+			return err
+		}
+		return enqueueUser(tx, existingUser.BotID, existingUser.TelegramID)
 	})
 
 	if err != nil {
@@ -70,9 +100,12 @@ func (dao *UserDaoImplGorm) Get(botID int64, telegramID int64) (*types.User, err
 		}
 		return nil, err
 	}
-	user := &types.User{}
-	userModel.ToEntity(user)
-	return user, nil
+	usersList := make([]types.User, 1)
+	userModel.ToEntity(&usersList[0])
+	if err := fillUserTags(dao.db, botID, usersList); err != nil {
+		return nil, err
+	}
+	return &usersList[0], nil
 }
 
 func (dao *UserDaoImplGorm) List(botID int64, pageRequest *types.PaginatorRequest) ([]types.User, *types.PaginatorResponse, error) {
@@ -94,6 +127,9 @@ func (dao *UserDaoImplGorm) List(botID int64, pageRequest *types.PaginatorReques
 	for i, model := range userModelsList {
 		model.ToEntity(&usersList[i])
 	}
+	if err := fillUserTags(dao.db, botID, usersList); err != nil {
+		return nil, nil, err
+	}
 	return usersList,
 		&types.PaginatorResponse{
 			Page:       resp.Page,
@@ -103,3 +139,209 @@ func (dao *UserDaoImplGorm) List(botID int64, pageRequest *types.PaginatorReques
 		},
 		nil
 }
+
+func (dao *UserDaoImplGorm) SetBlocked(botID int64, telegramID int64, blocked bool) (*types.User, error) {
+	return dao.setExcluded(botID, telegramID, "blocked", func(userModel *database.User) {
+		userModel.Blocked = blocked
+	})
+}
+
+func (dao *UserDaoImplGorm) SetUnsubscribed(botID int64, telegramID int64, unsubscribed bool) (*types.User, error) {
+	return dao.setExcluded(botID, telegramID, "unsubscribed", func(userModel *database.User) {
+		userModel.Unsubscribed = unsubscribed
+	})
+}
+
+// setExcluded changes a flag which excludes the user from campaigns,
+// removing them from the recipient queues or putting them back
+func (dao *UserDaoImplGorm) setExcluded(botID int64, telegramID int64, column string, set func(userModel *database.User)) (*types.User, error) {
+	user := &types.User{}
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		userModel := &database.User{}
+		if err := tx.Where("bot_id=? AND telegram_id=?", botID, telegramID).
+			First(userModel).Error; err != nil {
+			return err
+		}
+		wasExcluded := userModel.Blocked || userModel.Unsubscribed
+		set(userModel)
+		if err := tx.Model(userModel).Select(column).Updates(userModel).Error; err != nil {
+			return err
+		}
+		isExcluded := userModel.Blocked || userModel.Unsubscribed
+		if !wasExcluded && isExcluded {
+			if err := dequeueUser(tx, botID, telegramID); err != nil {
+				return err
+			}
+			if err := cancelSequences(tx, botID, telegramID); err != nil {
+				return err
+			}
+		} else if wasExcluded && !isExcluded {
+			if err := enqueueUser(tx, botID, telegramID); err != nil {
+				return err
+			}
+		}
+		userModel.ToEntity(user)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// migratedModels are the models which refer to a recipient by its Telegram ID
+var migratedModels = []interface{}{
+	&database.UserTag{},
+	&database.QueuedRecipient{},
+	&database.Delivery{},
+	&database.MessageJob{},
+	&database.SequenceEnrollment{},
+	&database.Click{},
+	&database.TrackedLink{},
+	&database.LinkClick{},
+	&database.Conversion{},
+	&database.Answer{},
+	&database.InboxMessage{},
+	&database.Conversation{},
+	&database.Tester{},
+}
+
+func (dao *UserDaoImplGorm) Migrate(botID int64, telegramID int64, newTelegramID int64) (*types.User, error) {
+	if newTelegramID == 0 || newTelegramID == telegramID {
+		return nil, errors.New("Wrong new Telegram ID")
+	}
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		userModel := &database.User{}
+		if err := tx.Where("bot_id=? AND telegram_id=?", botID, telegramID).
+			First(userModel).Error; err != nil {
+			return err
+		}
+
+		existingCount := int64(0)
+		if err := tx.Model(&database.User{}).
+			Where("bot_id=? AND telegram_id=?", botID, newTelegramID).
+			Count(&existingCount).Error; err != nil {
+			return err
+		}
+		if existingCount > 0 {
+			//The new chat is known already, e.g. the migration is reported twice, so the old one is just dropped
+			if err := dequeueUser(tx, botID, telegramID); err != nil {
+				return err
+			}
+			return tx.Delete(userModel).Error
+		}
+
+		userModel.TelegramID = newTelegramID
+		userModel.ChatType = types.ChatTypeSupergroup
+		if err := tx.Model(userModel).Select("telegram_id", "chat_type").Updates(userModel).Error; err != nil {
+			return err
+		}
+		for _, model := range migratedModels {
+			if err := tx.Model(model).
+				Where("bot_id = ? AND telegram_id = ?", botID, telegramID).
+				Update("telegram_id", newTelegramID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return dao.Get(botID, newTelegramID)
+}
+
+func (dao *UserDaoImplGorm) AddTag(botID int64, telegramID int64, tag string) (*types.User, error) {
+	if tag == "" {
+		return nil, errors.New("Tag missing")
+	}
+	user, err := dao.Get(botID, telegramID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	if err := dao.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&database.UserTag{
+		BotID:      botID,
+		TelegramID: telegramID,
+		Tag:        tag,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return dao.Get(botID, telegramID)
+}
+
+// fillUserTags sets tags of users of a bot, ordered by name
+func fillUserTags(db *gorm.DB, botID int64, users []types.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	byTelegramID := map[int64]*types.User{}
+	telegramIDs := make([]int64, len(users))
+	for i := range users {
+		byTelegramID[users[i].TelegramID] = &users[i]
+		telegramIDs[i] = users[i].TelegramID
+	}
+	tagModelsList := []database.UserTag{}
+	if err := db.
+		Where("bot_id = ? AND telegram_id IN ?", botID, telegramIDs).
+		Order("tag").
+		Find(&tagModelsList).Error; err != nil {
+		return err
+	}
+	for _, model := range tagModelsList {
+		if user, ok := byTelegramID[model.TelegramID]; ok {
+			user.Tags = append(user.Tags, model.Tag)
+		}
+	}
+	return nil
+}
+
+func (dao *UserDaoImplGorm) Sources(botID int64) ([]types.SourceUsers, error) {
+	bySource := map[string]*types.SourceUsers{}
+	sources := []string{}
+	count := func(column string, set func(sourceUsers *types.SourceUsers, users int64)) error {
+		rows := []struct {
+			Source string
+			Users  int64
+		}{}
+		if err := dao.db.Model(&database.User{}).
+			Select(column+" AS source, COUNT(*) AS users").
+			Where("bot_id = ?", botID).
+			Group(column).
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			sourceUsers, ok := bySource[row.Source]
+			if !ok {
+				sourceUsers = &types.SourceUsers{Source: row.Source}
+				bySource[row.Source] = sourceUsers
+				sources = append(sources, row.Source)
+			}
+			set(sourceUsers, row.Users)
+		}
+		return nil
+	}
+	if err := count("first_source", func(sourceUsers *types.SourceUsers, users int64) {
+		sourceUsers.FirstTouch = users
+	}); err != nil {
+		return nil, err
+	}
+	if err := count("last_source", func(sourceUsers *types.SourceUsers, users int64) {
+		sourceUsers.LastTouch = users
+	}); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(sources)
+	sourceUsersList := make([]types.SourceUsers, len(sources))
+	for i, source := range sources {
+		sourceUsersList[i] = *bySource[source]
+	}
+	return sourceUsersList, nil
+}
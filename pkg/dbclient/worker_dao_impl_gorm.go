@@ -0,0 +1,149 @@
+package dbclient
+
+import (
+	"errors"
+	"time"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/database"
+	"github.com/corporateanon/barker/pkg/types"
+	"gorm.io/gorm"
+)
+
+type WorkerDaoImplGorm struct {
+	db *gorm.DB
+}
+
+func NewWorkerDaoImplGorm(db *gorm.DB) dao.WorkerDao {
+	return &WorkerDaoImplGorm{
+		db: db,
+	}
+}
+
+func (dao *WorkerDaoImplGorm) Register(worker *types.Worker) (*types.Worker, error) {
+	if worker.ID == "" {
+		return nil, errors.New("ID missing")
+	}
+	workerModel := &database.Worker{}
+
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("worker_id = ?", worker.ID).First(workerModel).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+		workerModel.FromEntity(worker)
+		workerModel.LastHeartbeat = time.Now()
+		return tx.Save(workerModel).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resultingWorker := &types.Worker{}
+	workerModel.ToEntity(resultingWorker)
+	return resultingWorker, nil
+}
+
+func (dao *WorkerDaoImplGorm) Heartbeat(worker *types.Worker) (*types.Worker, error) {
+	if worker.ID == "" {
+		return nil, errors.New("ID missing")
+	}
+	workerModel := &database.Worker{}
+
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("worker_id = ?", worker.ID).First(workerModel).Error; err != nil {
+			return err
+		}
+		workerModel.FromEntity(worker)
+		workerModel.LastHeartbeat = time.Now()
+		return tx.Save(workerModel).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resultingWorker := &types.Worker{}
+	workerModel.ToEntity(resultingWorker)
+	return resultingWorker, nil
+}
+
+func (dao *WorkerDaoImplGorm) Get(ID string) (*types.Worker, error) {
+	workerModel := &database.Worker{}
+
+	if err := dao.db.Where("worker_id = ?", ID).First(workerModel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	resultingWorker := &types.Worker{}
+	workerModel.ToEntity(resultingWorker)
+	return resultingWorker, nil
+}
+
+func (this *WorkerDaoImplGorm) List() ([]types.Worker, error) {
+	workerModelsList := []database.Worker{}
+	if err := this.db.
+		Where("last_heartbeat >= ?", time.Now().Add(-dao.WorkerTimeout)).
+		Order("worker_id ASC").
+		Find(&workerModelsList).Error; err != nil {
+		return nil, err
+	}
+
+	workersList := make([]types.Worker, len(workerModelsList))
+	for i, model := range workerModelsList {
+		model.ToEntity(&workersList[i])
+	}
+	return workersList, nil
+}
+
+func (dao *WorkerDaoImplGorm) ReleaseExpired(timeout time.Duration) ([]types.Worker, error) {
+	workerModelsList := []database.Worker{}
+
+	err := dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("last_heartbeat < ?", time.Now().Add(-timeout)).
+			Find(&workerModelsList).Error; err != nil {
+			return err
+		}
+		if len(workerModelsList) == 0 {
+			return nil
+		}
+
+		workerIDs := make([]string, len(workerModelsList))
+		for i, model := range workerModelsList {
+			workerIDs[i] = model.WorkerID
+		}
+
+		if err := requeueWorkerDeliveries(tx, workerIDs); err != nil {
+			return err
+		}
+		//Deliveries are removed permanently, otherwise they would still
+		//prevent their recipients from being taken again
+		if err := tx.Unscoped().
+			Where("worker_id IN ? AND state = ?", workerIDs, types.DeliveryStateProgress).
+			Delete(&database.Delivery{}).Error; err != nil {
+			return err
+		}
+		if err := requeueWorkerMessageJobs(tx, workerIDs); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().
+			Where("worker_id IN ?", workerIDs).
+			Delete(&database.Worker{}).Error; err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	workersList := make([]types.Worker, len(workerModelsList))
+	for i, model := range workerModelsList {
+		model.ToEntity(&workersList[i])
+	}
+	return workersList, nil
+}
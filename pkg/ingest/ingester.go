@@ -0,0 +1,281 @@
+package ingest
+
+import (
+	"log"
+	"strings"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/telegram"
+	"github.com/corporateanon/barker/pkg/types"
+)
+
+// Ingester keeps the audience of a bot in sync with incoming Telegram updates,
+// whether they come from a webhook or from polling
+type Ingester struct {
+	userDao     dao.UserDao
+	clickDao    dao.ClickDao
+	answerDao   dao.AnswerDao
+	inboxDao    dao.InboxDao
+	rulesDao    dao.AutoResponderDao
+	sequenceDao dao.SequenceDao
+	telegram    *telegram.Client
+}
+
+func NewIngester(
+	userDao dao.UserDao,
+	clickDao dao.ClickDao,
+	answerDao dao.AnswerDao,
+	inboxDao dao.InboxDao,
+	rulesDao dao.AutoResponderDao,
+	sequenceDao dao.SequenceDao,
+	telegramClient *telegram.Client,
+) *Ingester {
+	return &Ingester{
+		userDao:     userDao,
+		clickDao:    clickDao,
+		answerDao:   answerDao,
+		inboxDao:    inboxDao,
+		rulesDao:    rulesDao,
+		sequenceDao: sequenceDao,
+		telegram:    telegramClient,
+	}
+}
+
+// Ingest registers the author of a private message with the deep-link source of /start, stores the message in the inbox and applies
+// the first matching auto-responder rule to it, tracks users who block or unblock the bot,
+// registers groups and channels the bot is added to and follows migrations of groups to supergroups,
+// records clicks of campaign buttons and answers to surveys and polls.
+// Updates of other kinds are ignored.
+func (ingester *Ingester) Ingest(bot *types.Bot, update *telegram.Update) error {
+	botID := bot.ID
+	if query := update.CallbackQuery; query != nil {
+		return ingester.callbackQuery(bot, query)
+	}
+	if pollAnswer := update.PollAnswer; pollAnswer != nil {
+		return ingester.pollAnswer(bot, pollAnswer)
+	}
+	if message := update.Message; message != nil {
+		if message.MigrateToChatID != 0 && message.Chat != nil {
+			_, err := ingester.userDao.Migrate(botID, message.Chat.ID, message.MigrateToChatID)
+			return err
+		}
+		if message.From == nil || !isPrivate(message.Chat) {
+			return nil
+		}
+		//Writing to the bot, e.g. sending /start, means it is not blocked anymore
+		if err := ingester.putUser(botID, message.From, false, startPayload(message.Text)); err != nil {
+			return err
+		}
+		text := message.Text
+		if text == "" {
+			text = message.Caption
+		}
+		if _, err := ingester.inboxDao.Receive(&types.InboxMessage{
+			BotID:      botID,
+			TelegramID: message.From.ID,
+			MessageID:  message.MessageID,
+			Text:       text,
+		}); err != nil {
+			return err
+		}
+		if text == "" {
+			return nil
+		}
+		//A redelivered message is responded to again, in case the response has failed.
+		//The actions are idempotent and the reply is queued once.
+		return ingester.respond(botID, message.From.ID, message.MessageID, text)
+	}
+	if member := update.MyChatMember; member != nil {
+		if member.Chat == nil || member.NewChatMember == nil {
+			return nil
+		}
+		if !isPrivate(member.Chat) {
+			return ingester.chatMember(botID, member.Chat, member.NewChatMember.Status)
+		}
+		if member.From == nil {
+			return nil
+		}
+		switch member.NewChatMember.Status {
+		case telegram.ChatMemberStatusKicked:
+			return ingester.putUser(botID, member.From, true, "")
+		case telegram.ChatMemberStatusMember:
+			return ingester.putUser(botID, member.From, false, "")
+		}
+	}
+	return nil
+}
+
+func (ingester *Ingester) callbackQuery(bot *types.Bot, query *telegram.CallbackQuery) error {
+	if query.From == nil {
+		return nil
+	}
+	if campaignID, variant, button, ok := types.ParseCallbackData(query.Data); ok {
+		if _, err := ingester.clickDao.Add(&types.Click{
+			BotID:           bot.ID,
+			CampaignID:      campaignID,
+			TelegramID:      recipientID(query),
+			Variant:         variant,
+			Button:          button,
+			CallbackQueryID: query.ID,
+		}); err != nil {
+			return err
+		}
+	} else if campaignID, option, ok := types.ParseAnswerCallbackData(query.Data); ok {
+		if _, err := ingester.answerDao.Put(&types.Answer{
+			BotID:      bot.ID,
+			CampaignID: campaignID,
+			TelegramID: recipientID(query),
+			Option:     option,
+		}); err != nil {
+			return err
+		}
+	} else {
+		//Not a campaign button
+		return nil
+	}
+	//The click is stored, so failing to answer the query must not make Telegram redeliver the update
+	if err := ingester.telegram.AnswerCallbackQuery(bot.Token, &telegram.AnswerCallbackQueryRequest{
+		CallbackQueryID: query.ID,
+	}); err != nil {
+		log.Printf("Failed to answer callback query %s of bot %d: %s", query.ID, bot.ID, err)
+	}
+	return nil
+}
+
+func (ingester *Ingester) pollAnswer(bot *types.Bot, pollAnswer *telegram.PollAnswer) error {
+	if pollAnswer.User == nil {
+		return nil
+	}
+	answer := &types.Answer{
+		BotID:      bot.ID,
+		PollID:     pollAnswer.PollID,
+		TelegramID: pollAnswer.User.ID,
+	}
+	if len(pollAnswer.OptionIDs) == 0 {
+		return ingester.answerDao.Retract(answer)
+	}
+	//Polls of campaigns allow a single option
+	answer.Option = pollAnswer.OptionIDs[0]
+	_, err := ingester.answerDao.Put(answer)
+	return err
+}
+
+// respond applies the first auto-responder rule matching the text, its reply is queued like inbox replies
+// and the steps of its sequence are queued after it
+func (ingester *Ingester) respond(botID int64, telegramID int64, messageID int64, text string) error {
+	rules, err := ingester.rulesDao.List(botID)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if !rule.Matches(text) {
+			continue
+		}
+		if rule.AddTag != "" {
+			if _, err := ingester.userDao.AddTag(botID, telegramID, rule.AddTag); err != nil {
+				return err
+			}
+		}
+		if rule.Unsubscribe {
+			if _, err := ingester.userDao.SetUnsubscribed(botID, telegramID, true); err != nil {
+				return err
+			}
+		}
+		if _, err := ingester.inboxDao.AutoReply(botID, telegramID, messageID, rule.Reply); err != nil {
+			return err
+		}
+		//The sequence starts after the reply. A deleted sequence is not an error, the rule just has nothing to enroll in.
+		if rule.EnrollSequenceID != 0 {
+			if _, err := ingester.sequenceDao.Enroll(botID, rule.EnrollSequenceID, telegramID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// chatMember registers a group or a channel the bot has been added to, and blocks it when the bot is removed
+func (ingester *Ingester) chatMember(botID int64, chat *telegram.Chat, status string) error {
+	var blocked bool
+	switch status {
+	case telegram.ChatMemberStatusMember, telegram.ChatMemberStatusAdministrator:
+		blocked = false
+	case telegram.ChatMemberStatusLeft, telegram.ChatMemberStatusKicked:
+		blocked = true
+	default:
+		return nil
+	}
+	return ingester.putRecipient(&types.User{
+		BotID:       botID,
+		TelegramID:  chat.ID,
+		DisplayName: chat.Title,
+		UserName:    chat.UserName,
+		Blocked:     blocked,
+		ChatType:    chat.Type,
+	})
+}
+
+func (ingester *Ingester) putUser(botID int64, from *telegram.User, blocked bool, source string) error {
+	return ingester.putRecipient(&types.User{
+		BotID:        botID,
+		TelegramID:   from.ID,
+		FirstName:    from.FirstName,
+		LastName:     from.LastName,
+		DisplayName:  displayName(from),
+		UserName:     from.UserName,
+		Blocked:      blocked,
+		LastSource:   source,
+		ChatType:     types.ChatTypePrivate,
+		LanguageCode: from.LanguageCode,
+	})
+}
+
+func (ingester *Ingester) putRecipient(recipient *types.User) error {
+	user, err := ingester.userDao.Put(recipient)
+	if err != nil {
+		return err
+	}
+	if user.Blocked == recipient.Blocked {
+		return nil
+	}
+	_, err = ingester.userDao.SetBlocked(recipient.BotID, recipient.TelegramID, recipient.Blocked)
+	return err
+}
+
+// recipientID is the chat which has got the campaign with the pressed button.
+// In groups and channels it is the chat rather than the user who has pressed the button.
+func recipientID(query *telegram.CallbackQuery) int64 {
+	if query.Message != nil && query.Message.Chat != nil {
+		return query.Message.Chat.ID
+	}
+	return query.From.ID
+}
+
+func isPrivate(chat *telegram.Chat) bool {
+	return chat != nil && chat.Type == types.ChatTypePrivate
+}
+
+func displayName(from *telegram.User) string {
+	return strings.TrimSpace(from.FirstName + " " + from.LastName)
+}
+
+// maxStartPayloadLength is the limit of Telegram for deep-link payloads
+const maxStartPayloadLength = 64
+
+// startPayload returns the payload of a deep link t.me/bot?start=<payload>,
+// which Telegram sends as "/start <payload>"
+func startPayload(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) != 2 {
+		return ""
+	}
+	command := fields[0]
+	if at := strings.Index(command, "@"); at >= 0 {
+		command = command[:at]
+	}
+	if command != "/start" || len(fields[1]) > maxStartPayloadLength {
+		return ""
+	}
+	return fields[1]
+}
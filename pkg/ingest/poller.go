@@ -0,0 +1,209 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/notify"
+	"github.com/corporateanon/barker/pkg/telegram"
+	"github.com/corporateanon/barker/pkg/types"
+)
+
+// Updates the ingester understands, others are not requested
+var allowedUpdates = []string{"message", "my_chat_member", "callback_query", "poll_answer"}
+
+type PollerOptions struct {
+	//Long polling timeout of getUpdates, must be shorter than the Telegram client timeout
+	PollTimeout time.Duration
+	//How often the list of bots is reloaded to follow mode switches
+	RefreshInterval time.Duration
+	//Pause after a failed getUpdates
+	ErrorDelay time.Duration
+}
+
+// Poller fetches updates with getUpdates for every bot in polling mode
+// and feeds them into the Ingester
+type Poller struct {
+	botDao   dao.BotDao
+	ingester *Ingester
+	telegram *telegram.Client
+	notifier *notify.Notifier
+	options  PollerOptions
+
+	//Running loops by bot ID
+	mutex sync.Mutex
+	loops map[int64]*pollLoop
+}
+
+type pollLoop struct {
+	token  string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewPoller(
+	botDao dao.BotDao,
+	ingester *Ingester,
+	telegramClient *telegram.Client,
+	notifier *notify.Notifier,
+	options PollerOptions,
+) *Poller {
+	if options.PollTimeout <= 0 {
+		options.PollTimeout = 25 * time.Second
+	}
+	if options.RefreshInterval <= 0 {
+		options.RefreshInterval = 30 * time.Second
+	}
+	if options.ErrorDelay <= 0 {
+		options.ErrorDelay = 5 * time.Second
+	}
+	return &Poller{
+		botDao:   botDao,
+		ingester: ingester,
+		telegram: telegramClient,
+		notifier: notifier,
+		options:  options,
+		loops:    map[int64]*pollLoop{},
+	}
+}
+
+// Run polls until ctx is cancelled. Bots switched to or from polling mode are picked up
+// on the next refresh.
+func (p *Poller) Run(ctx context.Context) {
+	defer p.stopAll()
+	for {
+		if err := p.refresh(ctx); err != nil {
+			log.Printf("Failed to load bots for polling: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.options.RefreshInterval):
+		}
+	}
+}
+
+// refresh starts loops of bots in polling mode and stops the others
+func (p *Poller) refresh(ctx context.Context) error {
+	bots, err := p.listBots()
+	if err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	polled := map[int64]types.Bot{}
+	for _, bot := range bots {
+		if bot.IngestionMode == types.IngestionModePolling {
+			polled[bot.ID] = bot
+		}
+	}
+	for botID, loop := range p.loops {
+		if bot, ok := polled[botID]; !ok || bot.Token != loop.token {
+			loop.stop()
+			delete(p.loops, botID)
+		}
+	}
+	for botID, bot := range polled {
+		if _, ok := p.loops[botID]; ok {
+			continue
+		}
+		loopCtx, cancel := context.WithCancel(ctx)
+		loop := &pollLoop{token: bot.Token, cancel: cancel, done: make(chan struct{})}
+		p.loops[botID] = loop
+		go func(bot types.Bot) {
+			defer close(loop.done)
+			p.poll(loopCtx, bot)
+		}(bot)
+	}
+	return nil
+}
+
+func (p *Poller) listBots() ([]types.Bot, error) {
+	bots := []types.Bot{}
+	for page := int64(1); ; page++ {
+		pageBots, pageResponse, err := p.botDao.List(&types.PaginatorRequest{Page: page, Size: 100})
+		if err != nil {
+			return nil, err
+		}
+		bots = append(bots, pageBots...)
+		if pageResponse == nil || page >= int64(pageResponse.Total) {
+			return bots, nil
+		}
+	}
+}
+
+// poll fetches updates of a single bot. The offset is saved after every batch,
+// so a restart continues where it has stopped.
+func (p *Poller) poll(ctx context.Context, bot types.Bot) {
+	offset := bot.UpdateOffset
+	for ctx.Err() == nil {
+		updates, err := p.telegram.GetUpdates(ctx, bot.Token, &telegram.GetUpdatesRequest{
+			Offset:         offset,
+			Timeout:        int(p.options.PollTimeout / time.Second),
+			AllowedUpdates: allowedUpdates,
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			var telegramErr *telegram.Error
+			if errors.As(err, &telegramErr) && telegramErr.Code == http.StatusConflict {
+				//A webhook left from the webhook mode blocks getUpdates
+				log.Printf("Deleting the webhook of bot %d, it is in polling mode", bot.ID)
+				err = p.telegram.DeleteWebhook(bot.Token)
+			}
+			if err != nil {
+				log.Printf("Failed to get updates of bot %d: %s", bot.ID, err)
+				p.wait(ctx, p.options.ErrorDelay)
+			}
+			continue
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		var ingestErr error
+		for _, update := range updates {
+			if ingestErr = p.ingester.Ingest(&bot, &update); ingestErr != nil {
+				log.Printf("Failed to ingest update %d of bot %d: %s", update.UpdateID, bot.ID, ingestErr)
+				break
+			}
+			offset = update.UpdateID + 1
+		}
+		if err := p.botDao.SetUpdateOffset(bot.ID, offset); err != nil {
+			log.Printf("Failed to save the update offset of bot %d: %s", bot.ID, err)
+		}
+		p.notifier.Notify()
+		if ingestErr != nil {
+			//The update is fetched again after the pause
+			p.wait(ctx, p.options.ErrorDelay)
+		}
+	}
+}
+
+func (p *Poller) wait(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+func (p *Poller) stopAll() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for botID, loop := range p.loops {
+		loop.stop()
+		delete(p.loops, botID)
+	}
+}
+
+func (loop *pollLoop) stop() {
+	loop.cancel()
+	<-loop.done
+}
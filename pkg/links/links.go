@@ -0,0 +1,38 @@
+// Package links finds URLs in campaign messages, so that they can be replaced with tracked ones
+package links
+
+import (
+	"regexp"
+	"strings"
+)
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"']+`)
+
+// Punctuation which usually ends a sentence rather than a URL
+const trailingPunctuation = ".,;:!?)]}"
+
+// Find returns distinct URLs of a message in order of appearance
+func Find(message string) []string {
+	urls := []string{}
+	seen := map[string]bool{}
+	for _, match := range urlPattern.FindAllString(message, -1) {
+		url := trimURL(match)
+		if !seen[url] {
+			seen[url] = true
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// Rewrite replaces every URL of a message with the result of replace
+func Rewrite(message string, replace func(url string) string) string {
+	return urlPattern.ReplaceAllStringFunc(message, func(match string) string {
+		url := trimURL(match)
+		return replace(url) + match[len(url):]
+	})
+}
+
+func trimURL(match string) string {
+	return strings.TrimRight(match, trailingPunctuation)
+}
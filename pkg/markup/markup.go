@@ -0,0 +1,346 @@
+// Package markup checks messages against the formatting rules and the limits of the Telegram Bot API
+package markup
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Parse modes of the Bot API. An empty parse mode sends the text as is.
+const (
+	ParseModeHTML       = "HTML"
+	ParseModeMarkdownV2 = "MarkdownV2"
+)
+
+// Limits of the Bot API, in UTF-16 code units of the text after entity parsing
+const (
+	MaxMessageLength      = 4096
+	MaxCaptionLength      = 1024
+	MaxPollQuestionLength = 300
+)
+
+// Parse returns the text which Telegram shows for a message, with the markup of the parse mode removed.
+// It fails if Telegram would reject the markup.
+func Parse(text string, parseMode string) (string, error) {
+	switch parseMode {
+	case "":
+		return text, nil
+	case ParseModeHTML:
+		return parseHTML(text)
+	case ParseModeMarkdownV2:
+		return parseMarkdownV2(text)
+	default:
+		return "", fmt.Errorf("Unknown parse mode %s", parseMode)
+	}
+}
+
+// Length is the length of a text as Telegram counts it
+func Length(text string) int {
+	return len(utf16.Encode([]rune(text)))
+}
+
+// Validate checks the markup of a message and its length after entity parsing
+func Validate(text string, parseMode string, maxLength int) error {
+	plainText, err := Parse(text, parseMode)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(plainText) == "" {
+		return errors.New("Message is empty")
+	}
+	if length := Length(plainText); length > maxLength {
+		return fmt.Errorf("Message is too long: %d characters, at most %d", length, maxLength)
+	}
+	return nil
+}
+
+// CheckURL checks a URL of a button or a text link. Telegram accepts http, https and tg links.
+func CheckURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("Invalid URL %q", rawURL)
+	}
+	switch parsed.Scheme {
+	case "http", "https", "tg":
+		return nil
+	default:
+		return fmt.Errorf("Invalid URL %q: only http, https and tg links are allowed", rawURL)
+	}
+}
+
+// Tags of the HTML parse mode, by the attribute they require
+var htmlTags = map[string]string{
+	"b":          "",
+	"strong":     "",
+	"i":          "",
+	"em":         "",
+	"u":          "",
+	"ins":        "",
+	"s":          "",
+	"strike":     "",
+	"del":        "",
+	"tg-spoiler": "",
+	"span":       "class",
+	"a":          "href",
+	"tg-emoji":   "emoji-id",
+	"code":       "",
+	"pre":        "",
+	"blockquote": "",
+}
+
+var htmlEntities = map[string]string{
+	"lt":   "<",
+	"gt":   ">",
+	"amp":  "&",
+	"quot": "\"",
+}
+
+var htmlTagPattern = regexp.MustCompile(`^<(/?)([a-z-]+)((?:\s+[a-z-]+\s*=\s*(?:"[^"]*"|'[^']*'|[^\s>"']+))*)\s*>`)
+var htmlAttributePattern = regexp.MustCompile(`([a-z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>"']+))`)
+
+func parseHTML(text string) (string, error) {
+	plainText := strings.Builder{}
+	openTags := []string{}
+	for i := 0; i < len(text); {
+		switch text[i] {
+		case '<':
+			match := htmlTagPattern.FindStringSubmatch(text[i:])
+			if match == nil {
+				return "", fmt.Errorf("Unescaped '<' at byte %d, use &lt;", i)
+			}
+			closing, name := match[1] == "/", match[2]
+			required, ok := htmlTags[name]
+			if !ok {
+				return "", fmt.Errorf("Unsupported tag <%s>", name)
+			}
+			if closing {
+				if len(openTags) == 0 || openTags[len(openTags)-1] != name {
+					return "", fmt.Errorf("Unexpected closing tag </%s>", name)
+				}
+				openTags = openTags[:len(openTags)-1]
+			} else {
+				attributes := map[string]string{}
+				for _, attribute := range htmlAttributePattern.FindAllStringSubmatch(match[3], -1) {
+					attributes[attribute[1]] = attribute[2] + attribute[3] + attribute[4]
+				}
+				if err := checkHTMLAttributes(name, required, attributes); err != nil {
+					return "", err
+				}
+				openTags = append(openTags, name)
+			}
+			i += len(match[0])
+		case '&':
+			end := strings.IndexByte(text[i:], ';')
+			if end < 0 {
+				return "", fmt.Errorf("Unescaped '&' at byte %d, use &amp;", i)
+			}
+			decoded, err := decodeHTMLEntity(text[i+1 : i+end])
+			if err != nil {
+				return "", err
+			}
+			plainText.WriteString(decoded)
+			i += end + 1
+		default:
+			plainText.WriteByte(text[i])
+			i++
+		}
+	}
+	if len(openTags) > 0 {
+		return "", fmt.Errorf("Unclosed tag <%s>", openTags[len(openTags)-1])
+	}
+	return plainText.String(), nil
+}
+
+func checkHTMLAttributes(name string, required string, attributes map[string]string) error {
+	if required == "" {
+		return nil
+	}
+	value, ok := attributes[required]
+	if !ok {
+		return fmt.Errorf("Tag <%s> needs the %s attribute", name, required)
+	}
+	switch name {
+	case "span":
+		if value != "tg-spoiler" {
+			return errors.New("Tag <span> is only allowed with class=\"tg-spoiler\"")
+		}
+	case "a":
+		return CheckURL(value)
+	}
+	return nil
+}
+
+func decodeHTMLEntity(entity string) (string, error) {
+	if decoded, ok := htmlEntities[entity]; ok {
+		return decoded, nil
+	}
+	if strings.HasPrefix(entity, "#") {
+		var code int64
+		var err error
+		if strings.HasPrefix(entity, "#x") || strings.HasPrefix(entity, "#X") {
+			code, err = strconv.ParseInt(entity[2:], 16, 32)
+		} else {
+			code, err = strconv.ParseInt(entity[1:], 10, 32)
+		}
+		if err == nil && code > 0 {
+			return string(rune(code)), nil
+		}
+	}
+	return "", fmt.Errorf("Unsupported HTML entity &%s;", entity)
+}
+
+// Characters which must be escaped with '\' in MarkdownV2 outside of code
+const markdownV2Reserved = "_*[]()~`>#+-=|{}.!"
+
+func parseMarkdownV2(text string) (string, error) {
+	runes := []rune(text)
+	plainText := strings.Builder{}
+	openEntities := []string{}
+	toggle := func(marker string) error {
+		for i := len(openEntities) - 1; i >= 0; i-- {
+			if openEntities[i] != marker {
+				continue
+			}
+			if i != len(openEntities)-1 {
+				return fmt.Errorf("Entity '%s' is closed before the nested '%s'", marker, openEntities[len(openEntities)-1])
+			}
+			openEntities = openEntities[:i]
+			return nil
+		}
+		openEntities = append(openEntities, marker)
+		return nil
+	}
+
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		next := func(offset int) rune {
+			if i+offset < len(runes) {
+				return runes[i+offset]
+			}
+			return 0
+		}
+		switch {
+		case c == '\\':
+			if next(1) == 0 || next(1) > 126 {
+				return "", fmt.Errorf("Only ASCII characters can be escaped, at character %d", i)
+			}
+			plainText.WriteRune(next(1))
+			i++
+		case c == '`':
+			delimiter := "`"
+			if next(1) == '`' && next(2) == '`' {
+				delimiter = "```"
+			}
+			code, end, err := readMarkdownV2Code(runes, i+len(delimiter), delimiter)
+			if err != nil {
+				return "", err
+			}
+			plainText.WriteString(code)
+			i = end
+		case c == '*' || c == '~':
+			if err := toggle(string(c)); err != nil {
+				return "", err
+			}
+		case c == '_':
+			marker := "_"
+			if next(1) == '_' {
+				marker = "__"
+				i++
+			}
+			if err := toggle(marker); err != nil {
+				return "", err
+			}
+		case c == '|' && next(1) == '|':
+			if err := toggle("||"); err != nil {
+				return "", err
+			}
+			i++
+		case c == '[':
+			openEntities = append(openEntities, "[")
+		case c == ']' && len(openEntities) > 0 && openEntities[len(openEntities)-1] == "[":
+			openEntities = openEntities[:len(openEntities)-1]
+			if next(1) != '(' {
+				return "", fmt.Errorf("Link text at character %d is not followed by (URL)", i)
+			}
+			end, err := readMarkdownV2URL(runes, i+2)
+			if err != nil {
+				return "", err
+			}
+			i = end
+		case c == '>' && (i == 0 || runes[i-1] == '\n'):
+			//Block quotation
+		case strings.ContainsRune(markdownV2Reserved, c):
+			return "", fmt.Errorf("Character '%c' at character %d must be escaped with '\\'", c, i)
+		default:
+			plainText.WriteRune(c)
+		}
+	}
+	if len(openEntities) > 0 {
+		return "", fmt.Errorf("Unclosed entity '%s'", openEntities[len(openEntities)-1])
+	}
+	return plainText.String(), nil
+}
+
+// readMarkdownV2Code reads code or a pre-formatted block up to the closing delimiter.
+// Only '`' and '\' are escaped inside. The language of a block is not a part of its text.
+func readMarkdownV2Code(runes []rune, start int, delimiter string) (string, int, error) {
+	if delimiter == "```" {
+		if newline := indexRune(runes, start, '\n'); newline >= 0 && !strings.ContainsAny(string(runes[start:newline]), " `\\") {
+			start = newline + 1
+		}
+	}
+	code := strings.Builder{}
+	for i := start; i < len(runes); i++ {
+		switch {
+		case runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == '`' || runes[i+1] == '\\'):
+			code.WriteRune(runes[i+1])
+			i++
+		case hasPrefix(runes, i, delimiter):
+			return code.String(), i + len(delimiter) - 1, nil
+		default:
+			code.WriteRune(runes[i])
+		}
+	}
+	return "", 0, fmt.Errorf("Unclosed entity '%s'", delimiter)
+}
+
+// readMarkdownV2URL reads the URL of a link up to the closing ')', only ')' and '\' are escaped inside
+func readMarkdownV2URL(runes []rune, start int) (int, error) {
+	link := strings.Builder{}
+	for i := start; i < len(runes); i++ {
+		switch {
+		case runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == ')' || runes[i+1] == '\\'):
+			link.WriteRune(runes[i+1])
+			i++
+		case runes[i] == ')':
+			return i, CheckURL(link.String())
+		default:
+			link.WriteRune(runes[i])
+		}
+	}
+	return 0, errors.New("Unclosed link URL")
+}
+
+func hasPrefix(runes []rune, start int, prefix string) bool {
+	for _, r := range prefix {
+		if start >= len(runes) || runes[start] != r {
+			return false
+		}
+		start++
+	}
+	return true
+}
+
+func indexRune(runes []rune, start int, r rune) int {
+	for i := start; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
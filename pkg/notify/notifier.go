@@ -0,0 +1,30 @@
+package notify
+
+import "sync"
+
+// Notifier wakes up everyone waiting for new work in this process
+type Notifier struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func NewNotifier() *Notifier {
+	return &Notifier{
+		ch: make(chan struct{}),
+	}
+}
+
+// Wait returns a channel which is closed by the next Notify call.
+// Call it before checking for work, so that a notification is not missed.
+func (n *Notifier) Wait() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.ch
+}
+
+func (n *Notifier) Notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	close(n.ch)
+	n.ch = make(chan struct{})
+}
@@ -0,0 +1,73 @@
+package sender
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/corporateanon/barker/pkg/telegram"
+	"github.com/corporateanon/barker/pkg/types"
+)
+
+// errTokenRevoked stops sending for a bot whose token Telegram has rejected
+var errTokenRevoked = errors.New("The bot token has been revoked")
+
+// isTokenRevoked tells whether Telegram has rejected the bot token.
+// It is an error of the bot rather than of a recipient, so it is not classified.
+func isTokenRevoked(err error) bool {
+	var telegramErr *telegram.Error
+	return errors.As(err, &telegramErr) && telegramErr.Code == http.StatusUnauthorized
+}
+
+// classify turns the result of a send into a delivery state and a reason.
+// retry tells whether the same message may succeed if it is sent again later.
+func classify(err error) (state types.DeliveryState, reason string, retry bool) {
+	if err == nil {
+		return types.DeliveryStateSuccess, "", false
+	}
+	var telegramErr *telegram.Error
+	if !errors.As(err, &telegramErr) {
+		//Network errors and timeouts
+		return types.DeliveryStateFail, types.DeliveryReasonTemporaryError, true
+	}
+	description := strings.ToLower(telegramErr.Description)
+	switch {
+	case telegramErr.Code == http.StatusTooManyRequests:
+		return types.DeliveryStateFail, types.DeliveryReasonRateLimited, true
+	case telegramErr.Code >= http.StatusInternalServerError:
+		return types.DeliveryStateFail, types.DeliveryReasonTemporaryError, true
+	case telegramErr.Code == http.StatusForbidden:
+		if strings.Contains(description, "blocked") {
+			return types.DeliveryStateFail, types.DeliveryReasonBlocked, false
+		}
+		if strings.Contains(description, "deactivated") {
+			return types.DeliveryStateFail, types.DeliveryReasonDeactivated, false
+		}
+		return types.DeliveryStateFail, types.DeliveryReasonForbidden, false
+	case strings.Contains(description, "chat not found"):
+		return types.DeliveryStateFail, types.DeliveryReasonChatNotFound, false
+	default:
+		return types.DeliveryStateFail, types.DeliveryReasonBadRequest, false
+	}
+}
+
+// classifyMessageChange is classify for edits and deletions of delivered messages
+func classifyMessageChange(err error) (state types.MessageJobState, reason string, retry bool) {
+	var telegramErr *telegram.Error
+	if errors.As(err, &telegramErr) {
+		description := strings.ToLower(telegramErr.Description)
+		switch {
+		//The message already has the new text, e.g. after a retry
+		case strings.Contains(description, "message is not modified"):
+			return types.MessageJobStateSuccess, "", false
+		case strings.Contains(description, "message to edit not found"),
+			strings.Contains(description, "message to delete not found"):
+			return types.MessageJobStateFail, types.DeliveryReasonMessageNotFound, false
+		}
+	}
+	deliveryState, reason, retry := classify(err)
+	if deliveryState == types.DeliveryStateSuccess {
+		return types.MessageJobStateSuccess, reason, retry
+	}
+	return types.MessageJobStateFail, reason, retry
+}
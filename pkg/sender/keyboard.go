@@ -0,0 +1,51 @@
+package sender
+
+import (
+	"sort"
+
+	"github.com/corporateanon/barker/pkg/telegram"
+	"github.com/corporateanon/barker/pkg/types"
+)
+
+// Every recipient of a campaign gets the same message so far
+const defaultVariant = 0
+
+// inlineKeyboard puts survey options one per row, followed by campaign buttons laid out by rows.
+// Callback data identifies the option or the button, so answers and clicks can be attributed
+// to the campaign.
+func inlineKeyboard(campaignID int64, variant int, surveyOptions []string, buttons []types.CampaignButton) *telegram.InlineKeyboardMarkup {
+	if len(surveyOptions) == 0 && len(buttons) == 0 {
+		return nil
+	}
+	markup := &telegram.InlineKeyboardMarkup{}
+	for i, option := range surveyOptions {
+		markup.InlineKeyboard = append(markup.InlineKeyboard, []telegram.InlineKeyboardButton{{
+			Text:         option,
+			CallbackData: types.EncodeAnswerCallbackData(campaignID, i),
+		}})
+	}
+
+	rows := map[int][]telegram.InlineKeyboardButton{}
+	rowNumbers := []int{}
+	for i, button := range buttons {
+		if _, ok := rows[button.Row]; !ok {
+			rowNumbers = append(rowNumbers, button.Row)
+		}
+		if button.URL != "" {
+			rows[button.Row] = append(rows[button.Row], telegram.InlineKeyboardButton{
+				Text: button.Text,
+				URL:  button.URL,
+			})
+			continue
+		}
+		rows[button.Row] = append(rows[button.Row], telegram.InlineKeyboardButton{
+			Text:         button.Text,
+			CallbackData: types.EncodeCallbackData(campaignID, variant, i),
+		})
+	}
+	sort.Ints(rowNumbers)
+	for _, row := range rowNumbers {
+		markup.InlineKeyboard = append(markup.InlineKeyboard, rows[row])
+	}
+	return markup
+}
@@ -0,0 +1,58 @@
+package sender
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter spaces out requests of each bot, so that a bot does not exceed the Telegram broadcasting limits
+type limiter struct {
+	interval time.Duration
+	mutex    sync.Mutex
+	next     map[int64]time.Time
+}
+
+func newLimiter(rate float64) *limiter {
+	return &limiter{
+		interval: time.Duration(float64(time.Second) / rate),
+		next:     map[int64]time.Time{},
+	}
+}
+
+// wait blocks until the bot is allowed to make a request
+func (l *limiter) wait(ctx context.Context, botID int64) error {
+	l.mutex.Lock()
+	now := time.Now()
+	at := l.next[botID]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[botID] = at.Add(l.interval)
+	l.mutex.Unlock()
+
+	return sleep(ctx, time.Until(at))
+}
+
+// pause postpones all the requests of the bot, e.g. when Telegram asks to retry later
+func (l *limiter) pause(botID int64, d time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if at := time.Now().Add(d); l.next[botID].Before(at) {
+		l.next[botID] = at
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,551 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/links"
+	"github.com/corporateanon/barker/pkg/telegram"
+	"github.com/corporateanon/barker/pkg/types"
+)
+
+type Options struct {
+	WorkerID string
+	Version  string
+	Host     string
+	//How many deliveries or message jobs are taken at once
+	BatchSize int
+	//Messages per second per bot
+	RateLimit float64
+	//How many times a message is resent after a rate limit or a temporary error
+	MaxRetries int
+	//Delay before a retry of a temporary error, doubled on each attempt
+	RetryDelay time.Duration
+	//How long to rest when there is nothing to send
+	IdleWait time.Duration
+	//How often the worker reports that it is alive
+	HeartbeatInterval time.Duration
+	//Public base URL of barker for tracked links, e.g. https://barker.example.com.
+	//Without it the original URLs are sent.
+	LinkBaseURL string
+}
+
+// Sender takes deliveries from barker and sends them to the Telegram Bot API.
+// It also edits and deletes delivered messages on behalf of campaign operations.
+type Sender struct {
+	botDao       dao.BotDao
+	deliveryDao  dao.DeliveryDao
+	workerDao    dao.WorkerDao
+	operationDao dao.CampaignOperationDao
+	mediaDao     dao.MediaDao
+	telegram     *telegram.Client
+	options      Options
+	limiter      *limiter
+	//file_ids of media uploaded by this sender, until deliveries carry them.
+	//Used by RunOnce only, which never runs concurrently.
+	fileIDs map[mediaFile]string
+	//States of deliveries and message jobs which are processed but not stored yet.
+	//Their messages are sent, so they are reported again instead of being released.
+	unreported     []types.Delivery
+	unreportedJobs []types.MessageJob
+
+	mutex    sync.Mutex
+	botID    int64
+	sent     int
+	sentFrom time.Time
+}
+
+func NewSender(
+	botDao dao.BotDao,
+	deliveryDao dao.DeliveryDao,
+	workerDao dao.WorkerDao,
+	operationDao dao.CampaignOperationDao,
+	mediaDao dao.MediaDao,
+	telegramClient *telegram.Client,
+	options Options,
+) *Sender {
+	if options.BatchSize <= 0 {
+		options.BatchSize = 100
+	}
+	if options.RateLimit <= 0 {
+		options.RateLimit = 25
+	}
+	if options.MaxRetries <= 0 {
+		options.MaxRetries = 3
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = time.Second
+	}
+	if options.IdleWait <= 0 {
+		options.IdleWait = 5 * time.Second
+	}
+	if options.HeartbeatInterval <= 0 {
+		options.HeartbeatInterval = dao.WorkerTimeout / 4
+	}
+	return &Sender{
+		botDao:       botDao,
+		deliveryDao:  deliveryDao,
+		workerDao:    workerDao,
+		operationDao: operationDao,
+		mediaDao:     mediaDao,
+		telegram:     telegramClient,
+		options:      options,
+		limiter:      newLimiter(options.RateLimit),
+		fileIDs:      map[mediaFile]string{},
+		sentFrom:     time.Now(),
+	}
+}
+
+// Run sends messages until ctx is cancelled.
+// The message being sent is finished and the states of the messages sent so far are reported.
+// Deliveries and message jobs which were taken but not processed are returned to the queue.
+// States which barker has failed to store are retried until it is reachable again.
+func (s *Sender) Run(ctx context.Context) error {
+	if _, err := s.workerDao.Register(s.worker()); err != nil {
+		return err
+	}
+
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		s.heartbeat(ctx)
+	}()
+	defer func() { <-heartbeatDone }()
+
+	for ctx.Err() == nil {
+		sent, err := s.RunOnce(ctx)
+		if err != nil {
+			log.Printf("Failed to send a batch: %s", err)
+		}
+		if err != nil || sent == 0 {
+			sleep(ctx, s.options.IdleWait)
+		}
+	}
+	if err := s.flushReports(); err != nil {
+		log.Printf("Failed to report states: %s", err)
+	}
+	return nil
+}
+
+// RunOnce takes a batch of work of the least recently served bot, processes it
+// and reports the states. Message jobs go before deliveries, so that a recalled
+// or corrected message is fixed as soon as possible.
+// It returns the number of deliveries or jobs processed.
+// If ctx is cancelled, the unprocessed rest of the batch is returned to the queue.
+// If the bot token turns out to be revoked, the bot is flagged and its unsent work is returned to the queue.
+func (s *Sender) RunOnce(ctx context.Context) (int, error) {
+	//Nothing new is taken until the states of the previous batch are stored
+	if err := s.flushReports(); err != nil {
+		return 0, err
+	}
+
+	bot, err := s.botDao.RRTake()
+	if err != nil {
+		return 0, err
+	}
+	if bot == nil {
+		return 0, nil
+	}
+	s.setBot(bot.ID)
+	defer s.setBot(0)
+
+	messageJobs, err := s.operationDao.TakeJobs(s.options.WorkerID, bot.ID, s.options.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(messageJobs) > 0 {
+		return s.runMessageJobs(ctx, bot, messageJobs)
+	}
+
+	jobs, err := s.deliveryDao.TakeBatch(s.options.WorkerID, bot.ID, s.options.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	deliveries := make([]types.Delivery, 0, len(jobs))
+	unsent := []types.Delivery{}
+	revoked := false
+	for i, job := range jobs {
+		err := ctx.Err()
+		var state types.DeliveryState
+		var reason string
+		var message *telegram.Message
+		if err == nil {
+			state, reason, message, err = s.send(ctx, bot, &job)
+		}
+		if err != nil {
+			//Interrupted or the token is revoked, the rest of the batch returns to the queue
+			revoked = errors.Is(err, errTokenRevoked)
+			for _, job := range jobs[i:] {
+				unsent = append(unsent, *job.Delivery)
+			}
+			break
+		}
+		delivery := *job.Delivery
+		delivery.State = state
+		delivery.Reason = reason
+		if message != nil {
+			delivery.MessageID = message.MessageID
+			if message.Poll != nil {
+				delivery.PollID = message.Poll.ID
+			}
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	s.unreported = append(s.unreported, deliveries...)
+	if len(unsent) > 0 {
+		if err := s.release(bot, revoked, func() error { return s.deliveryDao.Release(unsent) }); err != nil {
+			return 0, err
+		}
+	}
+	if err := s.report(ctx); err != nil {
+		return 0, err
+	}
+	return len(deliveries), nil
+}
+
+// release returns the work taken but not processed to the queue, flagging the bot first if its token is revoked
+func (s *Sender) release(bot *types.Bot, revoked bool, release func() error) error {
+	if revoked {
+		return s.revokeToken(bot, release)
+	}
+	return release()
+}
+
+// revokeToken flags a bot whose token Telegram has rejected and returns the work taken for it to the queue,
+// so that it is sent once the bot is validated with a new token
+func (s *Sender) revokeToken(bot *types.Bot, release func() error) error {
+	log.Printf("The token of bot %d has been revoked, its messages are returned to the queue", bot.ID)
+	revokeErr := s.botDao.SetTokenRevoked(bot.ID)
+	if err := release(); err != nil {
+		return err
+	}
+	return revokeErr
+}
+
+// report stores the states processed so far, retrying like a send
+func (s *Sender) report(ctx context.Context) error {
+	delay := s.options.RetryDelay
+	for attempt := 0; ; attempt++ {
+		err := s.flushReports()
+		if err == nil || attempt >= s.options.MaxRetries || sleep(ctx, delay) != nil {
+			return err
+		}
+		delay *= 2
+	}
+}
+
+// flushReports stores the states of processed deliveries and message jobs.
+// Those that fail to be stored are kept for the next attempt.
+func (s *Sender) flushReports() error {
+	if len(s.unreported) > 0 {
+		if err := s.deliveryDao.SetStates(s.unreported); err != nil {
+			return err
+		}
+		s.unreported = nil
+	}
+	if len(s.unreportedJobs) > 0 {
+		if err := s.operationDao.SetJobStates(s.unreportedJobs); err != nil {
+			return err
+		}
+		s.unreportedJobs = nil
+	}
+	return nil
+}
+
+// send delivers a single message, retrying rate limits and temporary errors.
+// An error is returned only if ctx is cancelled before the outcome is known
+// or if the bot token has been revoked.
+func (s *Sender) send(ctx context.Context, bot *types.Bot, job *dao.DeliveryTakeResult) (types.DeliveryState, string, *telegram.Message, error) {
+	delay := s.options.RetryDelay
+	chatID := job.Delivery.TelegramID
+	for attempt := 0; ; attempt++ {
+		if err := s.limiter.wait(ctx, bot.ID); err != nil {
+			return 0, "", nil, err
+		}
+		message, sendErr := s.sendCampaign(bot, job, chatID)
+		if isTokenRevoked(sendErr) {
+			return 0, "", nil, errTokenRevoked
+		}
+		var telegramErr *telegram.Error
+		if errors.As(sendErr, &telegramErr) && telegramErr.MigrateToChatID != 0 && telegramErr.MigrateToChatID != chatID {
+			//The group has become a supergroup, the recipient is moved to its new ID when the migration is ingested
+			chatID = telegramErr.MigrateToChatID
+			continue
+		}
+		state, reason, retry := classify(sendErr)
+		if state == types.DeliveryStateSuccess {
+			s.countSent()
+			return state, reason, message, nil
+		}
+		if !retry || attempt >= s.options.MaxRetries {
+			return state, reason, nil, nil
+		}
+
+		if telegramErr != nil && telegramErr.RetryAfter > 0 {
+			s.limiter.pause(bot.ID, telegramErr.RetryAfter)
+		} else {
+			s.limiter.pause(bot.ID, delay)
+			delay *= 2
+		}
+	}
+}
+
+func (s *Sender) sendCampaign(bot *types.Bot, job *dao.DeliveryTakeResult, chatID int64) (*telegram.Message, error) {
+	campaign := job.Campaign
+	if campaign.Kind == types.CampaignKindPoll {
+		options := make([]telegram.InputPollOption, len(campaign.Options))
+		for i, option := range campaign.Options {
+			options[i] = telegram.InputPollOption{Text: option}
+		}
+		return s.telegram.SendPoll(bot.Token, &telegram.SendPollRequest{
+			ChatID:   chatID,
+			Question: campaign.Message,
+			Options:  options,
+		})
+	}
+
+	var surveyOptions []string
+	if campaign.Kind == types.CampaignKindSurvey {
+		surveyOptions = campaign.Options
+	}
+	replyMarkup := inlineKeyboard(campaign.ID, defaultVariant, surveyOptions, campaign.Buttons)
+	if job.FileID != "" || job.Media != nil {
+		return s.sendPhoto(bot, job, &telegram.SendPhotoRequest{
+			ChatID:      chatID,
+			Caption:     s.message(job),
+			ParseMode:   campaign.ParseMode,
+			ReplyMarkup: replyMarkup,
+		})
+	}
+	return s.telegram.SendMessage(bot.Token, &telegram.SendMessageRequest{
+		ChatID:      chatID,
+		Text:        s.message(job),
+		ParseMode:   campaign.ParseMode,
+		ReplyMarkup: replyMarkup,
+	})
+}
+
+// mediaFile identifies the file_id of a media asset for a bot
+type mediaFile struct {
+	botID   int64
+	mediaID int64
+}
+
+// sendPhoto sends the photo of a campaign by its file_id, or uploads it if the bot has none yet.
+// The file_id of an upload is reported to barker, so that the next deliveries carry it.
+func (s *Sender) sendPhoto(bot *types.Bot, job *dao.DeliveryTakeResult, request *telegram.SendPhotoRequest) (*telegram.Message, error) {
+	request.Photo = job.FileID
+	if request.Photo == "" {
+		request.Photo = s.fileIDs[mediaFile{bot.ID, job.Media.ID}]
+	}
+	if request.Photo != "" {
+		return s.telegram.SendPhoto(bot.Token, request)
+	}
+
+	content, err := s.mediaDao.GetContent(job.Media.ID)
+	if err != nil {
+		return nil, err
+	}
+	message, err := s.telegram.UploadPhoto(bot.Token, request, &telegram.InputFile{
+		FileName: job.Media.FileName,
+		Content:  content,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(message.Photo) > 0 {
+		//The largest size is the original photo
+		fileID := message.Photo[len(message.Photo)-1].FileID
+		s.fileIDs[mediaFile{bot.ID, job.Media.ID}] = fileID
+		if err := s.mediaDao.SetFileID(bot.ID, job.Media.ID, fileID); err != nil {
+			log.Printf("Failed to report file_id of media %d of bot %d: %s", job.Media.ID, bot.ID, err)
+		}
+	}
+	return message, nil
+}
+
+// message replaces URLs of the campaign message with tracked links of the delivery
+func (s *Sender) message(job *dao.DeliveryTakeResult) string {
+	if len(job.Links) == 0 || s.options.LinkBaseURL == "" {
+		return job.Campaign.Message
+	}
+	codes := map[string]string{}
+	for _, link := range job.Links {
+		codes[link.URL] = link.Code
+	}
+	return links.Rewrite(job.Campaign.Message, func(url string) string {
+		code, ok := codes[url]
+		if !ok {
+			return url
+		}
+		return strings.TrimRight(s.options.LinkBaseURL, "/") + "/l/" + code
+	})
+}
+
+func (s *Sender) runMessageJobs(ctx context.Context, bot *types.Bot, jobs []types.MessageJob) (int, error) {
+	done := make([]types.MessageJob, 0, len(jobs))
+	unprocessed := []types.MessageJob{}
+	revoked := false
+	for i, job := range jobs {
+		err := ctx.Err()
+		var state types.MessageJobState
+		var reason string
+		if err == nil {
+			state, reason, err = s.changeMessage(ctx, bot, &job)
+		}
+		if err != nil {
+			//Interrupted or the token is revoked, the rest of the batch returns to the queue
+			revoked = errors.Is(err, errTokenRevoked)
+			unprocessed = append(unprocessed, jobs[i:]...)
+			break
+		}
+		job.State = state
+		job.Reason = reason
+		done = append(done, job)
+	}
+
+	s.unreportedJobs = append(s.unreportedJobs, done...)
+	if len(unprocessed) > 0 {
+		if err := s.release(bot, revoked, func() error { return s.operationDao.ReleaseJobs(unprocessed) }); err != nil {
+			return 0, err
+		}
+	}
+	if err := s.report(ctx); err != nil {
+		return 0, err
+	}
+	return len(done), nil
+}
+
+// changeMessage edits or deletes a delivered message, or sends a reply, retrying like send.
+// It fails like send, if ctx is cancelled or the bot token has been revoked.
+func (s *Sender) changeMessage(ctx context.Context, bot *types.Bot, job *types.MessageJob) (types.MessageJobState, string, error) {
+	delay := s.options.RetryDelay
+	for attempt := 0; ; attempt++ {
+		if err := s.limiter.wait(ctx, bot.ID); err != nil {
+			return 0, "", err
+		}
+		var changeErr error
+		switch job.Kind {
+		case types.CampaignOperationEdit:
+			replyMarkup := inlineKeyboard(job.CampaignID, defaultVariant, job.SurveyOptions, job.Buttons)
+			if job.Caption {
+				_, changeErr = s.telegram.EditMessageCaption(bot.Token, &telegram.EditMessageCaptionRequest{
+					ChatID:      job.TelegramID,
+					MessageID:   job.MessageID,
+					Caption:     job.Text,
+					ParseMode:   job.ParseMode,
+					ReplyMarkup: replyMarkup,
+				})
+				break
+			}
+			_, changeErr = s.telegram.EditMessageText(bot.Token, &telegram.EditMessageTextRequest{
+				ChatID:      job.TelegramID,
+				MessageID:   job.MessageID,
+				Text:        job.Text,
+				ParseMode:   job.ParseMode,
+				ReplyMarkup: replyMarkup,
+			})
+		case types.CampaignOperationRecall:
+			changeErr = s.telegram.DeleteMessage(bot.Token, &telegram.DeleteMessageRequest{
+				ChatID:    job.TelegramID,
+				MessageID: job.MessageID,
+			})
+		case types.MessageJobReply, types.MessageJobSequence:
+			var message *telegram.Message
+			message, changeErr = s.telegram.SendMessage(bot.Token, &telegram.SendMessageRequest{
+				ChatID: job.TelegramID,
+				Text:   job.Text,
+			})
+			if changeErr == nil {
+				job.MessageID = message.MessageID
+			}
+		case types.CampaignOperationTest:
+			if job.Campaign == nil {
+				return types.MessageJobStateFail, types.DeliveryReasonCampaignNotFound, nil
+			}
+			var message *telegram.Message
+			message, changeErr = s.sendCampaign(bot, &dao.DeliveryTakeResult{
+				Campaign: job.Campaign,
+				FileID:   job.FileID,
+				Media:    job.Media,
+			}, job.TelegramID)
+			if changeErr == nil {
+				job.MessageID = message.MessageID
+			}
+		default:
+			return types.MessageJobStateFail, types.DeliveryReasonUnknownOperation, nil
+		}
+		if isTokenRevoked(changeErr) {
+			return 0, "", errTokenRevoked
+		}
+		state, reason, retry := classifyMessageChange(changeErr)
+		if !retry || attempt >= s.options.MaxRetries {
+			return state, reason, nil
+		}
+
+		var telegramErr *telegram.Error
+		if errors.As(changeErr, &telegramErr) && telegramErr.RetryAfter > 0 {
+			s.limiter.pause(bot.ID, telegramErr.RetryAfter)
+		} else {
+			s.limiter.pause(bot.ID, delay)
+			delay *= 2
+		}
+	}
+}
+
+func (s *Sender) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(s.options.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := s.workerDao.Heartbeat(s.worker()); err != nil {
+			//The worker may have been released after a long pause, register it again
+			if _, err := s.workerDao.Register(s.worker()); err != nil {
+				log.Printf("Failed to send a heartbeat: %s", err)
+			}
+		}
+	}
+}
+
+// worker describes the sender and resets the throughput counter
+func (s *Sender) worker() *types.Worker {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	throughput := 0.0
+	if elapsed := now.Sub(s.sentFrom).Seconds(); elapsed > 0 {
+		throughput = float64(s.sent) / elapsed
+	}
+	s.sent = 0
+	s.sentFrom = now
+	return &types.Worker{
+		ID:         s.options.WorkerID,
+		Version:    s.options.Version,
+		Host:       s.options.Host,
+		BotID:      s.botID,
+		Throughput: throughput,
+	}
+}
+
+func (s *Sender) setBot(botID int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.botID = botID
+}
+
+func (s *Sender) countSent() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sent++
+}
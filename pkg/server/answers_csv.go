@@ -0,0 +1,34 @@
+package server
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/corporateanon/barker/pkg/types"
+)
+
+// writeAnswersCSV exports raw answers of a survey or a poll campaign, one row per user
+func writeAnswersCSV(w io.Writer, campaign *types.Campaign, answers []types.Answer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"TelegramID", "Option", "OptionText", "AnsweredAt"}); err != nil {
+		return err
+	}
+	for _, answer := range answers {
+		optionText := ""
+		if answer.Option < len(campaign.Options) {
+			optionText = campaign.Options[answer.Option]
+		}
+		if err := writer.Write([]string{
+			strconv.FormatInt(answer.TelegramID, 10),
+			strconv.Itoa(answer.Option),
+			optionText,
+			answer.AnsweredAt.UTC().Format(time.RFC3339),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
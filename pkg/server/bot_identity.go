@@ -0,0 +1,43 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/corporateanon/barker/pkg/telegram"
+	"github.com/corporateanon/barker/pkg/types"
+	"github.com/gin-gonic/gin"
+)
+
+// identifyBot checks the token of a bot with getMe and fills in the bot's Telegram identity
+func identifyBot(telegramClient *telegram.Client, bot *types.Bot) error {
+	me, err := telegramClient.GetMe(bot.Token)
+	if err != nil {
+		return err
+	}
+	bot.TelegramID = me.ID
+	bot.TelegramUserName = me.UserName
+	bot.CanJoinGroups = me.CanJoinGroups
+	bot.CanReadAllGroupMessages = me.CanReadAllGroupMessages
+	bot.SupportsInlineQueries = me.SupportsInlineQueries
+	bot.TokenRevoked = false
+	bot.ValidatedAt = time.Now()
+	return nil
+}
+
+// isInvalidToken tells whether Telegram has rejected a bot token.
+// Malformed tokens get 404, revoked ones get 401.
+func isInvalidToken(err error) bool {
+	var telegramErr *telegram.Error
+	return errors.As(err, &telegramErr) &&
+		(telegramErr.Code == http.StatusUnauthorized || telegramErr.Code == http.StatusNotFound)
+}
+
+func respondIdentifyError(c *gin.Context, err error) {
+	if isInvalidToken(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bot token"})
+		return
+	}
+	c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to validate the bot token: " + err.Error()})
+}
@@ -0,0 +1,17 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/corporateanon/barker/pkg/dao"
+)
+
+// errorStatus is 409 for requests which do not fit the current state of the data, 500 for the rest
+func errorStatus(err error) int {
+	conflict := &dao.ConflictError{}
+	if errors.As(err, &conflict) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
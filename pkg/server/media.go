@@ -0,0 +1,61 @@
+package server
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/types"
+	"github.com/gin-gonic/gin"
+)
+
+// checkCampaignMedia rejects a campaign with a photo which is not in the media library or which cannot have one
+func checkCampaignMedia(mediaDao dao.MediaDao, campaign *types.Campaign) error {
+	if campaign.MediaID == 0 {
+		return nil
+	}
+	if campaign.Kind == types.CampaignKindPoll {
+		return errors.New("Polls cannot have media")
+	}
+	asset, err := mediaDao.Get(campaign.MediaID)
+	if err != nil {
+		return err
+	}
+	if asset == nil {
+		return errors.New("Media not found")
+	}
+	return nil
+}
+
+// readUpload reads the file of a multipart form. The content type is taken from the ContentType field,
+// or detected if it is missing.
+func readUpload(c *gin.Context) (*types.MediaAsset, []byte, error) {
+	fileHeader, err := c.FormFile("File")
+	if err != nil {
+		return nil, nil, err
+	}
+	if fileHeader.Size > types.MaxMediaSize {
+		return nil, nil, errors.New("Media is too large")
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(content) == 0 {
+		return nil, nil, errors.New("File is empty")
+	}
+	asset := &types.MediaAsset{
+		FileName:    fileHeader.Filename,
+		ContentType: c.PostForm("ContentType"),
+	}
+	if asset.ContentType == "" {
+		asset.ContentType = http.DetectContentType(content)
+	}
+	return asset, content, nil
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/corporateanon/barker/pkg/markup"
+	"github.com/corporateanon/barker/pkg/types"
+)
+
+// maxMessageLength is the limit of Telegram for the message of a campaign
+func maxMessageLength(campaign *types.Campaign) int {
+	switch {
+	case campaign.Kind == types.CampaignKindPoll:
+		return markup.MaxPollQuestionLength
+	case campaign.MediaID != 0:
+		return markup.MaxCaptionLength
+	default:
+		return markup.MaxMessageLength
+	}
+}
+
+// checkCampaignMessage rejects a campaign which Telegram would refuse to send:
+// malformed markup, a message or a localization over the limit, or a button with an invalid URL
+func checkCampaignMessage(campaign *types.Campaign) error {
+	if campaign.Kind == types.CampaignKindPoll && campaign.ParseMode != "" {
+		return errors.New("Polls cannot have a parse mode")
+	}
+	if campaign.TrackLinks && campaign.ParseMode == markup.ParseModeMarkdownV2 {
+		return errors.New("Tracked links are not supported with MarkdownV2")
+	}
+	if err := checkMessage(campaign, campaign.Message); err != nil {
+		return err
+	}
+	for _, localization := range campaign.Localizations {
+		if err := checkMessage(campaign, localization.Message); err != nil {
+			return fmt.Errorf("Localization %s: %w", localization.Language, err)
+		}
+	}
+	for _, button := range campaign.Buttons {
+		if button.URL == "" {
+			continue
+		}
+		if err := markup.CheckURL(button.URL); err != nil {
+			return fmt.Errorf("Button %q: %w", button.Text, err)
+		}
+	}
+	return nil
+}
+
+// checkMessage checks a message or a new text of a campaign
+func checkMessage(campaign *types.Campaign, message string) error {
+	return markup.Validate(message, campaign.ParseMode, maxMessageLength(campaign))
+}
+
+// previewCampaign renders a campaign for a user, or in the default language if there is no user
+func previewCampaign(campaign *types.Campaign, user *types.User) (*types.CampaignPreview, error) {
+	if err := checkCampaignMessage(campaign); err != nil {
+		return nil, err
+	}
+	languageCode := ""
+	if user != nil {
+		languageCode = user.LanguageCode
+	}
+	localizedCampaign, language := campaign.Localize(languageCode)
+	plainText, err := markup.Parse(localizedCampaign.Message, campaign.ParseMode)
+	if err != nil {
+		return nil, err
+	}
+	preview := &types.CampaignPreview{
+		Language:  language,
+		Text:      localizedCampaign.Message,
+		ParseMode: campaign.ParseMode,
+		PlainText: plainText,
+		Length:    markup.Length(plainText),
+		MaxLength: maxMessageLength(campaign),
+		MediaID:   campaign.MediaID,
+		Buttons:   campaign.Buttons,
+	}
+	if campaign.Kind != "" {
+		preview.Options = campaign.Options
+	}
+	return preview, nil
+}
@@ -1,19 +1,43 @@
 package server
 
 import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/ingest"
+	"github.com/corporateanon/barker/pkg/notify"
 	"github.com/corporateanon/barker/pkg/server/middleware"
+	"github.com/corporateanon/barker/pkg/telegram"
 	"github.com/corporateanon/barker/pkg/types"
 	"github.com/gin-gonic/gin"
 )
 
+// telegramSecretHeader carries the secret token of a webhook, see setWebhook
+const telegramSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
 func NewHandler(
 	userDao dao.UserDao,
 	campaignDao dao.CampaignDao,
 	deliveryDao dao.DeliveryDao,
 	botDao dao.BotDao,
+	workerDao dao.WorkerDao,
+	campaignOperationDao dao.CampaignOperationDao,
+	clickDao dao.ClickDao,
+	linkDao dao.LinkDao,
+	conversionDao dao.ConversionDao,
+	answerDao dao.AnswerDao,
+	inboxDao dao.InboxDao,
+	autoResponderDao dao.AutoResponderDao,
+	mediaDao dao.MediaDao,
+	testerDao dao.TesterDao,
+	sequenceDao dao.SequenceDao,
+	notifier *notify.Notifier,
+	ingester *ingest.Ingester,
+	telegramClient *telegram.Client,
 ) *gin.Engine {
 	router := gin.Default()
 	router.GET("/", func(c *gin.Context) {
@@ -27,6 +51,10 @@ func NewHandler(
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if err := identifyBot(telegramClient, bot); err != nil {
+			respondIdentifyError(c, err)
+			return
+		}
 		resultingBot, err := botDao.Create(bot)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -44,6 +72,29 @@ func NewHandler(
 		c.JSON(http.StatusOK, gin.H{"data": resultingBot})
 	})
 
+	router.POST("/rr/delivery", func(c *gin.Context) {
+		urlParams := &struct {
+			WorkerID string `form:"WorkerID"`
+			//Seconds
+			Wait int64 `form:"Wait"`
+		}{}
+		if err := c.ShouldBind(urlParams); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var result *dao.DeliveryTakeResult
+		err := waitFor(c.Request.Context(), notifier, time.Duration(urlParams.Wait)*time.Second, func() (bool, error) {
+			var err error
+			result, err = deliveryDao.NextJob(urlParams.WorkerID)
+			return result != nil, err
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": result})
+	})
+
 	router.GET("/bot", func(c *gin.Context) {
 		pageRequest := &types.PaginatorRequest{}
 		if err := c.ShouldBind(pageRequest); err != nil {
@@ -89,6 +140,240 @@ func NewHandler(
 
 	})
 
+	router.POST("/worker", func(c *gin.Context) {
+		worker := &types.Worker{}
+		if err := c.ShouldBindJSON(worker); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		resultingWorker, err := workerDao.Register(worker)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": resultingWorker})
+	})
+
+	router.GET("/worker", func(c *gin.Context) {
+		workers, err := workerDao.List()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": workers})
+	})
+
+	router.GET("/worker/:WorkerID", func(c *gin.Context) {
+		params := &struct {
+			WorkerID string `uri:"WorkerID"`
+		}{}
+		if err := c.ShouldBindUri(params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		worker, err := workerDao.Get(params.WorkerID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if worker == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Worker not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": worker})
+	})
+
+	router.PUT("/worker/:WorkerID/heartbeat", func(c *gin.Context) {
+		params := &struct {
+			WorkerID string `uri:"WorkerID"`
+		}{}
+		if err := c.ShouldBindUri(params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		//The ID comes from the path, the body may omit it
+		worker := &types.Worker{ID: params.WorkerID}
+		if err := c.ShouldBindJSON(worker); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		worker.ID = params.WorkerID
+
+		existingWorker, err := workerDao.Get(worker.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if existingWorker == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Worker not registered"})
+			return
+		}
+
+		resultingWorker, err := workerDao.Heartbeat(worker)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": resultingWorker})
+	})
+
+	//Tracked links of campaign messages
+	router.GET("/l/:Code", func(c *gin.Context) {
+		link, err := linkDao.Open(c.Param("Code"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if link == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			return
+		}
+		c.Redirect(http.StatusFound, link.URL)
+	})
+
+	router.POST("/link/:Code/open", func(c *gin.Context) {
+		link, err := linkDao.Open(c.Param("Code"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if link == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": link})
+	})
+
+	router.POST("/media", func(c *gin.Context) {
+		asset, content, err := readUpload(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		resultingAsset, err := mediaDao.Create(asset, content)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": resultingAsset})
+	})
+
+	router.GET("/media", func(c *gin.Context) {
+		pageRequest := &types.PaginatorRequest{}
+		if err := c.ShouldBind(pageRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		assets, pageResponse, err := mediaDao.List(pageRequest)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": assets, "paging": pageResponse})
+	})
+
+	router.GET("/media/:MediaID", func(c *gin.Context) {
+		params := &struct {
+			MediaID int64 `uri:"MediaID" binding:"required"`
+		}{}
+		if err := c.ShouldBindUri(params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		asset, err := mediaDao.Get(params.MediaID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if asset == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": asset})
+	})
+
+	router.GET("/media/:MediaID/content", func(c *gin.Context) {
+		params := &struct {
+			MediaID int64 `uri:"MediaID" binding:"required"`
+		}{}
+		if err := c.ShouldBindUri(params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		asset, err := mediaDao.Get(params.MediaID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if asset == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+		content, err := mediaDao.GetContent(params.MediaID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Data(http.StatusOK, asset.ContentType, content)
+	})
+
+	router.POST("/worker-expired/release", func(c *gin.Context) {
+		params := &struct {
+			//Seconds
+			Timeout int64 `form:"Timeout"`
+		}{}
+		if err := c.ShouldBind(params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		timeout := dao.WorkerTimeout
+		if params.Timeout > 0 {
+			timeout = time.Duration(params.Timeout) * time.Second
+		}
+		workers, err := workerDao.ReleaseExpired(timeout)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(workers) > 0 {
+			notifier.Notify()
+		}
+		c.JSON(http.StatusOK, gin.H{"data": workers})
+	})
+
+	//-------------------------------------------
+	telegramRouter := router.Group("/telegram/:BotID")
+	{
+		telegramRouter.Use(middleware.NewMiddlewareLoadBot(botDao))
+
+		telegramRouter.POST("/webhook", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+
+			if bot.IngestionMode == types.IngestionModePolling {
+				c.JSON(http.StatusConflict, gin.H{"error": "Bot is in polling mode"})
+				return
+			}
+			secret := c.GetHeader(telegramSecretHeader)
+			if bot.WebhookSecret == "" ||
+				subtle.ConstantTimeCompare([]byte(secret), []byte(bot.WebhookSecret)) != 1 {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Wrong secret token"})
+				return
+			}
+			update := &telegram.Update{}
+			if err := c.ShouldBindJSON(update); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			//Telegram redelivers the update if it gets an error
+			if err := ingester.Ingest(bot, update); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			notifier.Notify()
+			c.JSON(http.StatusOK, gin.H{})
+		})
+	}
+
 	//-------------------------------------------
 	botRouter := router.Group("/bot/:BotID")
 	{
@@ -109,6 +394,31 @@ func NewHandler(
 			}
 
 			bot.ID = existingBot.ID
+			if err := identifyBot(telegramClient, bot); err != nil {
+				respondIdentifyError(c, err)
+				return
+			}
+
+			resultingBot, err := botDao.Update(bot)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			c.JSON(http.StatusOK, gin.H{"data": resultingBot})
+		})
+
+		botRouter.POST("/validate", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+
+			if err := identifyBot(telegramClient, bot); err != nil {
+				if !isInvalidToken(err) {
+					respondIdentifyError(c, err)
+					return
+				}
+				bot.TokenRevoked = true
+				bot.ValidatedAt = time.Now()
+			}
 
 			resultingBot, err := botDao.Update(bot)
 			if err != nil {
@@ -119,6 +429,79 @@ func NewHandler(
 			c.JSON(http.StatusOK, gin.H{"data": resultingBot})
 		})
 
+		botRouter.PUT("/update-offset", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+
+			offsetRequest := &struct {
+				Offset int64
+			}{}
+			if err := c.ShouldBindJSON(offsetRequest); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err := botDao.SetUpdateOffset(bot.ID, offsetRequest.Offset); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{})
+		})
+
+		botRouter.PUT("/token-revoked", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			if err := botDao.SetTokenRevoked(bot.ID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{})
+		})
+
+		botRouter.POST("/conversion", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			conversion := &types.Conversion{}
+			if err := c.ShouldBindJSON(conversion); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			conversion.BotID = bot.ID
+			resultingConversion, err := conversionDao.Create(conversion)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": resultingConversion})
+		})
+
+		botRouter.PUT("/answer", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			answer := &types.Answer{}
+			if err := c.ShouldBindJSON(answer); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			answer.BotID = bot.ID
+			resultingAnswer, err := answerDao.Put(answer)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": resultingAnswer})
+		})
+
+		botRouter.POST("/answer/retract", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			answer := &types.Answer{}
+			if err := c.ShouldBindJSON(answer); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			answer.BotID = bot.ID
+			if err := answerDao.Retract(answer); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{})
+		})
+
 		botRouter.GET("/user", func(c *gin.Context) {
 			bot := c.MustGet("Bot").(*types.Bot)
 			pageRequest := &types.PaginatorRequest{}
@@ -135,6 +518,16 @@ func NewHandler(
 			c.JSON(http.StatusOK, gin.H{"data": users, "paging": pageResponse})
 		})
 
+		botRouter.GET("/sources", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			sources, err := userDao.Sources(bot.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": sources})
+		})
+
 		botRouter.PUT("/user", func(c *gin.Context) {
 			bot := c.MustGet("Bot").(*types.Bot)
 
@@ -145,6 +538,13 @@ func NewHandler(
 				UserName    string
 				TelegramID  int64 `binding:"required"`
 				BotID       int64
+				//Deep-link payload the user has come by. The first source is kept once set.
+				FirstSource string `binding:"max=64"`
+				LastSource  string `binding:"max=64"`
+				//private, group, supergroup or channel. Private if empty.
+				ChatType string `binding:"omitempty,oneof=private group supergroup channel"`
+				//Telegram language_code, e.g. "en" or "pt-br"
+				LanguageCode string `binding:"max=16"`
 			}
 
 			userRequest := &UserRequest{}
@@ -154,17 +554,22 @@ func NewHandler(
 			}
 
 			resultingUser, err := userDao.Put(&types.User{
-				BotID:       bot.ID,
-				DisplayName: userRequest.DisplayName,
-				FirstName:   userRequest.FirstName,
-				LastName:    userRequest.LastName,
-				UserName:    userRequest.UserName,
-				TelegramID:  userRequest.TelegramID,
+				BotID:        bot.ID,
+				DisplayName:  userRequest.DisplayName,
+				FirstName:    userRequest.FirstName,
+				LastName:     userRequest.LastName,
+				UserName:     userRequest.UserName,
+				TelegramID:   userRequest.TelegramID,
+				FirstSource:  userRequest.FirstSource,
+				LastSource:   userRequest.LastSource,
+				ChatType:     userRequest.ChatType,
+				LanguageCode: userRequest.LanguageCode,
 			})
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 				return
 			}
+			notifier.Notify()
 
 			c.JSON(http.StatusOK, gin.H{"data": resultingUser})
 		})
@@ -194,20 +599,522 @@ func NewHandler(
 			c.JSON(http.StatusOK, gin.H{"data": user})
 		})
 
-		botRouter.GET("/campaign", func(c *gin.Context) {
+		botRouter.PUT("/user/:TelegramID/blocked", func(c *gin.Context) {
 			bot := c.MustGet("Bot").(*types.Bot)
-			pageRequest := &types.PaginatorRequest{}
-			if err := c.ShouldBind(pageRequest); err != nil {
+
+			params := &struct {
+				TelegramID int64 `uri:"TelegramID"`
+			}{}
+			if err := c.ShouldBindUri(params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			blockedRequest := &struct {
+				Blocked bool
+			}{}
+			if err := c.ShouldBindJSON(blockedRequest); err != nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			users, pageResponse, err := campaignDao.List(bot.ID, pageRequest)
 
+			user, err := userDao.SetBlocked(bot.ID, params.TelegramID, blockedRequest.Blocked)
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 				return
 			}
-			c.JSON(http.StatusOK, gin.H{"data": users, "paging": pageResponse})
+			if user == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			if !user.Blocked {
+				notifier.Notify()
+			}
+
+			c.JSON(http.StatusOK, gin.H{"data": user})
+		})
+
+		botRouter.PUT("/user/:TelegramID/unsubscribed", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+
+			params := &struct {
+				TelegramID int64 `uri:"TelegramID"`
+			}{}
+			if err := c.ShouldBindUri(params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			unsubscribedRequest := &struct {
+				Unsubscribed bool
+			}{}
+			if err := c.ShouldBindJSON(unsubscribedRequest); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			user, err := userDao.SetUnsubscribed(bot.ID, params.TelegramID, unsubscribedRequest.Unsubscribed)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if user == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			if !user.Unsubscribed {
+				notifier.Notify()
+			}
+
+			c.JSON(http.StatusOK, gin.H{"data": user})
+		})
+
+		botRouter.POST("/user/:TelegramID/migrate", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+
+			params := &struct {
+				TelegramID int64 `uri:"TelegramID"`
+			}{}
+			if err := c.ShouldBindUri(params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			migrateRequest := &struct {
+				NewTelegramID int64 `binding:"required"`
+			}{}
+			if err := c.ShouldBindJSON(migrateRequest); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			user, err := userDao.Migrate(bot.ID, params.TelegramID, migrateRequest.NewTelegramID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if user == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": user})
+		})
+
+		botRouter.POST("/user/:TelegramID/tag", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+
+			params := &struct {
+				TelegramID int64 `uri:"TelegramID"`
+			}{}
+			if err := c.ShouldBindUri(params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			tagRequest := &struct {
+				Tag string `binding:"required,max=64"`
+			}{}
+			if err := c.ShouldBindJSON(tagRequest); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			user, err := userDao.AddTag(bot.ID, params.TelegramID, tagRequest.Tag)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if user == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": user})
+		})
+
+		botRouter.PUT("/media/:MediaID/file-id", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			params := &struct {
+				MediaID int64 `uri:"MediaID" binding:"required"`
+			}{}
+			if err := c.ShouldBindUri(params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			fileIDRequest := &struct {
+				FileID string `binding:"required"`
+			}{}
+			if err := c.ShouldBindJSON(fileIDRequest); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			asset, err := mediaDao.Get(params.MediaID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if asset == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+				return
+			}
+			if err := mediaDao.SetFileID(bot.ID, params.MediaID, fileIDRequest.FileID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": asset})
+		})
+
+		botRouter.POST("/inbox", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			message := &types.InboxMessage{}
+			if err := c.ShouldBindJSON(message); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			message.BotID = bot.ID
+			resultingMessage, err := inboxDao.Receive(message)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": resultingMessage})
+		})
+
+		botRouter.GET("/inbox", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			pageRequest := &types.PaginatorRequest{}
+			if err := c.ShouldBind(pageRequest); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			conversations, pageResponse, err := inboxDao.List(bot.ID, pageRequest)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": conversations, "paging": pageResponse})
+		})
+
+		botRouter.GET("/inbox/:TelegramID", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			params := &struct {
+				TelegramID int64 `uri:"TelegramID"`
+			}{}
+			if err := c.ShouldBindUri(params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			pageRequest := &types.PaginatorRequest{}
+			if err := c.ShouldBind(pageRequest); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			messages, pageResponse, err := inboxDao.Conversation(bot.ID, params.TelegramID, pageRequest)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": messages, "paging": pageResponse})
+		})
+
+		botRouter.POST("/inbox/:TelegramID/reply", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			params := &struct {
+				TelegramID int64 `uri:"TelegramID"`
+			}{}
+			if err := c.ShouldBindUri(params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			replyRequest := &struct {
+				Text string `binding:"required,max=4096"`
+				//Set for automatic replies, see InboxDao.AutoReply
+				ReplyTo int64
+			}{}
+			if err := c.ShouldBindJSON(replyRequest); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var message *types.InboxMessage
+			var err error
+			if replyRequest.ReplyTo != 0 {
+				message, err = inboxDao.AutoReply(bot.ID, params.TelegramID, replyRequest.ReplyTo, replyRequest.Text)
+			} else {
+				message, err = inboxDao.Reply(bot.ID, params.TelegramID, replyRequest.Text)
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if message == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			notifier.Notify()
+			c.JSON(http.StatusOK, gin.H{"data": message})
+		})
+
+		botRouter.GET("/tester", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			testers, err := testerDao.List(bot.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": testers})
+		})
+
+		botRouter.PUT("/tester/:TelegramID", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			params := &struct {
+				TelegramID int64 `uri:"TelegramID" binding:"required"`
+			}{}
+			if err := c.ShouldBindUri(params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			tester, err := testerDao.Add(bot.ID, params.TelegramID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if tester == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": tester})
+		})
+
+		botRouter.DELETE("/tester/:TelegramID", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			params := &struct {
+				TelegramID int64 `uri:"TelegramID" binding:"required"`
+			}{}
+			if err := c.ShouldBindUri(params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err := testerDao.Remove(bot.ID, params.TelegramID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{})
+		})
+
+		botRouter.GET("/auto-responder", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			rules, err := autoResponderDao.List(bot.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": rules})
+		})
+
+		botRouter.POST("/auto-responder", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			rule := &types.AutoResponderRule{}
+			if err := c.ShouldBindJSON(rule); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err := rule.Validate(); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			rule.BotID = bot.ID
+			resultingRule, err := autoResponderDao.Create(rule)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": resultingRule})
+		})
+
+		botRouter.GET("/auto-responder/:RuleID", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			params := &struct {
+				RuleID int64 `uri:"RuleID"`
+			}{}
+			if err := c.ShouldBindUri(params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			rule, err := autoResponderDao.Get(bot.ID, params.RuleID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if rule == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": rule})
+		})
+
+		botRouter.PUT("/auto-responder/:RuleID", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			params := &struct {
+				RuleID int64 `uri:"RuleID" binding:"required"`
+			}{}
+			if err := c.ShouldBindUri(params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			rule := &types.AutoResponderRule{}
+			if err := c.ShouldBindJSON(rule); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err := rule.Validate(); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			rule.ID = params.RuleID
+			rule.BotID = bot.ID
+			resultingRule, err := autoResponderDao.Update(rule)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": resultingRule})
+		})
+
+		botRouter.DELETE("/auto-responder/:RuleID", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			params := &struct {
+				RuleID int64 `uri:"RuleID"`
+			}{}
+			if err := c.ShouldBindUri(params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err := autoResponderDao.Delete(bot.ID, params.RuleID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{})
+		})
+
+		botRouter.GET("/sequence", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			sequences, err := sequenceDao.List(bot.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": sequences})
+		})
+
+		botRouter.POST("/sequence", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			sequence := &types.Sequence{}
+			if err := c.ShouldBindJSON(sequence); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			sequence.BotID = bot.ID
+			resultingSequence, err := sequenceDao.Create(sequence)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": resultingSequence})
+		})
+
+		botRouter.GET("/sequence/:SequenceID", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			params := &struct {
+				SequenceID int64 `uri:"SequenceID"`
+			}{}
+			if err := c.ShouldBindUri(params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			sequence, err := sequenceDao.Get(bot.ID, params.SequenceID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if sequence == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Sequence not found"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": sequence})
+		})
+
+		botRouter.PUT("/sequence/:SequenceID", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			params := &struct {
+				SequenceID int64 `uri:"SequenceID" binding:"required"`
+			}{}
+			if err := c.ShouldBindUri(params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			sequence := &types.Sequence{}
+			if err := c.ShouldBindJSON(sequence); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			sequence.ID = params.SequenceID
+			sequence.BotID = bot.ID
+			resultingSequence, err := sequenceDao.Update(sequence)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": resultingSequence})
+		})
+
+		botRouter.DELETE("/sequence/:SequenceID", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			params := &struct {
+				SequenceID int64 `uri:"SequenceID"`
+			}{}
+			if err := c.ShouldBindUri(params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err := sequenceDao.Delete(bot.ID, params.SequenceID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{})
+		})
+
+		botRouter.PUT("/sequence/:SequenceID/enrollment/:TelegramID", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			params := &struct {
+				SequenceID int64 `uri:"SequenceID" binding:"required"`
+				TelegramID int64 `uri:"TelegramID" binding:"required"`
+			}{}
+			if err := c.ShouldBindUri(params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			sequence, err := sequenceDao.Enroll(bot.ID, params.SequenceID, params.TelegramID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if sequence == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Sequence or user not found"})
+				return
+			}
+			notifier.Notify()
+			c.JSON(http.StatusOK, gin.H{"data": sequence})
+		})
+
+		botRouter.GET("/campaign", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			pageRequest := &types.PaginatorRequest{}
+			if err := c.ShouldBind(pageRequest); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			users, pageResponse, err := campaignDao.List(bot.ID, pageRequest)
+
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": users, "paging": pageResponse})
 		})
 
 		botRouter.POST("/campaign", func(c *gin.Context) {
@@ -215,69 +1122,270 @@ func NewHandler(
 
 			campaign := &types.Campaign{}
 
-			if err := c.ShouldBindJSON(campaign); err != nil {
+			if err := c.ShouldBindJSON(campaign); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			campaign.BotID = bot.ID
+			if err := checkCampaignMedia(mediaDao, campaign); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err := checkCampaignMessage(campaign); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			resultingCampaign, err := campaignDao.Create(campaign)
+			if err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+			if resultingCampaign.Active {
+				notifier.Notify()
+			}
+
+			c.JSON(http.StatusOK, gin.H{"data": resultingCampaign})
+		})
+
+		botRouter.PUT("/campaign/:CampaignID", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+
+			urlParams := &struct {
+				CampaignID int64 `uri:"CampaignID" binding:"required"`
+			}{}
+			if err := c.ShouldBindUri(urlParams); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			campaignUpdate := &types.Campaign{}
+			if err := c.ShouldBindJSON(campaignUpdate); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			campaignUpdate.ID = urlParams.CampaignID
+			campaignUpdate.BotID = bot.ID
+			if err := checkCampaignMedia(mediaDao, campaignUpdate); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err := checkCampaignMessage(campaignUpdate); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			resultingCampaign, err := campaignDao.Update(campaignUpdate)
+			if err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+			if resultingCampaign.Active {
+				notifier.Notify()
+			}
+
+			c.JSON(http.StatusOK, gin.H{"data": resultingCampaign})
+		})
+
+		botRouter.POST("/campaign-preview", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			params := &struct {
+				TelegramID int64 `form:"TelegramID"`
+			}{}
+			if err := c.ShouldBindQuery(params); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			campaign := &types.Campaign{}
+			if err := c.ShouldBindJSON(campaign); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			var user *types.User
+			if params.TelegramID != 0 {
+				var err error
+				user, err = userDao.Get(bot.ID, params.TelegramID)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				if user == nil {
+					c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+					return
+				}
+			}
+			preview, err := previewCampaign(campaign, user)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": preview})
+		})
+
+		botRouter.POST("/delivery", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			urlParams := &struct {
+				TelegramID int64  `form:"TelegramID"`
+				WorkerID   string `form:"WorkerID"`
+				//Seconds
+				Wait int64 `form:"Wait"`
+			}{}
+			if err := c.ShouldBind(urlParams); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			var result *dao.DeliveryTakeResult
+			err := waitFor(c.Request.Context(), notifier, time.Duration(urlParams.Wait)*time.Second, func() (bool, error) {
+				var err error
+				result, err = deliveryDao.TakeForWorker(urlParams.WorkerID, bot.ID, 0, urlParams.TelegramID)
+				return result != nil, err
+			})
+			if err != nil {
+				c.JSON(http.StatusNotFound, nil)
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"data": result,
+			})
+		})
+
+		botRouter.POST("/delivery/batch", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			urlParams := &struct {
+				Size     int    `form:"Size" binding:"required"`
+				WorkerID string `form:"WorkerID"`
+				//Seconds
+				Wait int64 `form:"Wait"`
+			}{}
+			if err := c.ShouldBind(urlParams); err != nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
+			if urlParams.Size < 1 || urlParams.Size > dao.MaxTakeBatchSize {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Wrong batch size"})
+				return
+			}
 
-			campaign.BotID = bot.ID
-
-			resultingCampaign, err := campaignDao.Create(campaign)
+			var results []dao.DeliveryTakeResult
+			err := waitFor(c.Request.Context(), notifier, time.Duration(urlParams.Wait)*time.Second, func() (bool, error) {
+				var err error
+				results, err = deliveryDao.TakeBatch(urlParams.WorkerID, bot.ID, urlParams.Size)
+				return len(results) > 0, err
+			})
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 				return
 			}
-
-			c.JSON(http.StatusOK, gin.H{"data": resultingCampaign})
+			c.JSON(http.StatusOK, gin.H{
+				"data": results,
+			})
 		})
 
-		botRouter.PUT("/campaign/:CampaignID", func(c *gin.Context) {
+		botRouter.PUT("/delivery/state", func(c *gin.Context) {
 			bot := c.MustGet("Bot").(*types.Bot)
-
-			urlParams := &struct {
-				CampaignID int64 `uri:"CampaignID" binding:"required"`
-			}{}
-			if err := c.ShouldBindUri(urlParams); err != nil {
+			reports := []types.DeliveryStateReport{}
+			if err := c.ShouldBindJSON(&reports); err != nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
+			deliveries := make([]types.Delivery, len(reports))
+			for i := range reports {
+				delivery, err := reports[i].ToDelivery(bot.ID)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				deliveries[i] = *delivery
+			}
+
+			if err := deliveryDao.SetStates(deliveries); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, nil)
+		})
 
-			campaignUpdate := &types.Campaign{}
-			if err := c.ShouldBindJSON(campaignUpdate); err != nil {
+		botRouter.POST("/delivery/release", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			deliveries := []types.Delivery{}
+			if err := c.ShouldBindJSON(&deliveries); err != nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
+			for i := range deliveries {
+				deliveries[i].BotID = bot.ID
+			}
 
-			campaignUpdate.ID = urlParams.CampaignID
-			campaignUpdate.BotID = bot.ID
-
-			resultingCampaign, err := campaignDao.Update(campaignUpdate)
-			if err != nil {
+			if err := deliveryDao.Release(deliveries); err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 				return
 			}
-
-			c.JSON(http.StatusOK, gin.H{"data": resultingCampaign})
+			notifier.Notify()
+			c.JSON(http.StatusOK, nil)
 		})
 
-		botRouter.POST("/delivery", func(c *gin.Context) {
+		botRouter.POST("/message-job/batch", func(c *gin.Context) {
 			bot := c.MustGet("Bot").(*types.Bot)
 			urlParams := &struct {
-				TelegramID int64 `form:"TelegramID"`
+				Size     int    `form:"Size" binding:"required"`
+				WorkerID string `form:"WorkerID"`
 			}{}
 			if err := c.ShouldBind(urlParams); err != nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
+			if urlParams.Size < 1 || urlParams.Size > dao.MaxTakeBatchSize {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Wrong batch size"})
+				return
+			}
 
-			result, err := deliveryDao.Take(bot.ID, 0, urlParams.TelegramID)
+			jobs, err := campaignOperationDao.TakeJobs(urlParams.WorkerID, bot.ID, urlParams.Size)
 			if err != nil {
-				c.JSON(http.StatusNotFound, nil)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 				return
 			}
-			c.JSON(http.StatusOK, gin.H{
-				"data": result,
-			})
+			c.JSON(http.StatusOK, gin.H{"data": jobs})
+		})
+
+		botRouter.PUT("/message-job/state", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			jobs := []types.MessageJob{}
+			if err := c.ShouldBindJSON(&jobs); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			for i := range jobs {
+				jobs[i].BotID = bot.ID
+			}
+
+			if err := campaignOperationDao.SetJobStates(jobs); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, nil)
+		})
+
+		botRouter.POST("/message-job/release", func(c *gin.Context) {
+			bot := c.MustGet("Bot").(*types.Bot)
+			jobs := []types.MessageJob{}
+			if err := c.ShouldBindJSON(&jobs); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			for i := range jobs {
+				jobs[i].BotID = bot.ID
+			}
+
+			if err := campaignOperationDao.ReleaseJobs(jobs); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			notifier.Notify()
+			c.JSON(http.StatusOK, nil)
 		})
 
 		//--------
@@ -300,19 +1408,282 @@ func NewHandler(
 				c.JSON(http.StatusOK, gin.H{"data": stat})
 			})
 
+			campaignRouter.GET("/sourceStatistics", func(c *gin.Context) {
+				campaign := c.MustGet("Campaign").(*types.Campaign)
+				params := &struct {
+					Touch string `form:"Touch" binding:"omitempty,oneof=first last"`
+				}{}
+				if err := c.ShouldBindQuery(params); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				if params.Touch == "" {
+					params.Touch = types.SourceTouchFirst
+				}
+				stat, err := campaignDao.GetSourceStatistics(campaign.BotID, campaign.ID, params.Touch)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"data": stat})
+			})
+
+			campaignRouter.GET("/languageStatistics", func(c *gin.Context) {
+				campaign := c.MustGet("Campaign").(*types.Campaign)
+				stat, err := campaignDao.GetLanguageStatistics(campaign.BotID, campaign.ID)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"data": stat})
+			})
+
+			campaignRouter.GET("/results", func(c *gin.Context) {
+				campaign := c.MustGet("Campaign").(*types.Campaign)
+				results, err := answerDao.Results(campaign.BotID, campaign.ID)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"data": results})
+			})
+
+			campaignRouter.GET("/answer", func(c *gin.Context) {
+				campaign := c.MustGet("Campaign").(*types.Campaign)
+				answers, err := answerDao.List(campaign.BotID, campaign.ID)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"data": answers})
+			})
+
+			campaignRouter.GET("/answer.csv", func(c *gin.Context) {
+				campaign := c.MustGet("Campaign").(*types.Campaign)
+				answers, err := answerDao.List(campaign.BotID, campaign.ID)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				//The file is built before anything is sent, so that a failure is still reported with a status
+				file := &bytes.Buffer{}
+				if err := writeAnswersCSV(file, campaign, answers); err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				c.Header("Content-Disposition",
+					fmt.Sprintf(`attachment; filename="campaign-%d-answers.csv"`, campaign.ID))
+				c.Data(http.StatusOK, "text/csv; charset=utf-8", file.Bytes())
+			})
+
+			campaignRouter.POST("/click", func(c *gin.Context) {
+				campaign := c.MustGet("Campaign").(*types.Campaign)
+				click := &types.Click{}
+				if err := c.ShouldBindJSON(click); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				click.BotID = campaign.BotID
+				click.CampaignID = campaign.ID
+				resultingClick, err := clickDao.Add(click)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"data": resultingClick})
+			})
+
+			campaignRouter.POST("/edit", func(c *gin.Context) {
+				campaign := c.MustGet("Campaign").(*types.Campaign)
+				editRequest := &struct {
+					Text string `binding:"required"`
+				}{}
+				if err := c.ShouldBindJSON(editRequest); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				if campaign.Kind != types.CampaignKindPoll {
+					if err := checkMessage(campaign, editRequest.Text); err != nil {
+						c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+						return
+					}
+				}
+				operation, err := campaignOperationDao.Edit(campaign.BotID, campaign.ID, editRequest.Text)
+				if err != nil {
+					c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+					return
+				}
+				notifier.Notify()
+				c.JSON(http.StatusOK, gin.H{"data": operation})
+			})
+
+			campaignRouter.POST("/recall", func(c *gin.Context) {
+				campaign := c.MustGet("Campaign").(*types.Campaign)
+				operation, err := campaignOperationDao.Recall(campaign.BotID, campaign.ID)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				notifier.Notify()
+				c.JSON(http.StatusOK, gin.H{"data": operation})
+			})
+
+			campaignRouter.PUT("/state", func(c *gin.Context) {
+				campaign := c.MustGet("Campaign").(*types.Campaign)
+				stateRequest := &struct {
+					State string `binding:"required,oneof=draft pending_approval approved running paused completed archived"`
+				}{}
+				if err := c.ShouldBindJSON(stateRequest); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				resultingCampaign, err := campaignDao.SetState(campaign.BotID, campaign.ID, stateRequest.State)
+				if err != nil {
+					c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+					return
+				}
+				if resultingCampaign.Active {
+					notifier.Notify()
+				}
+				c.JSON(http.StatusOK, gin.H{"data": resultingCampaign})
+			})
+
+			campaignRouter.POST("/approve", func(c *gin.Context) {
+				campaign := c.MustGet("Campaign").(*types.Campaign)
+				approveRequest := &struct {
+					ApprovedBy string `binding:"required,max=255"`
+				}{}
+				if err := c.ShouldBindJSON(approveRequest); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				resultingCampaign, err := campaignDao.Approve(campaign.BotID, campaign.ID, approveRequest.ApprovedBy)
+				if err != nil {
+					c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"data": resultingCampaign})
+			})
+
+			campaignRouter.GET("/revision", func(c *gin.Context) {
+				campaign := c.MustGet("Campaign").(*types.Campaign)
+				revisions, err := campaignDao.ListRevisions(campaign.BotID, campaign.ID)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"data": revisions})
+			})
+
+			campaignRouter.GET("/revision/:Revision", func(c *gin.Context) {
+				campaign := c.MustGet("Campaign").(*types.Campaign)
+				params := &struct {
+					Revision int64 `uri:"Revision" binding:"required"`
+				}{}
+				if err := c.ShouldBindUri(params); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				revision, err := campaignDao.GetRevision(campaign.BotID, campaign.ID, params.Revision)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				if revision == nil {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"data": revision})
+			})
+
+			campaignRouter.GET("/revision-diff", func(c *gin.Context) {
+				campaign := c.MustGet("Campaign").(*types.Campaign)
+				params := &struct {
+					From int64 `form:"From" binding:"required"`
+					To   int64 `form:"To"`
+				}{}
+				if err := c.ShouldBindQuery(params); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				if params.To == 0 {
+					params.To = campaign.Revision
+				}
+				diff, err := campaignDao.DiffRevisions(campaign.BotID, campaign.ID, params.From, params.To)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				if diff == nil {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"data": diff})
+			})
+
+			campaignRouter.POST("/test", func(c *gin.Context) {
+				campaign := c.MustGet("Campaign").(*types.Campaign)
+				operation, err := campaignOperationDao.Test(campaign.BotID, campaign.ID)
+				if err != nil {
+					c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+					return
+				}
+				notifier.Notify()
+				c.JSON(http.StatusOK, gin.H{"data": operation})
+			})
+
+			campaignRouter.GET("/operation", func(c *gin.Context) {
+				campaign := c.MustGet("Campaign").(*types.Campaign)
+				operations, err := campaignOperationDao.List(campaign.BotID, campaign.ID)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"data": operations})
+			})
+
+			campaignRouter.GET("/operation/:OperationID", func(c *gin.Context) {
+				campaign := c.MustGet("Campaign").(*types.Campaign)
+				params := &struct {
+					OperationID int64 `uri:"OperationID"`
+				}{}
+				if err := c.ShouldBindUri(params); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				operation, err := campaignOperationDao.Get(campaign.BotID, campaign.ID, params.OperationID)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				if operation == nil {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Operation not found"})
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"data": operation})
+			})
+
 			campaignRouter.POST("/delivery", func(c *gin.Context) {
 				campaign := c.MustGet("Campaign").(*types.Campaign)
 				bot := c.MustGet("Bot").(*types.Bot)
 
 				urlParams := &struct {
-					TelegramID int64 `form:"TelegramID"`
+					TelegramID int64  `form:"TelegramID"`
+					WorkerID   string `form:"WorkerID"`
+					//Seconds
+					Wait int64 `form:"Wait"`
 				}{}
 				if err := c.ShouldBind(urlParams); err != nil {
 					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 					return
 				}
 
-				result, err := deliveryDao.Take(bot.ID, campaign.ID, urlParams.TelegramID)
+				var result *dao.DeliveryTakeResult
+				err := waitFor(c.Request.Context(), notifier, time.Duration(urlParams.Wait)*time.Second, func() (bool, error) {
+					var err error
+					result, err = deliveryDao.TakeForWorker(urlParams.WorkerID, bot.ID, campaign.ID, urlParams.TelegramID)
+					return result != nil, err
+				})
 				if err != nil {
 					c.JSON(http.StatusNotFound, nil)
 					return
@@ -338,10 +1709,22 @@ func NewHandler(
 					c.JSON(http.StatusBadRequest, nil)
 					return
 				}
+				report := &struct {
+					Reason    string `form:"Reason"`
+					MessageID int64  `form:"MessageID"`
+					PollID    string `form:"PollID"`
+				}{}
+				if err := c.ShouldBindQuery(report); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
 				err = deliveryDao.SetState(&types.Delivery{
 					BotID:      bot.ID,
 					CampaignID: campaign.ID,
 					TelegramID: urlParams.TelegramID,
+					Reason:     report.Reason,
+					MessageID:  report.MessageID,
+					PollID:     report.PollID,
 				}, state)
 				if err != nil {
 					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -379,6 +1762,27 @@ func NewHandler(
 					"data": stateAsString,
 				})
 			})
+
+			campaignRouter.GET("/delivery/:TelegramID/revision", func(c *gin.Context) {
+				campaign := c.MustGet("Campaign").(*types.Campaign)
+				urlParams := &struct {
+					TelegramID int64 `uri:"TelegramID" binding:"required"`
+				}{}
+				if err := c.ShouldBindUri(urlParams); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				revision, err := campaignDao.GetDeliveredRevision(campaign.BotID, campaign.ID, urlParams.TelegramID)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				if revision == nil {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"data": revision})
+			})
 		}
 	}
 
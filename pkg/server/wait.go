@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/corporateanon/barker/pkg/notify"
+)
+
+// MaxWait limits how long a request may wait for a delivery
+const MaxWait = 60 * time.Second
+
+// waitFor calls take until it finds something, the wait time elapses or the request is cancelled.
+// take is retried every time the notifier reports that new work may have appeared.
+func waitFor(ctx context.Context, notifier *notify.Notifier, wait time.Duration, take func() (bool, error)) error {
+	if wait > MaxWait {
+		wait = MaxWait
+	}
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+
+	for {
+		woken := notifier.Wait()
+		found, err := take()
+		if err != nil || found || wait <= 0 {
+			return err
+		}
+		select {
+		case <-woken:
+		case <-timeout.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
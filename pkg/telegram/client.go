@@ -0,0 +1,206 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// DefaultAPIURL is the base URL of the public Bot API server
+const DefaultAPIURL = "https://api.telegram.org"
+
+// Client calls Bot API methods on behalf of any bot, the bot token is passed to every call
+type Client struct {
+	resty *resty.Client
+}
+
+func NewClient(apiURL string) *Client {
+	return &Client{
+		resty: resty.New().
+			SetHostURL(apiURL).
+			SetTimeout(30 * time.Second),
+	}
+}
+
+func (client *Client) GetMe(token string) (*User, error) {
+	user := &User{}
+	if err := client.call(token, "getMe", nil, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (client *Client) SendMessage(token string, request *SendMessageRequest) (*Message, error) {
+	message := &Message{}
+	if err := client.call(token, "sendMessage", request, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (client *Client) SendPhoto(token string, request *SendPhotoRequest) (*Message, error) {
+	message := &Message{}
+	if err := client.call(token, "sendPhoto", request, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// UploadPhoto sends a photo which is not known to Telegram yet, request.Photo is ignored.
+// The file_id Telegram assigns to it is in the largest size of the photo of the resulting message.
+func (client *Client) UploadPhoto(token string, request *SendPhotoRequest, photo *InputFile) (*Message, error) {
+	form := map[string]string{
+		"chat_id": strconv.FormatInt(request.ChatID, 10),
+	}
+	if request.Caption != "" {
+		form["caption"] = request.Caption
+	}
+	if request.ParseMode != "" {
+		form["parse_mode"] = request.ParseMode
+	}
+	if request.ReplyMarkup != nil {
+		markup, err := json.Marshal(request.ReplyMarkup)
+		if err != nil {
+			return nil, err
+		}
+		form["reply_markup"] = string(markup)
+	}
+	message := &Message{}
+	if err := client.upload(token, "sendPhoto", form, "photo", photo, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (client *Client) SendPoll(token string, request *SendPollRequest) (*Message, error) {
+	message := &Message{}
+	if err := client.call(token, "sendPoll", request, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (client *Client) EditMessageText(token string, request *EditMessageTextRequest) (*Message, error) {
+	message := &Message{}
+	if err := client.call(token, "editMessageText", request, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (client *Client) EditMessageCaption(token string, request *EditMessageCaptionRequest) (*Message, error) {
+	message := &Message{}
+	if err := client.call(token, "editMessageCaption", request, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (client *Client) DeleteMessage(token string, request *DeleteMessageRequest) error {
+	return client.call(token, "deleteMessage", request, nil)
+}
+
+// AnswerCallbackQuery stops the progress indicator of a pressed button
+func (client *Client) AnswerCallbackQuery(token string, request *AnswerCallbackQueryRequest) error {
+	return client.call(token, "answerCallbackQuery", request, nil)
+}
+
+// GetUpdates fetches incoming updates. The long polling timeout must be shorter than the client timeout.
+// Cancelling ctx aborts the request.
+func (client *Client) GetUpdates(ctx context.Context, token string, request *GetUpdatesRequest) ([]Update, error) {
+	updates := []Update{}
+	if err := client.callContext(ctx, token, "getUpdates", request, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+func (client *Client) SetWebhook(token string, request *SetWebhookRequest) error {
+	return client.call(token, "setWebhook", request, nil)
+}
+
+func (client *Client) DeleteWebhook(token string) error {
+	return client.call(token, "deleteWebhook", nil, nil)
+}
+
+// SetTimeout limits the duration of a single request
+func (client *Client) SetTimeout(timeout time.Duration) *Client {
+	client.resty.SetTimeout(timeout)
+	return client
+}
+
+// call invokes a Bot API method and decodes its result.
+// Unsuccessful responses are returned as *Error.
+func (client *Client) call(token string, method string, request interface{}, result interface{}) error {
+	return client.callContext(context.Background(), token, method, request, result)
+}
+
+func (client *Client) callContext(ctx context.Context, token string, method string, request interface{}, result interface{}) error {
+	req := client.resty.R().
+		SetContext(ctx).
+		SetPathParams(map[string]string{
+			"Token":  token,
+			"Method": method,
+		})
+	if request != nil {
+		req = req.SetBody(request)
+	}
+	res, err := req.Post("/bot{Token}/{Method}")
+	if err != nil {
+		return err
+	}
+	return decodeResponse(res, result)
+}
+
+// upload invokes a Bot API method with a multipart form carrying a file
+func (client *Client) upload(token string, method string, form map[string]string, fileField string, file *InputFile, result interface{}) error {
+	res, err := client.resty.R().
+		SetPathParams(map[string]string{
+			"Token":  token,
+			"Method": method,
+		}).
+		SetMultipartFormData(form).
+		SetFileReader(fileField, file.FileName, bytes.NewReader(file.Content)).
+		Post("/bot{Token}/{Method}")
+	if err != nil {
+		return err
+	}
+	return decodeResponse(res, result)
+}
+
+func decodeResponse(res *resty.Response, result interface{}) error {
+	envelope := &response{}
+	if err := json.Unmarshal(res.Body(), envelope); err != nil {
+		return &Error{
+			Code:        res.StatusCode(),
+			Description: res.Status(),
+		}
+	}
+	if !envelope.OK {
+		telegramErr := &Error{
+			Code:        envelope.ErrorCode,
+			Description: envelope.Description,
+		}
+		if envelope.Parameters != nil {
+			telegramErr.RetryAfter = time.Duration(envelope.Parameters.RetryAfter) * time.Second
+			telegramErr.MigrateToChatID = envelope.Parameters.MigrateToChatID
+		}
+		return telegramErr
+	}
+	if result == nil {
+		return nil
+	}
+	resultJSON, err := json.Marshal(envelope.Result)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(resultJSON, result); err != nil {
+		return errors.New("Unexpected Telegram response: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package telegram
+
+import (
+	"fmt"
+	"time"
+)
+
+// Error is an unsuccessful Bot API response
+type Error struct {
+	Code        int
+	Description string
+	//How long to wait before the request can be repeated, for 429 responses
+	RetryAfter time.Duration
+	//The new ID of a group which has been migrated to a supergroup
+	MigrateToChatID int64
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Telegram error %d: %s", e.Code, e.Description)
+}
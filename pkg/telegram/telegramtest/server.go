@@ -0,0 +1,591 @@
+// Package telegramtest provides an in-process fake of the Telegram Bot API for tests
+package telegramtest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/corporateanon/barker/pkg/telegram"
+)
+
+// Failure is a scripted unsuccessful response
+type Failure struct {
+	Code        int
+	Description string
+	//Sent as parameters.retry_after
+	RetryAfter int
+	//Sent as parameters.migrate_to_chat_id
+	MigrateToChatID int64
+	//The response is held for this long, so a client with a shorter timeout gives up
+	Delay time.Duration
+	//How many requests fail, 1 if not set
+	Times int
+}
+
+func Blocked() Failure {
+	return Failure{Code: http.StatusForbidden, Description: "Forbidden: bot was blocked by the user"}
+}
+
+func Deactivated() Failure {
+	return Failure{Code: http.StatusForbidden, Description: "Forbidden: user is deactivated"}
+}
+
+func ChatNotFound() Failure {
+	return Failure{Code: http.StatusBadRequest, Description: "Bad Request: chat not found"}
+}
+
+func BadEntity() Failure {
+	return Failure{Code: http.StatusBadRequest, Description: "Bad Request: can't parse entities: Unsupported start tag \"x\" at byte offset 0"}
+}
+
+// Migrated is the response to a message sent to a group which has become a supergroup
+func Migrated(newChatID int64) Failure {
+	return Failure{
+		Code:            http.StatusBadRequest,
+		Description:     "Bad Request: group chat was upgraded to a supergroup chat",
+		MigrateToChatID: newChatID,
+	}
+}
+
+func TooManyRequests(retryAfter int) Failure {
+	return Failure{
+		Code:        http.StatusTooManyRequests,
+		Description: "Too Many Requests: retry after " + strconv.Itoa(retryAfter),
+		RetryAfter:  retryAfter,
+	}
+}
+
+func Timeout(delay time.Duration) Failure {
+	return Failure{Code: http.StatusGatewayTimeout, Description: "Gateway Timeout", Delay: delay}
+}
+
+// Sent is a message accepted by the fake
+type Sent struct {
+	Token     string
+	Method    string
+	ChatID    int64
+	MessageID int64
+	Text      string
+	Photo     string
+	//Content of an uploaded photo, Photo is empty then
+	Upload      []byte
+	Caption     string
+	ParseMode   string
+	ReplyMarkup *telegram.InlineKeyboardMarkup
+	//Set by sendPoll, Text is the question
+	PollID      string
+	PollOptions []string
+	//The text or the caption has been changed by editMessageText or editMessageCaption
+	Edited  bool
+	Deleted bool
+}
+
+type Webhook struct {
+	URL         string
+	SecretToken string
+}
+
+// Server is a fake Bot API. Unknown bot tokens get 401 unless AcceptAnyToken is called.
+type Server struct {
+	*httptest.Server
+
+	mutex         sync.Mutex
+	bots          map[string]telegram.User
+	failures      map[int64][]Failure
+	sent          []Sent
+	updates       map[string][]telegram.Update
+	updatesPushed chan struct{}
+	webhooks      map[string]Webhook
+	answered      []string
+	nextID        int64
+	anyToken      bool
+}
+
+func NewServer() *Server {
+	s := &Server{
+		bots:          map[string]telegram.User{},
+		failures:      map[int64][]Failure{},
+		updates:       map[string][]telegram.Update{},
+		updatesPushed: make(chan struct{}),
+		webhooks:      map[string]Webhook{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// AddBot registers a bot token. The bot gets an ID if user.ID is not set.
+func (s *Server) AddBot(token string, user telegram.User) telegram.User {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if user.ID == 0 {
+		user.ID = s.newID()
+	}
+	user.IsBot = true
+	s.bots[token] = user
+	return user
+}
+
+// AcceptAnyToken makes unknown tokens register new bots instead of getting 401
+func (s *Server) AcceptAnyToken() *Server {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.anyToken = true
+	return s
+}
+
+// RemoveBot revokes a bot token, so that the following requests get 401
+func (s *Server) RemoveBot(token string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.bots, token)
+}
+
+// Fail makes the next requests addressed to a chat fail. Chat ID 0 matches any request.
+func (s *Server) Fail(chatID int64, failure Failure) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if failure.Times <= 0 {
+		failure.Times = 1
+	}
+	s.failures[chatID] = append(s.failures[chatID], failure)
+}
+
+// Sent returns the messages accepted so far
+func (s *Server) Sent() []Sent {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Sent{}, s.sent...)
+}
+
+// Answered returns IDs of callback queries answered so far
+func (s *Server) Answered() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.answered...)
+}
+
+// PushUpdate queues an incoming update of a bot for getUpdates. The update ID is assigned if not set.
+func (s *Server) PushUpdate(token string, update telegram.Update) telegram.Update {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if update.UpdateID == 0 {
+		update.UpdateID = s.newID()
+	}
+	s.updates[token] = append(s.updates[token], update)
+	close(s.updatesPushed)
+	s.updatesPushed = make(chan struct{})
+	return update
+}
+
+// Webhook returns the webhook set by a bot
+func (s *Server) Webhook(token string) Webhook {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.webhooks[token]
+}
+
+func (s *Server) newID() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	//Paths look like /bot<token>/<method>
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/bot"), "/", 2)
+	if len(parts) != 2 || !strings.HasPrefix(r.URL.Path, "/bot") {
+		writeError(w, Failure{Code: http.StatusNotFound, Description: "Not Found"})
+		return
+	}
+	token, method := parts[0], parts[1]
+	params, err := readParams(r)
+	if err != nil {
+		writeError(w, Failure{Code: http.StatusBadRequest, Description: "Bad Request: " + err.Error()})
+		return
+	}
+
+	s.mutex.Lock()
+	bot, ok := s.bots[token]
+	if !ok && s.anyToken {
+		id := s.newID()
+		bot = telegram.User{ID: id, IsBot: true, FirstName: "Bot", UserName: "bot" + strconv.FormatInt(id, 10) + "_bot"}
+		s.bots[token] = bot
+		ok = true
+	}
+	s.mutex.Unlock()
+	if !ok {
+		writeError(w, Failure{Code: http.StatusUnauthorized, Description: "Unauthorized"})
+		return
+	}
+
+	switch strings.ToLower(method) {
+	case "getme":
+		writeResult(w, bot)
+	case "sendmessage":
+		s.send(w, token, "sendMessage", params)
+	case "sendphoto":
+		s.send(w, token, "sendPhoto", params)
+	case "sendpoll":
+		s.send(w, token, "sendPoll", params)
+	case "editmessagetext":
+		s.editMessage(w, token, params, "text")
+	case "editmessagecaption":
+		s.editMessage(w, token, params, "caption")
+	case "deletemessage":
+		s.deleteMessage(w, token, params)
+	case "answercallbackquery":
+		s.mutex.Lock()
+		s.answered = append(s.answered, params.str("callback_query_id"))
+		s.mutex.Unlock()
+		writeResult(w, true)
+	case "getupdates":
+		s.getUpdates(w, r, token, params)
+	case "setwebhook":
+		s.mutex.Lock()
+		s.webhooks[token] = Webhook{URL: params.str("url"), SecretToken: params.str("secret_token")}
+		s.mutex.Unlock()
+		writeResult(w, true)
+	case "deletewebhook":
+		s.mutex.Lock()
+		delete(s.webhooks, token)
+		s.mutex.Unlock()
+		writeResult(w, true)
+	default:
+		writeError(w, Failure{Code: http.StatusNotFound, Description: "Not Found: method not found"})
+	}
+}
+
+func (s *Server) send(w http.ResponseWriter, token string, method string, params params) {
+	chatID := params.int("chat_id")
+	if failure, ok := s.takeFailure(chatID); ok {
+		time.Sleep(failure.Delay)
+		writeError(w, failure)
+		return
+	}
+	if chatID == 0 {
+		writeError(w, Failure{Code: http.StatusBadRequest, Description: "Bad Request: chat_id is empty"})
+		return
+	}
+
+	s.mutex.Lock()
+	sent := Sent{
+		Token:       token,
+		Method:      method,
+		ChatID:      chatID,
+		MessageID:   s.newID(),
+		Text:        params.str("text"),
+		Photo:       params.str("photo"),
+		Upload:      params.upload("photo"),
+		Caption:     params.str("caption"),
+		ParseMode:   params.str("parse_mode"),
+		ReplyMarkup: params.markup("reply_markup"),
+	}
+	if method == "sendPoll" {
+		sent.Text = params.str("question")
+		sent.PollID = "poll-" + strconv.FormatInt(sent.MessageID, 10)
+		sent.PollOptions = params.pollOptions("options")
+	}
+	s.sent = append(s.sent, sent)
+	bot := s.bots[token]
+	s.mutex.Unlock()
+
+	message := &telegram.Message{
+		MessageID: sent.MessageID,
+		From:      &bot,
+		Chat:      &telegram.Chat{ID: chatID, Type: "private"},
+		Date:      time.Now().Unix(),
+		Text:      sent.Text,
+		Caption:   sent.Caption,
+	}
+	if sent.PollID != "" {
+		message.Text = ""
+		message.Poll = &telegram.Poll{
+			ID:          sent.PollID,
+			Question:    sent.Text,
+			IsAnonymous: params.str("is_anonymous") == "true",
+		}
+		for _, option := range sent.PollOptions {
+			message.Poll.Options = append(message.Poll.Options, telegram.PollOption{Text: option})
+		}
+	}
+	if sent.Photo != "" || sent.Upload != nil {
+		//Telegram assigns a file_id to uploaded photos and to photos fetched by URL, a known file_id stays the same
+		fileID := sent.Photo
+		if sent.Upload != nil || strings.Contains(sent.Photo, "://") {
+			fileID = "photo-" + strconv.FormatInt(sent.MessageID, 10)
+		}
+		message.Photo = []telegram.PhotoSize{{
+			FileID:       fileID,
+			FileUniqueID: "unique-" + strconv.FormatInt(sent.MessageID, 10),
+			Width:        800,
+			Height:       600,
+		}}
+	}
+	writeResult(w, message)
+}
+
+// editMessage changes the text or the caption of a sent message
+func (s *Server) editMessage(w http.ResponseWriter, token string, params params, field string) {
+	chatID := params.int("chat_id")
+	if failure, ok := s.takeFailure(chatID); ok {
+		time.Sleep(failure.Delay)
+		writeError(w, failure)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sent := s.findSent(token, chatID, params.int("message_id"))
+	if sent == nil {
+		writeError(w, Failure{Code: http.StatusBadRequest, Description: "Bad Request: message to edit not found"})
+		return
+	}
+	current := &sent.Text
+	if field == "caption" {
+		current = &sent.Caption
+	}
+	if (field == "caption") != (sent.Photo != "" || sent.Upload != nil) {
+		writeError(w, Failure{Code: http.StatusBadRequest, Description: "Bad Request: there is no " + field + " in the message to edit"})
+		return
+	}
+	text := params.str(field)
+	if *current == text {
+		writeError(w, Failure{Code: http.StatusBadRequest, Description: "Bad Request: message is not modified: specified new message content and reply markup are exactly the same as a current content and reply markup of the message"})
+		return
+	}
+	*current = text
+	sent.ParseMode = params.str("parse_mode")
+	sent.ReplyMarkup = params.markup("reply_markup")
+	sent.Edited = true
+	bot := s.bots[token]
+	message := &telegram.Message{
+		MessageID: sent.MessageID,
+		From:      &bot,
+		Chat:      &telegram.Chat{ID: chatID, Type: "private"},
+		Date:      time.Now().Unix(),
+		Text:      sent.Text,
+		Caption:   sent.Caption,
+	}
+	writeResult(w, message)
+}
+
+func (s *Server) deleteMessage(w http.ResponseWriter, token string, params params) {
+	chatID := params.int("chat_id")
+	if failure, ok := s.takeFailure(chatID); ok {
+		time.Sleep(failure.Delay)
+		writeError(w, failure)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sent := s.findSent(token, chatID, params.int("message_id"))
+	if sent == nil {
+		writeError(w, Failure{Code: http.StatusBadRequest, Description: "Bad Request: message to delete not found"})
+		return
+	}
+	sent.Deleted = true
+	writeResult(w, true)
+}
+
+// findSent looks up a message which has not been deleted, the caller holds the mutex
+func (s *Server) findSent(token string, chatID int64, messageID int64) *Sent {
+	for i := range s.sent {
+		sent := &s.sent[i]
+		if sent.Token == token && sent.ChatID == chatID && sent.MessageID == messageID && !sent.Deleted {
+			return sent
+		}
+	}
+	return nil
+}
+
+func (s *Server) getUpdates(w http.ResponseWriter, r *http.Request, token string, params params) {
+	offset := params.int("offset")
+	limit := int(params.int("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	deadline := time.After(time.Duration(params.int("timeout")) * time.Second)
+
+	for {
+		s.mutex.Lock()
+		if webhook, ok := s.webhooks[token]; ok && webhook.URL != "" {
+			s.mutex.Unlock()
+			writeError(w, Failure{Code: http.StatusConflict, Description: "Conflict: can't use getUpdates method while webhook is active; use deleteWebhook to delete the webhook first"})
+			return
+		}
+		//Like Telegram, an offset confirms all the updates before it
+		pending := []telegram.Update{}
+		for _, update := range s.updates[token] {
+			if update.UpdateID >= offset {
+				pending = append(pending, update)
+			}
+		}
+		s.updates[token] = pending
+		pushed := s.updatesPushed
+		s.mutex.Unlock()
+
+		if len(pending) > 0 {
+			if len(pending) > limit {
+				pending = pending[:limit]
+			}
+			writeResult(w, pending)
+			return
+		}
+		select {
+		case <-pushed:
+		case <-deadline:
+			writeResult(w, pending)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) takeFailure(chatID int64) (Failure, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, key := range []int64{chatID, 0} {
+		failures := s.failures[key]
+		if len(failures) == 0 {
+			continue
+		}
+		failure := failures[0]
+		failures[0].Times--
+		if failures[0].Times == 0 {
+			s.failures[key] = failures[1:]
+		}
+		return failure, true
+	}
+	return Failure{}, false
+}
+
+// params are Bot API method parameters, passed either as JSON or as a form
+type params map[string]interface{}
+
+func readParams(r *http.Request) (params, error) {
+	p := params{}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		if err := decoder.Decode(&p); err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+	if err := r.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+		return nil, err
+	}
+	for key, values := range r.Form {
+		p[key] = values[0]
+	}
+	if r.MultipartForm != nil {
+		for key, files := range r.MultipartForm.File {
+			file, err := files[0].Open()
+			if err != nil {
+				return nil, err
+			}
+			content, err := ioutil.ReadAll(file)
+			file.Close()
+			if err != nil {
+				return nil, err
+			}
+			p[key] = content
+		}
+	}
+	return p, nil
+}
+
+// upload returns the content of an uploaded file, nil if the parameter is not a file
+func (p params) upload(key string) []byte {
+	content, _ := p[key].([]byte)
+	return content
+}
+
+func (p params) str(key string) string {
+	switch value := p[key].(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	default:
+		return ""
+	}
+}
+
+func (p params) int(key string) int64 {
+	value, _ := strconv.ParseInt(p.str(key), 10, 64)
+	return value
+}
+
+// markup decodes an inline keyboard, which is a JSON-serialized string in form bodies
+func (p params) markup(key string) *telegram.InlineKeyboardMarkup {
+	var raw []byte
+	switch value := p[key].(type) {
+	case nil:
+		return nil
+	case string:
+		raw = []byte(value)
+	default:
+		raw, _ = json.Marshal(value)
+	}
+	markup := &telegram.InlineKeyboardMarkup{}
+	if err := json.Unmarshal(raw, markup); err != nil {
+		return nil
+	}
+	return markup
+}
+
+// pollOptions decodes options of a poll, which are either strings or InputPollOption objects
+func (p params) pollOptions(key string) []string {
+	var raw []byte
+	switch value := p[key].(type) {
+	case nil:
+		return nil
+	case string:
+		raw = []byte(value)
+	default:
+		raw, _ = json.Marshal(value)
+	}
+	options := []telegram.InputPollOption{}
+	if err := json.Unmarshal(raw, &options); err == nil {
+		texts := make([]string, len(options))
+		for i, option := range options {
+			texts[i] = option.Text
+		}
+		return texts
+	}
+	texts := []string{}
+	json.Unmarshal(raw, &texts)
+	return texts
+}
+
+func writeResult(w http.ResponseWriter, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": result})
+}
+
+func writeError(w http.ResponseWriter, failure Failure) {
+	body := map[string]interface{}{
+		"ok":          false,
+		"error_code":  failure.Code,
+		"description": failure.Description,
+	}
+	if failure.RetryAfter > 0 {
+		body["parameters"] = map[string]interface{}{"retry_after": failure.RetryAfter}
+	}
+	if failure.MigrateToChatID != 0 {
+		body["parameters"] = map[string]interface{}{"migrate_to_chat_id": failure.MigrateToChatID}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(failure.Code)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,210 @@
+package telegram
+
+// The subset of Telegram Bot API types used by barker, see https://core.telegram.org/bots/api
+
+type User struct {
+	ID                      int64  `json:"id"`
+	IsBot                   bool   `json:"is_bot"`
+	FirstName               string `json:"first_name"`
+	LastName                string `json:"last_name,omitempty"`
+	UserName                string `json:"username,omitempty"`
+	LanguageCode            string `json:"language_code,omitempty"`
+	CanJoinGroups           bool   `json:"can_join_groups,omitempty"`
+	CanReadAllGroupMessages bool   `json:"can_read_all_group_messages,omitempty"`
+	SupportsInlineQueries   bool   `json:"supports_inline_queries,omitempty"`
+}
+
+type Chat struct {
+	ID        int64  `json:"id"`
+	Type      string `json:"type"`
+	Title     string `json:"title,omitempty"`
+	UserName  string `json:"username,omitempty"`
+	FirstName string `json:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty"`
+}
+
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	From      *User  `json:"from,omitempty"`
+	Chat      *Chat  `json:"chat"`
+	Date      int64  `json:"date"`
+	Text      string `json:"text,omitempty"`
+	//Largest photo size is the last one
+	Photo   []PhotoSize `json:"photo,omitempty"`
+	Caption string      `json:"caption,omitempty"`
+	Poll    *Poll       `json:"poll,omitempty"`
+	//Service message of a group which has been migrated to a supergroup with this ID
+	MigrateToChatID int64 `json:"migrate_to_chat_id,omitempty"`
+}
+
+type Poll struct {
+	ID          string       `json:"id"`
+	Question    string       `json:"question"`
+	Options     []PollOption `json:"options"`
+	IsAnonymous bool         `json:"is_anonymous"`
+}
+
+type PollOption struct {
+	Text       string `json:"text"`
+	VoterCount int    `json:"voter_count"`
+}
+
+// PollAnswer is sent when a user votes in a non-anonymous poll sent by the bot
+type PollAnswer struct {
+	PollID string `json:"poll_id"`
+	User   *User  `json:"user"`
+	//Empty if the vote has been retracted
+	OptionIDs []int `json:"option_ids"`
+}
+
+type InputPollOption struct {
+	Text string `json:"text"`
+}
+
+type SendPollRequest struct {
+	ChatID   int64             `json:"chat_id"`
+	Question string            `json:"question"`
+	Options  []InputPollOption `json:"options"`
+	//Telegram reports votes of non-anonymous polls only
+	IsAnonymous bool `json:"is_anonymous"`
+}
+
+type SendMessageRequest struct {
+	ChatID                int64                 `json:"chat_id"`
+	Text                  string                `json:"text"`
+	ParseMode             string                `json:"parse_mode,omitempty"`
+	DisableWebPagePreview bool                  `json:"disable_web_page_preview,omitempty"`
+	ReplyMarkup           *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+type InlineKeyboardButton struct {
+	Text string `json:"text"`
+	URL  string `json:"url,omitempty"`
+	//Up to 64 bytes sent back in a callback query when the button is pressed
+	CallbackData string `json:"callback_data,omitempty"`
+}
+
+// CallbackQuery is sent when a user presses an inline keyboard button
+type CallbackQuery struct {
+	ID   string `json:"id"`
+	From *User  `json:"from"`
+	//The message with the button, missing if the message is too old
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data,omitempty"`
+}
+
+type AnswerCallbackQueryRequest struct {
+	CallbackQueryID string `json:"callback_query_id"`
+	Text            string `json:"text,omitempty"`
+}
+
+type PhotoSize struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	FileSize     int    `json:"file_size,omitempty"`
+}
+
+// Statuses of a chat member
+const (
+	ChatMemberStatusMember = "member"
+	//A private chat member is kicked when the user has blocked the bot
+	ChatMemberStatusKicked = "kicked"
+	ChatMemberStatusLeft   = "left"
+	//Bots need to be administrators to post in channels
+	ChatMemberStatusAdministrator = "administrator"
+)
+
+type ChatMember struct {
+	User   *User  `json:"user"`
+	Status string `json:"status"`
+}
+
+type ChatMemberUpdated struct {
+	Chat          *Chat       `json:"chat"`
+	From          *User       `json:"from"`
+	Date          int64       `json:"date"`
+	OldChatMember *ChatMember `json:"old_chat_member"`
+	NewChatMember *ChatMember `json:"new_chat_member"`
+}
+
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message,omitempty"`
+	//The bot's own membership has changed, e.g. a user has blocked or unblocked it
+	MyChatMember  *ChatMemberUpdated `json:"my_chat_member,omitempty"`
+	CallbackQuery *CallbackQuery     `json:"callback_query,omitempty"`
+	PollAnswer    *PollAnswer        `json:"poll_answer,omitempty"`
+}
+
+type SendPhotoRequest struct {
+	ChatID int64 `json:"chat_id"`
+	//file_id of a photo known to Telegram or an HTTP URL
+	Photo       string                `json:"photo"`
+	Caption     string                `json:"caption,omitempty"`
+	ParseMode   string                `json:"parse_mode,omitempty"`
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+// InputFile is the content of a file uploaded with a request
+type InputFile struct {
+	FileName string
+	Content  []byte
+}
+
+type EditMessageCaptionRequest struct {
+	ChatID    int64  `json:"chat_id"`
+	MessageID int64  `json:"message_id"`
+	Caption   string `json:"caption"`
+	ParseMode string `json:"parse_mode,omitempty"`
+	//Without a markup the inline keyboard is removed
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+type EditMessageTextRequest struct {
+	ChatID    int64  `json:"chat_id"`
+	MessageID int64  `json:"message_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode,omitempty"`
+	//Without a markup the inline keyboard is removed
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+type DeleteMessageRequest struct {
+	ChatID    int64 `json:"chat_id"`
+	MessageID int64 `json:"message_id"`
+}
+
+type GetUpdatesRequest struct {
+	Offset int64 `json:"offset,omitempty"`
+	Limit  int   `json:"limit,omitempty"`
+	//Long polling timeout in seconds
+	Timeout        int      `json:"timeout,omitempty"`
+	AllowedUpdates []string `json:"allowed_updates,omitempty"`
+}
+
+type SetWebhookRequest struct {
+	//An empty URL removes the webhook
+	URL            string   `json:"url"`
+	SecretToken    string   `json:"secret_token,omitempty"`
+	AllowedUpdates []string `json:"allowed_updates,omitempty"`
+}
+
+// response is an envelope of every Bot API response
+type response struct {
+	OK          bool                `json:"ok"`
+	Result      interface{}         `json:"result,omitempty"`
+	ErrorCode   int                 `json:"error_code,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Parameters  *responseParameters `json:"parameters,omitempty"`
+}
+
+type responseParameters struct {
+	MigrateToChatID int64 `json:"migrate_to_chat_id,omitempty"`
+	RetryAfter      int   `json:"retry_after,omitempty"`
+}
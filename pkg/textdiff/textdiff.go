@@ -0,0 +1,65 @@
+// Package textdiff compares texts line by line
+package textdiff
+
+import "strings"
+
+// Operations of a line
+const (
+	Equal   = "="
+	Removed = "-"
+	Added   = "+"
+)
+
+type Line struct {
+	Op   string
+	Text string
+}
+
+// Lines returns the lines of both texts, each either kept, removed from the first text or added in the second one.
+// Removed lines come before the lines which replace them.
+func Lines(from string, to string) []Line {
+	a := splitLines(from)
+	b := splitLines(to)
+
+	//common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	lines := []Line{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, Line{Equal, a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			lines = append(lines, Line{Removed, a[i]})
+			i++
+		default:
+			lines = append(lines, Line{Added, b[j]})
+			j++
+		}
+	}
+	return lines
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,57 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Answer is a user's choice in a survey or a poll campaign. A user has one answer per campaign,
+// the last choice wins.
+type Answer struct {
+	BotID int64 `json:"BotID,omitempty"`
+	//Either CampaignID or PollID identifies the campaign
+	CampaignID int64  `json:"CampaignID,omitempty"`
+	PollID     string `json:"PollID,omitempty"`
+	TelegramID int64  `binding:"required" json:"TelegramID,omitempty"`
+	//Index of the option in Campaign.Options
+	Option     int       `binding:"min=0" json:"Option,omitempty"`
+	AnsweredAt time.Time `json:"AnsweredAt,omitempty" ts_type:"string"`
+}
+
+type SurveyResults struct {
+	Answers int64          `json:"Answers,omitempty"`
+	Options []OptionResult `json:"Options,omitempty"`
+}
+
+type OptionResult struct {
+	Option  int    `json:"Option,omitempty"`
+	Text    string `json:"Text,omitempty"`
+	Answers int64  `json:"Answers,omitempty"`
+	//Answers of this option per all answers
+	Share float64 `json:"Share,omitempty"`
+}
+
+const answerCallbackDataPrefix = "a"
+
+// EncodeAnswerCallbackData builds callback data of a survey option button
+func EncodeAnswerCallbackData(campaignID int64, option int) string {
+	return fmt.Sprintf("%s:%d:%d", answerCallbackDataPrefix, campaignID, option)
+}
+
+// ParseAnswerCallbackData decodes data built by EncodeAnswerCallbackData. ok is false for other data.
+func ParseAnswerCallbackData(data string) (campaignID int64, option int, ok bool) {
+	parts := strings.Split(data, ":")
+	if len(parts) != 3 || parts[0] != answerCallbackDataPrefix {
+		return 0, 0, false
+	}
+	campaignID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || campaignID <= 0 {
+		return 0, 0, false
+	}
+	if option, err = strconv.Atoi(parts[2]); err != nil || option < 0 {
+		return 0, 0, false
+	}
+	return campaignID, option, true
+}
@@ -0,0 +1,102 @@
+package types
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// How an auto-responder rule matches an incoming message
+const (
+	//The whole message equals the pattern, ignoring case and surrounding spaces
+	AutoResponderMatchExact = "exact"
+	//The message contains the pattern as whole words, ignoring case
+	AutoResponderMatchKeyword = "keyword"
+	//The message matches the pattern as a regular expression
+	AutoResponderMatchRegex = "regex"
+)
+
+// AutoResponderRule replies to incoming messages of a bot which match it.
+// Rules are tried by ascending Priority, then by ID; the first matching one is applied.
+type AutoResponderRule struct {
+	ID       int64  `json:"ID,omitempty"`
+	BotID    int64  `json:"BotID,omitempty"`
+	Match    string `binding:"required,oneof=exact keyword regex" json:"Match,omitempty"`
+	Pattern  string `binding:"required,max=1000" json:"Pattern,omitempty"`
+	Reply    string `binding:"required,max=4096" json:"Reply,omitempty"`
+	Priority int    `json:"Priority,omitempty"`
+	//Actions applied to the author of a matching message
+	AddTag           string    `binding:"max=64" json:"AddTag,omitempty"`
+	Unsubscribe      bool      `json:"Unsubscribe,omitempty"`
+	EnrollSequenceID int64     `json:"EnrollSequenceID,omitempty"`
+	CreatedAt        time.Time `json:"CreatedAt,omitempty" ts_type:"string"`
+}
+
+// Validate checks what binding cannot, i.e. that a regex pattern compiles
+func (rule *AutoResponderRule) Validate() error {
+	if rule.Match != AutoResponderMatchRegex {
+		return nil
+	}
+	_, err := compilePattern(rule.Pattern)
+	return err
+}
+
+// Matches tells whether the rule applies to the text of a message
+func (rule *AutoResponderRule) Matches(text string) bool {
+	switch rule.Match {
+	case AutoResponderMatchExact:
+		return strings.EqualFold(strings.TrimSpace(text), strings.TrimSpace(rule.Pattern))
+	case AutoResponderMatchKeyword:
+		return containsWords(words(text), words(rule.Pattern))
+	case AutoResponderMatchRegex:
+		pattern, err := compilePattern(rule.Pattern)
+		return err == nil && pattern.MatchString(text)
+	default:
+		return false
+	}
+}
+
+// compiledPatterns keeps regex patterns of rules compiled, since rules are loaded for every incoming message
+var compiledPatterns sync.Map
+
+// compilePattern compiles a regex pattern once per process
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if compiled, ok := compiledPatterns.Load(pattern); ok {
+		return compiled.(*regexp.Regexp), nil
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	compiledPatterns.Store(pattern, compiled)
+	return compiled, nil
+}
+
+// words splits lowercased text by anything but letters and digits.
+// Unlike \b of regexp it works for any alphabet.
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func containsWords(haystack []string, needle []string) bool {
+	if len(needle) == 0 {
+		return false
+	}
+	for start := 0; start+len(needle) <= len(haystack); start++ {
+		found := true
+		for i, word := range needle {
+			if haystack[start+i] != word {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
@@ -2,10 +2,38 @@ package types
 
 import "time"
 
+// How a bot receives Telegram updates
+const (
+	//Telegram pushes updates to POST /telegram/:BotID/webhook. Bots without a mode use it.
+	IngestionModeWebhook = "webhook"
+	//barker pulls updates with getUpdates
+	IngestionModePolling = "polling"
+)
+
 type Bot struct {
 	ID              int64     `json:"ID,omitempty"`
 	Title           string    `binding:"required" json:"Title,omitempty"`
 	Token           string    `binding:"required" json:"Token,omitempty"`
 	RRAccessTime    time.Time `json:"RRAccessTime,omitempty" ts_type:"string"`
 	RRPossiblyEmpty bool      `json:"RRPossiblyEmpty,omitempty"`
+	//Telegram sends it in the X-Telegram-Bot-Api-Secret-Token header of webhook requests.
+	//The webhook is disabled while it is empty.
+	WebhookSecret string `json:"WebhookSecret,omitempty"`
+	IngestionMode string `binding:"omitempty,oneof=webhook polling" json:"IngestionMode,omitempty"`
+	//ID of the next update to fetch in polling mode
+	UpdateOffset int64 `json:"UpdateOffset,omitempty"`
+	//Identity reported by getMe
+	TelegramID              int64  `json:"TelegramID,omitempty"`
+	TelegramUserName        string `json:"TelegramUserName,omitempty"`
+	CanJoinGroups           bool   `json:"CanJoinGroups,omitempty"`
+	CanReadAllGroupMessages bool   `json:"CanReadAllGroupMessages,omitempty"`
+	SupportsInlineQueries   bool   `json:"SupportsInlineQueries,omitempty"`
+	//Telegram has rejected the token on the last validation
+	TokenRevoked bool      `json:"TokenRevoked,omitempty"`
+	ValidatedAt  time.Time `json:"ValidatedAt,omitempty" ts_type:"string"`
+	//Conversions are attributed to a campaign delivered within this many hours,
+	//DefaultConversionWindowHours if zero
+	ConversionWindowHours int `binding:"omitempty,min=1,max=8760" json:"ConversionWindowHours,omitempty"`
+	//Campaigns of the bot must be approved before they run
+	RequireApproval bool `json:"RequireApproval,omitempty"`
 }
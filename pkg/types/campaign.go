@@ -1,9 +1,132 @@
 package types
 
+import (
+	"strings"
+	"time"
+)
+
+// Kinds of campaigns which collect answers
+const (
+	//The message carries a callback button per answer option
+	CampaignKindSurvey = "survey"
+	//The message is a native Telegram poll, its question is the campaign message
+	CampaignKindPoll = "poll"
+)
+
+// Lifecycle states of a campaign
+const (
+	//Being written, the default for new campaigns
+	CampaignStateDraft = "draft"
+	//Submitted for approval
+	CampaignStatePendingApproval = "pending_approval"
+	//Approved, ready to run
+	CampaignStateApproved = "approved"
+	//Being sent, the only state in which a campaign is active
+	CampaignStateRunning = "running"
+	//Stopped, can be resumed
+	CampaignStatePaused = "paused"
+	//Stopped for good
+	CampaignStateCompleted = "completed"
+	//Hidden, cannot be changed anymore
+	CampaignStateArchived = "archived"
+)
+
 type Campaign struct {
 	ID      int64  `json:"ID,omitempty"`
 	BotID   int64  `json:"BotID,omitempty"`
 	Title   string `binding:"required" json:"Title,omitempty"`
 	Message string `binding:"required" json:"Message,omitempty"`
-	Active  bool   `json:"Active,omitempty"`
+	//Formatting of the message and its localizations: HTML, MarkdownV2 or none
+	ParseMode string `binding:"omitempty,oneof=HTML MarkdownV2" json:"ParseMode,omitempty"`
+	//Set while the campaign is running. Setting it on its own starts or pauses the campaign.
+	Active bool `json:"Active,omitempty"`
+	//Lifecycle state, draft for a new campaign unless it is Active
+	State string `binding:"omitempty,oneof=draft pending_approval approved running paused completed archived" json:"State,omitempty"`
+	//Who has approved the campaign and when. Changing the message of an approved campaign makes it a draft again.
+	ApprovedBy string    `json:"ApprovedBy,omitempty"`
+	ApprovedAt time.Time `json:"ApprovedAt,omitempty" ts_type:"string"`
+	//Current revision of the title and the message, kept by the server
+	Revision int64 `json:"Revision,omitempty"`
+	//URLs of the message are replaced with per-delivery redirect URLs to count clicks
+	TrackLinks bool `json:"TrackLinks,omitempty"`
+	//Inline keyboard attached to the message. Clicks are counted in the campaign statistics.
+	Buttons []CampaignButton `binding:"omitempty,max=100,dive" json:"Buttons,omitempty"`
+	//Empty for a plain message
+	Kind string `binding:"omitempty,oneof=survey poll" json:"Kind,omitempty"`
+	//Answer options of a survey or a poll
+	Options []string `binding:"required_with=Kind,omitempty,min=2,max=10,dive,required,max=100" json:"Options,omitempty"`
+	//If set, only users with this first or last deep-link source get the campaign
+	FirstSource string `binding:"max=64" json:"FirstSource,omitempty"`
+	LastSource  string `binding:"max=64" json:"LastSource,omitempty"`
+	//Kind of chats which get the campaign: private, group (including supergroups), channel or all. Private chats by default.
+	ChatType string `binding:"omitempty,oneof=private group channel all" json:"ChatType,omitempty"`
+	//Translations of the message for users with other languages. Users whose language has none get Message.
+	Localizations []CampaignLocalization `binding:"omitempty,max=20,dive" json:"Localizations,omitempty"`
+	//Photo from the media library, the message becomes its caption
+	MediaID int64 `json:"MediaID,omitempty"`
+}
+
+// CampaignLocalization is the message of a campaign in a language, e.g. "de" or "pt-br"
+type CampaignLocalization struct {
+	Language string `binding:"required,max=16" json:"Language,omitempty"`
+	Message  string `binding:"required" json:"Message,omitempty"`
+}
+
+// Localize returns the campaign with the message in the language of a user and that language.
+// A localization for a language with a region (pt-br) is preferred, then one for the base language (pt).
+// The campaign itself and an empty language are returned if there is no localization for the user.
+func (campaign *Campaign) Localize(languageCode string) (*Campaign, string) {
+	languageCode = normalizeLanguage(languageCode)
+	if languageCode == "" {
+		return campaign, ""
+	}
+	baseLanguage := strings.SplitN(languageCode, "-", 2)[0]
+	var match *CampaignLocalization
+	for i := range campaign.Localizations {
+		localization := &campaign.Localizations[i]
+		language := normalizeLanguage(localization.Language)
+		if language == languageCode {
+			match = localization
+			break
+		}
+		if language == baseLanguage && match == nil {
+			match = localization
+		}
+	}
+	if match == nil {
+		return campaign, ""
+	}
+	localized := *campaign
+	localized.Message = match.Message
+	return &localized, normalizeLanguage(match.Language)
+}
+
+// normalizeLanguage brings language tags like "pt_BR" and "pt-br" to the same form
+func normalizeLanguage(language string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(language)), "_", "-")
+}
+
+// CampaignButton is a callback button of the inline keyboard, or a link button if it has a URL.
+// Buttons with the same Row are placed side by side.
+type CampaignButton struct {
+	Text string `binding:"required" json:"Text,omitempty"`
+	Row  int    `binding:"min=0" json:"Row,omitempty"`
+	//Opened by the button, presses of link buttons are not counted as clicks
+	URL string `json:"URL,omitempty"`
+}
+
+// CampaignPreview is the message of a campaign as a user would get it
+type CampaignPreview struct {
+	//Language of the localization, empty for the default message
+	Language  string `json:"Language,omitempty"`
+	Text      string `json:"Text,omitempty"`
+	ParseMode string `json:"ParseMode,omitempty"`
+	//Text shown by Telegram, without the markup
+	PlainText string `json:"PlainText,omitempty"`
+	//Length of the plain text as Telegram counts it, and its limit
+	Length    int              `json:"Length,omitempty"`
+	MaxLength int              `json:"MaxLength,omitempty"`
+	MediaID   int64            `json:"MediaID,omitempty"`
+	Options   []string         `json:"Options,omitempty"`
+	Buttons   []CampaignButton `json:"Buttons,omitempty"`
 }
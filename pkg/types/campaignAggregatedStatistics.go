@@ -6,4 +6,49 @@ type CampaignAggregatedStatistics struct {
 	Errors    int64 `json:"Errors,omitempty"`
 	Pending   int64 `json:"Pending,omitempty"`
 	TimedOut  int64 `json:"TimedOut,omitempty"`
+	//Presses of all buttons
+	Clicks int64 `json:"Clicks,omitempty"`
+	//Users who have pressed any button
+	Clickers int64 `json:"Clickers,omitempty"`
+	//Clickers per delivered message
+	ClickThroughRate float64            `json:"ClickThroughRate,omitempty"`
+	Buttons          []ButtonStatistics `json:"Buttons,omitempty"`
+	//Openings of tracked links
+	LinkClicks int64 `json:"LinkClicks,omitempty"`
+	//Recipients who have opened any tracked link
+	UniqueLinkClicks int64 `json:"UniqueLinkClicks,omitempty"`
+	//Conversion events attributed to the campaign and the sum of their values
+	Conversions int64   `json:"Conversions,omitempty"`
+	Revenue     float64 `json:"Revenue,omitempty"`
+}
+
+type ButtonStatistics struct {
+	//Index of the button in Campaign.Buttons
+	Button           int     `json:"Button,omitempty"`
+	Text             string  `json:"Text,omitempty"`
+	Clicks           int64   `json:"Clicks,omitempty"`
+	Clickers         int64   `json:"Clickers,omitempty"`
+	ClickThroughRate float64 `json:"ClickThroughRate,omitempty"`
+}
+
+// CampaignLanguageStatistics is the performance of a campaign localization. The default message has an empty Language.
+type CampaignLanguageStatistics struct {
+	Language         string  `json:"Language,omitempty"`
+	Delivered        int64   `json:"Delivered,omitempty"`
+	Errors           int64   `json:"Errors,omitempty"`
+	Clickers         int64   `json:"Clickers,omitempty"`
+	ClickThroughRate float64 `json:"ClickThroughRate,omitempty"`
+	Conversions      int64   `json:"Conversions,omitempty"`
+	Revenue          float64 `json:"Revenue,omitempty"`
+}
+
+// CampaignSourceStatistics is the performance of a campaign among users who have come by a deep-link source
+type CampaignSourceStatistics struct {
+	Source           string  `json:"Source,omitempty"`
+	Delivered        int64   `json:"Delivered,omitempty"`
+	Errors           int64   `json:"Errors,omitempty"`
+	Clickers         int64   `json:"Clickers,omitempty"`
+	ClickThroughRate float64 `json:"ClickThroughRate,omitempty"`
+	Conversions      int64   `json:"Conversions,omitempty"`
+	Revenue          float64 `json:"Revenue,omitempty"`
 }
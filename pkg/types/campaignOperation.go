@@ -0,0 +1,119 @@
+package types
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Kinds of campaign operations
+const (
+	//Replace the text of every delivered message
+	CampaignOperationEdit = "edit"
+	//Delete every delivered message
+	CampaignOperationRecall = "recall"
+	//Send the campaign to the testers of the bot
+	CampaignOperationTest = "test"
+)
+
+// MessageJobReply is the kind of a message job which sends a one-off message to a user,
+// e.g. a reply from the inbox. It belongs to no operation.
+const MessageJobReply = "reply"
+
+// MessageJobSequence is the kind of a message job which sends a step of a sequence the user is enrolled in
+const MessageJobSequence = "sequence"
+
+type MessageJobState int
+
+const (
+	MessageJobStateQueued   MessageJobState = 1
+	MessageJobStateProgress                 = 2
+	MessageJobStateSuccess                  = 3
+	MessageJobStateFail                     = 4
+)
+
+func (state MessageJobState) ToString() (string, error) {
+	switch state {
+	case MessageJobStateQueued:
+		return "Queued", nil
+	case MessageJobStateProgress:
+		return "Progress", nil
+	case MessageJobStateSuccess:
+		return "Success", nil
+	case MessageJobStateFail:
+		return "Fail", nil
+	default:
+		return "", errors.New("Wrong state")
+	}
+}
+
+func MessageJobStateFromString(in string) (MessageJobState, error) {
+	switch strings.ToLower(in) {
+	case "queued":
+		return MessageJobStateQueued, nil
+	case "progress":
+		return MessageJobStateProgress, nil
+	case "success":
+		return MessageJobStateSuccess, nil
+	case "fail":
+		return MessageJobStateFail, nil
+	default:
+		return 0, errors.New("Wrong state")
+	}
+}
+
+var AllMessageJobStates = []struct {
+	Value  MessageJobState
+	TSName string
+}{
+	{MessageJobStateQueued, "queued"},
+	{MessageJobStateProgress, "progress"},
+	{MessageJobStateSuccess, "success"},
+	{MessageJobStateFail, "fail"},
+}
+
+// CampaignOperation changes messages of a campaign which have already been delivered
+type CampaignOperation struct {
+	ID         int64     `json:"ID,omitempty"`
+	BotID      int64     `json:"BotID,omitempty"`
+	CampaignID int64     `json:"CampaignID,omitempty"`
+	Kind       string    `json:"Kind,omitempty"`
+	Text       string    `json:"Text,omitempty"`
+	CreatedAt  time.Time `json:"CreatedAt,omitempty" ts_type:"string"`
+	//Revision of the campaign made by an edit
+	Revision int64 `json:"Revision,omitempty"`
+	//Number of message jobs in each state
+	Queued   int64 `json:"Queued,omitempty"`
+	Progress int64 `json:"Progress,omitempty"`
+	Success  int64 `json:"Success,omitempty"`
+	Fail     int64 `json:"Fail,omitempty"`
+}
+
+// MessageJob edits or deletes a single delivered message, or sends a reply or a step of a sequence
+type MessageJob struct {
+	ID          int64 `json:"ID,omitempty"`
+	OperationID int64 `json:"OperationID,omitempty"`
+	BotID       int64 `json:"BotID,omitempty"`
+	CampaignID  int64 `json:"CampaignID,omitempty"`
+	SequenceID  int64 `json:"SequenceID,omitempty"`
+	TelegramID  int64 `json:"TelegramID,omitempty"`
+	//The message to change, or the sent message of a reply
+	MessageID int64  `json:"MessageID,omitempty"`
+	Kind      string `json:"Kind,omitempty"`
+	//New text of an edit, text of a reply
+	Text     string          `json:"Text,omitempty"`
+	State    MessageJobState `json:"State,omitempty"`
+	Reason   string          `json:"Reason,omitempty"`
+	WorkerID string          `json:"WorkerID,omitempty"`
+	//Buttons and survey options of the campaign, an edit keeps them under the message
+	Buttons       []CampaignButton `json:"Buttons,omitempty"`
+	SurveyOptions []string         `json:"SurveyOptions,omitempty"`
+	//The message is a photo, an edit changes its caption
+	Caption bool `json:"Caption,omitempty"`
+	//Parse mode of the campaign, an edit keeps the formatting
+	ParseMode string `json:"ParseMode,omitempty"`
+	//Campaign of a test send, with the message in the language of the tester, and its photo
+	Campaign *Campaign   `json:"Campaign,omitempty"`
+	FileID   string      `json:"FileID,omitempty"`
+	Media    *MediaAsset `json:"Media,omitempty"`
+}
@@ -0,0 +1,44 @@
+package types
+
+import "time"
+
+// CampaignRevision is a version of the title and the message of a campaign.
+// A revision is made when a campaign is created and whenever its title or message changes.
+type CampaignRevision struct {
+	CampaignID    int64                  `json:"CampaignID,omitempty"`
+	BotID         int64                  `json:"BotID,omitempty"`
+	Revision      int64                  `json:"Revision,omitempty"`
+	Title         string                 `json:"Title,omitempty"`
+	Message       string                 `json:"Message,omitempty"`
+	ParseMode     string                 `json:"ParseMode,omitempty"`
+	TrackLinks    bool                   `json:"TrackLinks,omitempty"`
+	Buttons       []CampaignButton       `json:"Buttons,omitempty"`
+	Kind          string                 `json:"Kind,omitempty"`
+	Options       []string               `json:"Options,omitempty"`
+	Localizations []CampaignLocalization `json:"Localizations,omitempty"`
+	MediaID       int64                  `json:"MediaID,omitempty"`
+	CreatedAt     time.Time              `json:"CreatedAt,omitempty" ts_type:"string"`
+}
+
+// CampaignRevisionDiff lists what has changed between two revisions of a campaign
+type CampaignRevisionDiff struct {
+	CampaignID int64                    `json:"CampaignID,omitempty"`
+	From       int64                    `json:"From,omitempty"`
+	To         int64                    `json:"To,omitempty"`
+	Changes    []CampaignRevisionChange `json:"Changes"`
+}
+
+// CampaignRevisionChange is a changed field, e.g. Title, Buttons or Localizations.de.
+// Lists are JSON-encoded. Texts come with a line diff.
+type CampaignRevisionChange struct {
+	Field string     `json:"Field,omitempty"`
+	From  string     `json:"From"`
+	To    string     `json:"To"`
+	Lines []DiffLine `json:"Lines,omitempty"`
+}
+
+// DiffLine is a line kept (=), removed (-) or added (+) by a change of a text
+type DiffLine struct {
+	Op   string `json:"Op,omitempty"`
+	Text string `json:"Text"`
+}
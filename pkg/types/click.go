@@ -0,0 +1,49 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Click is a press of an inline keyboard button under a delivered campaign message
+type Click struct {
+	BotID      int64 `json:"BotID,omitempty"`
+	CampaignID int64 `json:"CampaignID,omitempty"`
+	TelegramID int64 `json:"TelegramID,omitempty"`
+	//Index of the message variant the user has received
+	Variant int `json:"Variant,omitempty"`
+	//Index of the button in Campaign.Buttons
+	Button int `json:"Button,omitempty"`
+	//ID of the Telegram callback query, so that a redelivered update is counted once
+	CallbackQueryID string    `binding:"required" json:"CallbackQueryID,omitempty"`
+	CreatedAt       time.Time `json:"CreatedAt,omitempty" ts_type:"string"`
+}
+
+const callbackDataPrefix = "b"
+
+// EncodeCallbackData builds callback data of a campaign button.
+// It stays well under the 64 bytes allowed by Telegram.
+func EncodeCallbackData(campaignID int64, variant int, button int) string {
+	return fmt.Sprintf("%s:%d:%d:%d", callbackDataPrefix, campaignID, variant, button)
+}
+
+// ParseCallbackData decodes data built by EncodeCallbackData. ok is false for foreign data.
+func ParseCallbackData(data string) (campaignID int64, variant int, button int, ok bool) {
+	parts := strings.Split(data, ":")
+	if len(parts) != 4 || parts[0] != callbackDataPrefix {
+		return 0, 0, 0, false
+	}
+	campaignID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || campaignID <= 0 {
+		return 0, 0, 0, false
+	}
+	if variant, err = strconv.Atoi(parts[2]); err != nil || variant < 0 {
+		return 0, 0, 0, false
+	}
+	if button, err = strconv.Atoi(parts[3]); err != nil || button < 0 {
+		return 0, 0, 0, false
+	}
+	return campaignID, variant, button, true
+}
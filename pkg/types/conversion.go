@@ -0,0 +1,20 @@
+package types
+
+import "time"
+
+// DefaultConversionWindowHours is the attribution window of bots without their own
+const DefaultConversionWindowHours = 7 * 24
+
+// Conversion is a user action reported by the bot's backend, e.g. a purchase
+type Conversion struct {
+	ID         int64  `json:"ID,omitempty"`
+	BotID      int64  `json:"BotID,omitempty"`
+	TelegramID int64  `binding:"required" json:"TelegramID,omitempty"`
+	Event      string `binding:"required" json:"Event,omitempty"`
+	//E.g. the amount of a purchase, summed up as revenue
+	Value float64 `json:"Value,omitempty"`
+	//The most recent campaign delivered to the user within the bot's conversion window,
+	//zero if there is none
+	CampaignID int64     `json:"CampaignID,omitempty"`
+	CreatedAt  time.Time `json:"CreatedAt,omitempty" ts_type:"string"`
+}
@@ -53,4 +53,84 @@ type Delivery struct {
 	BotID      int64         `json:"BotID,omitempty"`
 	TelegramID int64         `json:"TelegramID,omitempty"`
 	State      DeliveryState `json:"State,omitempty"`
+	//ID of the worker which took this delivery
+	WorkerID string `json:"WorkerID,omitempty"`
+	//Why a delivery has got its state, e.g. an error description
+	Reason string `json:"Reason,omitempty"`
+	//ID of the message in the recipient's chat, reported with the Success state
+	MessageID int64 `json:"MessageID,omitempty"`
+	//ID of the Telegram poll of a poll campaign, votes refer to it
+	PollID string `json:"PollID,omitempty"`
+	//Language of the campaign localization sent, empty for the default message
+	Language string `json:"Language,omitempty"`
+	//Revision of the campaign the user has got, updated when an edit reaches them
+	Revision int64 `json:"Revision,omitempty"`
 }
+
+// DeliveryStateReport is a delivery in a bulk state report. The state is in its string form: progress, success or fail.
+type DeliveryStateReport struct {
+	CampaignID int64  `json:"CampaignID,omitempty"`
+	TelegramID int64  `json:"TelegramID,omitempty"`
+	State      string `binding:"required" json:"State,omitempty"`
+	Reason     string `json:"Reason,omitempty"`
+	MessageID  int64  `json:"MessageID,omitempty"`
+	PollID     string `json:"PollID,omitempty"`
+}
+
+func NewDeliveryStateReport(delivery *Delivery) (*DeliveryStateReport, error) {
+	state, err := delivery.State.ToString()
+	if err != nil {
+		return nil, err
+	}
+	return &DeliveryStateReport{
+		CampaignID: delivery.CampaignID,
+		TelegramID: delivery.TelegramID,
+		State:      strings.ToLower(state),
+		Reason:     delivery.Reason,
+		MessageID:  delivery.MessageID,
+		PollID:     delivery.PollID,
+	}, nil
+}
+
+// ToDelivery returns the reported delivery of a bot, or an error if the state is wrong
+func (report *DeliveryStateReport) ToDelivery(botID int64) (*Delivery, error) {
+	state, err := DeliveryStateFromString(report.State)
+	if err != nil {
+		return nil, err
+	}
+	return &Delivery{
+		BotID:      botID,
+		CampaignID: report.CampaignID,
+		TelegramID: report.TelegramID,
+		State:      state,
+		Reason:     report.Reason,
+		MessageID:  report.MessageID,
+		PollID:     report.PollID,
+	}, nil
+}
+
+// Reasons of failed deliveries, as reported by the built-in sender
+const (
+	//The user has blocked the bot
+	DeliveryReasonBlocked = "blocked"
+	//The user account is deleted
+	DeliveryReasonDeactivated = "deactivated"
+	//The bot is not allowed to write to the chat for another reason
+	DeliveryReasonForbidden    = "forbidden"
+	DeliveryReasonChatNotFound = "chat_not_found"
+	//Telegram has rejected the message, e.g. because of a malformed text
+	DeliveryReasonBadRequest = "bad_request"
+	//The bot token has been revoked. The built-in sender does not fail deliveries with it:
+	//it flags the bot and returns the deliveries to the queue.
+	DeliveryReasonUnauthorized = "unauthorized"
+	//Telegram has kept throttling the bot after all the retries
+	DeliveryReasonRateLimited = "rate_limited"
+	//Network errors or Telegram server errors after all the retries
+	DeliveryReasonTemporaryError = "temporary_error"
+	//A message to edit or delete is gone, e.g. the user has deleted the chat
+	DeliveryReasonMessageNotFound = "message_not_found"
+	//The worker does not know the kind of a message job, e.g. one added by a newer barker
+	DeliveryReasonUnknownOperation = "unknown_operation"
+	//The campaign of a test send is gone
+	DeliveryReasonCampaignNotFound = "campaign_not_found"
+)
@@ -0,0 +1,40 @@
+package types
+
+import "time"
+
+// Directions of inbox messages
+const (
+	//Written by the user to the bot
+	InboxDirectionIncoming = "in"
+	//A reply sent to the user from the inbox
+	InboxDirectionOutgoing = "out"
+)
+
+// InboxMessage is a message of a conversation between a bot and a user
+type InboxMessage struct {
+	ID         int64  `json:"ID,omitempty"`
+	BotID      int64  `json:"BotID,omitempty"`
+	TelegramID int64  `binding:"required" json:"TelegramID,omitempty"`
+	Direction  string `json:"Direction,omitempty"`
+	Text       string `json:"Text,omitempty"`
+	//Telegram message ID, set for an outgoing message once it is sent
+	MessageID int64 `binding:"required" json:"MessageID,omitempty"`
+	//The last campaign delivered to the user before an incoming message
+	CampaignID int64 `json:"CampaignID,omitempty"`
+	//Telegram message ID of the incoming message an automatic reply answers
+	ReplyTo int64 `json:"ReplyTo,omitempty"`
+	//Message job which sends an outgoing message, with its state
+	JobID     int64           `json:"JobID,omitempty"`
+	State     MessageJobState `json:"State,omitempty"`
+	Reason    string          `json:"Reason,omitempty"`
+	CreatedAt time.Time       `json:"CreatedAt,omitempty" ts_type:"string"`
+}
+
+// Conversation is an entry of the inbox of a bot, one per user who has written to it
+type Conversation struct {
+	BotID       int64         `json:"BotID,omitempty"`
+	TelegramID  int64         `json:"TelegramID,omitempty"`
+	DisplayName string        `json:"DisplayName,omitempty"`
+	Messages    int64         `json:"Messages,omitempty"`
+	LastMessage *InboxMessage `json:"LastMessage,omitempty"`
+}
@@ -0,0 +1,12 @@
+package types
+
+// TrackedLink is a URL of a campaign message replaced with a short redirect URL, one per delivery
+type TrackedLink struct {
+	//Short code of the redirect URL /l/:Code
+	Code       string `json:"Code,omitempty"`
+	BotID      int64  `json:"BotID,omitempty"`
+	CampaignID int64  `json:"CampaignID,omitempty"`
+	TelegramID int64  `json:"TelegramID,omitempty"`
+	//The original URL
+	URL string `json:"URL,omitempty"`
+}
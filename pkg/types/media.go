@@ -0,0 +1,16 @@
+package types
+
+import "time"
+
+// MaxMediaSize is the limit of Telegram for uploaded photos
+const MaxMediaSize = 10 << 20
+
+// MediaAsset is a photo uploaded to barker once and sent by campaigns of any bot.
+// Telegram identifies it by a file_id of its own for every bot, which is cached after the first upload.
+type MediaAsset struct {
+	ID          int64     `json:"ID,omitempty"`
+	FileName    string    `json:"FileName,omitempty"`
+	ContentType string    `json:"ContentType,omitempty"`
+	Size        int64     `json:"Size,omitempty"`
+	CreatedAt   time.Time `json:"CreatedAt,omitempty" ts_type:"string"`
+}
@@ -0,0 +1,20 @@
+package types
+
+import "time"
+
+// Sequence is a series of messages sent to a user one by one after they are enrolled,
+// e.g. by an auto-responder rule
+type Sequence struct {
+	ID        int64          `json:"ID,omitempty"`
+	BotID     int64          `json:"BotID,omitempty"`
+	Title     string         `binding:"required" json:"Title,omitempty"`
+	Steps     []SequenceStep `binding:"required,min=1,dive" json:"Steps,omitempty"`
+	CreatedAt time.Time      `json:"CreatedAt,omitempty" ts_type:"string"`
+}
+
+// SequenceStep is a message of a sequence, sent DelayMinutes after the previous step,
+// or after the enrollment for the first step
+type SequenceStep struct {
+	DelayMinutes int    `binding:"min=0" json:"DelayMinutes,omitempty"`
+	Message      string `binding:"required,max=4096" json:"Message,omitempty"`
+}
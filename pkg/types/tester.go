@@ -0,0 +1,10 @@
+package types
+
+import "time"
+
+// Tester is a user of a bot who gets test sends of its campaigns before they are activated
+type Tester struct {
+	BotID      int64     `json:"BotID,omitempty"`
+	TelegramID int64     `json:"TelegramID,omitempty"`
+	CreatedAt  time.Time `json:"CreatedAt,omitempty" ts_type:"string"`
+}
@@ -1,5 +1,17 @@
 package types
 
+// Types of chats which get campaigns, as Telegram reports them
+const (
+	ChatTypePrivate    = "private"
+	ChatTypeGroup      = "group"
+	ChatTypeSupergroup = "supergroup"
+	ChatTypeChannel    = "channel"
+	//Campaigns only: chats of any type
+	ChatTypeAll = "all"
+)
+
+// User is a recipient of campaigns. Besides users in private chats, groups and channels where the bot is a member
+// are recipients too, with the negative chat ID as TelegramID and the title of the chat as DisplayName.
 type User struct {
 	//Telegram first name
 	FirstName string `json:"FirstName,omitempty"`
@@ -12,4 +24,29 @@ type User struct {
 	TelegramID int64 `json:"TelegramID,omitempty"`
 	//ID of bot which this user belongs to
 	BotID int64 `json:"BotID,omitempty"`
+	//The user has blocked the bot, so campaigns skip them
+	Blocked bool `json:"Blocked,omitempty"`
+	//The user has asked to get no campaigns, replies are still sent to them
+	Unsubscribed bool     `json:"Unsubscribed,omitempty"`
+	Tags         []string `json:"Tags,omitempty"`
+	//Payloads of the first and the latest deep link (t.me/bot?start=<payload>) the user has come by
+	FirstSource string `json:"FirstSource,omitempty"`
+	LastSource  string `json:"LastSource,omitempty"`
+	//Type of the chat with the recipient, private if empty
+	ChatType string `json:"ChatType,omitempty"`
+	//IETF language tag of the user's Telegram client, e.g. "en" or "pt-br"
+	LanguageCode string `json:"LanguageCode,omitempty"`
+}
+
+// Which deep-link source of users breakdowns use
+const (
+	SourceTouchFirst = "first"
+	SourceTouchLast  = "last"
+)
+
+// SourceUsers counts users of a bot by deep-link source. Users who have come without one have an empty Source.
+type SourceUsers struct {
+	Source     string `json:"Source,omitempty"`
+	FirstTouch int64  `json:"FirstTouch,omitempty"`
+	LastTouch  int64  `json:"LastTouch,omitempty"`
 }
@@ -0,0 +1,14 @@
+package types
+
+import "time"
+
+type Worker struct {
+	ID      string `binding:"required" json:"ID,omitempty"`
+	Version string `json:"Version,omitempty"`
+	Host    string `json:"Host,omitempty"`
+	//ID of the bot this worker currently holds a lease on
+	BotID int64 `json:"BotID,omitempty"`
+	//Deliveries per second, as reported by the worker
+	Throughput    float64   `json:"Throughput,omitempty"`
+	LastHeartbeat time.Time `json:"LastHeartbeat,omitempty" ts_type:"string"`
+}
@@ -2,10 +2,16 @@ package main
 
 import (
 	"github.com/corporateanon/barker/pkg/client"
+	"github.com/corporateanon/barker/pkg/dao"
 	"github.com/corporateanon/barker/pkg/database"
 	"github.com/corporateanon/barker/pkg/dbclient"
+	"github.com/corporateanon/barker/pkg/ingest"
+	"github.com/corporateanon/barker/pkg/notify"
 	"github.com/corporateanon/barker/pkg/server"
+	"github.com/corporateanon/barker/pkg/telegram"
+	"github.com/corporateanon/barker/pkg/telegram/telegramtest"
 	"github.com/go-resty/resty/v2"
+	"gorm.io/gorm"
 
 	"go.uber.org/fx"
 )
@@ -16,6 +22,17 @@ func createIntegrationTestConfigurationRoundRobin() fx.Option {
 		dbclient.NewCampaignDaoImplGorm,
 		dbclient.NewDeliveryDaoImplGorm,
 		dbclient.NewBotDaoImplGorm,
+		dbclient.NewWorkerDaoImplGorm,
+		dbclient.NewCampaignOperationDaoImplGorm,
+		dbclient.NewClickDaoImplGorm,
+		dbclient.NewLinkDaoImplGorm,
+		dbclient.NewConversionDaoImplGorm,
+		dbclient.NewAnswerDaoImplGorm,
+		dbclient.NewInboxDaoImplGorm,
+		dbclient.NewAutoResponderDaoImplGorm,
+		dbclient.NewMediaDaoImplGorm,
+		dbclient.NewTesterDaoImplGorm,
+		dbclient.NewSequenceDaoImplGorm,
 		database.NewDatabase,
 		database.NewDialectorSQLiteMemoryRoundRobin,
 	)
@@ -27,11 +44,40 @@ func createIntegrationTestConfigurationGorm() fx.Option {
 		dbclient.NewCampaignDaoImplGorm,
 		dbclient.NewDeliveryDaoImplGorm,
 		dbclient.NewBotDaoImplGorm,
+		dbclient.NewWorkerDaoImplGorm,
+		dbclient.NewCampaignOperationDaoImplGorm,
+		dbclient.NewClickDaoImplGorm,
+		dbclient.NewLinkDaoImplGorm,
+		dbclient.NewConversionDaoImplGorm,
+		dbclient.NewAnswerDaoImplGorm,
+		dbclient.NewInboxDaoImplGorm,
+		dbclient.NewAutoResponderDaoImplGorm,
+		dbclient.NewMediaDaoImplGorm,
+		dbclient.NewTesterDaoImplGorm,
+		dbclient.NewSequenceDaoImplGorm,
 		database.NewDatabase,
 		database.NewDialectorSQLiteMemoryClient,
 	)
 }
 
+func createIntegrationTestConfigurationGormJoinSelection() fx.Option {
+	return fx.Provide(
+		dbclient.NewUserDaoImplGorm,
+		dbclient.NewCampaignDaoImplGorm,
+		newDeliveryDaoImplGormJoinSelection,
+		dbclient.NewBotDaoImplGorm,
+		dbclient.NewConversionDaoImplGorm,
+		dbclient.NewAnswerDaoImplGorm,
+		dbclient.NewInboxDaoImplGorm,
+		dbclient.NewAutoResponderDaoImplGorm,
+		dbclient.NewMediaDaoImplGorm,
+		dbclient.NewTesterDaoImplGorm,
+		dbclient.NewSequenceDaoImplGorm,
+		database.NewDatabase,
+		database.NewDialectorSQLiteMemoryJoinSelection,
+	)
+}
+
 func createIntegrationTestConfigurationServer() fx.Option {
 	return fx.Provide(
 		server.NewHandler,
@@ -39,8 +85,147 @@ func createIntegrationTestConfigurationServer() fx.Option {
 		dbclient.NewCampaignDaoImplGorm,
 		dbclient.NewDeliveryDaoImplGorm,
 		dbclient.NewBotDaoImplGorm,
+		dbclient.NewWorkerDaoImplGorm,
+		dbclient.NewCampaignOperationDaoImplGorm,
+		dbclient.NewClickDaoImplGorm,
+		dbclient.NewLinkDaoImplGorm,
+		dbclient.NewConversionDaoImplGorm,
+		dbclient.NewAnswerDaoImplGorm,
+		dbclient.NewInboxDaoImplGorm,
+		dbclient.NewAutoResponderDaoImplGorm,
+		dbclient.NewMediaDaoImplGorm,
+		dbclient.NewTesterDaoImplGorm,
+		dbclient.NewSequenceDaoImplGorm,
 		database.NewDatabase,
 		database.NewDialectorSQLiteMemoryServer,
+		notify.NewNotifier,
+		ingest.NewIngester,
+		newPermissiveFakeTelegram,
+		newFakeTelegramClient,
+	)
+}
+
+func createIntegrationTestConfigurationWorkers() fx.Option {
+	return fx.Provide(
+		dbclient.NewUserDaoImplGorm,
+		dbclient.NewCampaignDaoImplGorm,
+		dbclient.NewDeliveryDaoImplGorm,
+		dbclient.NewBotDaoImplGorm,
+		dbclient.NewWorkerDaoImplGorm,
+		dbclient.NewCampaignOperationDaoImplGorm,
+		dbclient.NewClickDaoImplGorm,
+		dbclient.NewLinkDaoImplGorm,
+		dbclient.NewConversionDaoImplGorm,
+		dbclient.NewAnswerDaoImplGorm,
+		dbclient.NewInboxDaoImplGorm,
+		dbclient.NewAutoResponderDaoImplGorm,
+		dbclient.NewMediaDaoImplGorm,
+		dbclient.NewTesterDaoImplGorm,
+		dbclient.NewSequenceDaoImplGorm,
+		database.NewDatabase,
+		database.NewDialectorSQLiteMemoryWorkers,
+	)
+}
+
+func createIntegrationTestConfigurationLongPolling() fx.Option {
+	return fx.Provide(
+		server.NewHandler,
+		dbclient.NewUserDaoImplGorm,
+		dbclient.NewCampaignDaoImplGorm,
+		dbclient.NewDeliveryDaoImplGorm,
+		dbclient.NewBotDaoImplGorm,
+		dbclient.NewWorkerDaoImplGorm,
+		dbclient.NewCampaignOperationDaoImplGorm,
+		dbclient.NewClickDaoImplGorm,
+		dbclient.NewLinkDaoImplGorm,
+		dbclient.NewConversionDaoImplGorm,
+		dbclient.NewAnswerDaoImplGorm,
+		dbclient.NewInboxDaoImplGorm,
+		dbclient.NewAutoResponderDaoImplGorm,
+		dbclient.NewMediaDaoImplGorm,
+		dbclient.NewTesterDaoImplGorm,
+		dbclient.NewSequenceDaoImplGorm,
+		database.NewDatabase,
+		database.NewDialectorSQLiteMemoryLongPolling,
+		notify.NewNotifier,
+		ingest.NewIngester,
+		newPermissiveFakeTelegram,
+		newFakeTelegramClient,
+	)
+}
+
+func createIntegrationTestConfigurationSender() fx.Option {
+	return fx.Provide(
+		server.NewHandler,
+		dbclient.NewUserDaoImplGorm,
+		dbclient.NewCampaignDaoImplGorm,
+		dbclient.NewDeliveryDaoImplGorm,
+		dbclient.NewBotDaoImplGorm,
+		dbclient.NewWorkerDaoImplGorm,
+		dbclient.NewCampaignOperationDaoImplGorm,
+		dbclient.NewClickDaoImplGorm,
+		dbclient.NewLinkDaoImplGorm,
+		dbclient.NewConversionDaoImplGorm,
+		dbclient.NewAnswerDaoImplGorm,
+		dbclient.NewInboxDaoImplGorm,
+		dbclient.NewAutoResponderDaoImplGorm,
+		dbclient.NewMediaDaoImplGorm,
+		dbclient.NewTesterDaoImplGorm,
+		dbclient.NewSequenceDaoImplGorm,
+		database.NewDatabase,
+		database.NewDialectorSQLiteMemorySender,
+		notify.NewNotifier,
+		ingest.NewIngester,
+		telegramtest.NewServer,
+		newFakeTelegramClient,
+	)
+}
+
+func createIntegrationTestConfigurationWebhook() fx.Option {
+	return fx.Provide(
+		server.NewHandler,
+		dbclient.NewUserDaoImplGorm,
+		dbclient.NewCampaignDaoImplGorm,
+		dbclient.NewDeliveryDaoImplGorm,
+		dbclient.NewBotDaoImplGorm,
+		dbclient.NewWorkerDaoImplGorm,
+		dbclient.NewCampaignOperationDaoImplGorm,
+		dbclient.NewClickDaoImplGorm,
+		dbclient.NewLinkDaoImplGorm,
+		dbclient.NewConversionDaoImplGorm,
+		dbclient.NewAnswerDaoImplGorm,
+		dbclient.NewInboxDaoImplGorm,
+		dbclient.NewAutoResponderDaoImplGorm,
+		dbclient.NewMediaDaoImplGorm,
+		dbclient.NewTesterDaoImplGorm,
+		dbclient.NewSequenceDaoImplGorm,
+		database.NewDatabase,
+		database.NewDialectorSQLiteMemoryWebhook,
+		notify.NewNotifier,
+		ingest.NewIngester,
+		newPermissiveFakeTelegram,
+		newFakeTelegramClient,
+	)
+}
+
+func createIntegrationTestConfigurationPolling() fx.Option {
+	return fx.Provide(
+		dbclient.NewUserDaoImplGorm,
+		dbclient.NewBotDaoImplGorm,
+		dbclient.NewClickDaoImplGorm,
+		dbclient.NewCampaignDaoImplGorm,
+		dbclient.NewAnswerDaoImplGorm,
+		dbclient.NewInboxDaoImplGorm,
+		dbclient.NewAutoResponderDaoImplGorm,
+		dbclient.NewMediaDaoImplGorm,
+		dbclient.NewTesterDaoImplGorm,
+		dbclient.NewSequenceDaoImplGorm,
+		database.NewDatabase,
+		database.NewDialectorSQLiteMemoryPolling,
+		notify.NewNotifier,
+		ingest.NewIngester,
+		telegramtest.NewServer,
+		newFakeTelegramClient,
 	)
 }
 
@@ -51,9 +236,37 @@ func createIntegrationTestConfigurationClient() fx.Option {
 		client.NewUserDaoImplResty,
 		client.NewCampaignDaoImplResty,
 		client.NewDeliveryDaoImplResty,
+		client.NewWorkerDaoImplResty,
+		client.NewCampaignOperationDaoImplResty,
+		client.NewClickDaoImplResty,
+		client.NewLinkDaoImplResty,
+		client.NewConversionDaoImplResty,
+		client.NewAnswerDaoImplResty,
+		client.NewInboxDaoImplResty,
+		client.NewAutoResponderDaoImplResty,
+		client.NewMediaDaoImplResty,
+		client.NewTesterDaoImplResty,
+		client.NewSequenceDaoImplResty,
 	)
 }
 
 func newLocalClient() *resty.Client {
 	return resty.New().SetHostURL("http://127.0.0.1:3000")
 }
+
+// newPermissiveFakeTelegram lets the server accept bots with any token
+func newPermissiveFakeTelegram() *telegramtest.Server {
+	return telegramtest.NewServer().AcceptAnyToken()
+}
+
+func newFakeTelegramClient(fake *telegramtest.Server) *telegram.Client {
+	return telegram.NewClient(fake.URL)
+}
+
+func newDeliveryDaoImplGormJoinSelection(
+	db *gorm.DB,
+	campaignDao dao.CampaignDao,
+	userDao dao.UserDao,
+) dao.DeliveryDao {
+	return dbclient.NewDeliveryDaoImplGormWithSelection(db, campaignDao, userDao, dbclient.RecipientSelectionJoin)
+}
@@ -2,15 +2,31 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/corporateanon/barker/pkg/client"
 	"github.com/corporateanon/barker/pkg/dao"
+	"github.com/corporateanon/barker/pkg/database"
+	"github.com/corporateanon/barker/pkg/ingest"
+	"github.com/corporateanon/barker/pkg/notify"
+	"github.com/corporateanon/barker/pkg/sender"
+	"github.com/corporateanon/barker/pkg/telegram"
+	"github.com/corporateanon/barker/pkg/telegram/telegramtest"
 	"github.com/corporateanon/barker/pkg/types"
 	"github.com/gin-gonic/gin"
+	"github.com/go-resty/resty/v2"
 	"go.uber.org/fx"
+	"gorm.io/gorm"
 	"gotest.tools/assert"
 )
 
@@ -40,6 +56,7 @@ func createIntegrationTestInvocation(t *testing.T) fx.Option {
 			userDao dao.UserDao,
 			campaignDao dao.CampaignDao,
 			deliveryDao dao.DeliveryDao,
+			conversionDao dao.ConversionDao,
 		) {
 
 			// #region(collapsed) [create bots]
@@ -56,13 +73,13 @@ func createIntegrationTestInvocation(t *testing.T) fx.Option {
 				})
 				assert.NilError(t, err)
 
-				assert.DeepEqual(t, bot1Created, &types.Bot{
+				assert.DeepEqual(t, withoutIdentity(bot1Created), &types.Bot{
 					ID:    1,
 					Title: "hello_bot",
 					Token: "hello",
 				})
 
-				assert.DeepEqual(t, bot2Created, &types.Bot{
+				assert.DeepEqual(t, withoutIdentity(bot2Created), &types.Bot{
 					ID:    2,
 					Title: "world_bot",
 					Token: "world",
@@ -71,7 +88,7 @@ func createIntegrationTestInvocation(t *testing.T) fx.Option {
 				bot1, err := botDao.Get(1)
 				assert.NilError(t, err)
 
-				assert.DeepEqual(t, bot1, &types.Bot{
+				assert.DeepEqual(t, withoutIdentity(bot1), &types.Bot{
 					ID:    1,
 					Title: "hello_bot",
 					Token: "hello",
@@ -80,7 +97,7 @@ func createIntegrationTestInvocation(t *testing.T) fx.Option {
 				bot2, err := botDao.Get(2)
 				assert.NilError(t, err)
 
-				assert.DeepEqual(t, bot2, &types.Bot{
+				assert.DeepEqual(t, withoutIdentity(bot2), &types.Bot{
 					ID:    2,
 					Title: "world_bot",
 					Token: "world",
@@ -90,7 +107,7 @@ func createIntegrationTestInvocation(t *testing.T) fx.Option {
 					bot1, err := botDao.GetByToken("hello")
 					assert.NilError(t, err)
 
-					assert.DeepEqual(t, bot1, &types.Bot{
+					assert.DeepEqual(t, withoutIdentity(bot1), &types.Bot{
 						ID:    1,
 						Title: "hello_bot",
 						Token: "hello",
@@ -99,7 +116,7 @@ func createIntegrationTestInvocation(t *testing.T) fx.Option {
 					bot2, err := botDao.GetByToken("world")
 					assert.NilError(t, err)
 
-					assert.DeepEqual(t, bot2, &types.Bot{
+					assert.DeepEqual(t, withoutIdentity(bot2), &types.Bot{
 						ID:    2,
 						Title: "world_bot",
 						Token: "world",
@@ -150,12 +167,14 @@ func createIntegrationTestInvocation(t *testing.T) fx.Option {
 				assert.NilError(t, err)
 
 				assert.DeepEqual(t, user1, &types.User{
+					ChatType:   types.ChatTypePrivate,
 					FirstName:  "User",
 					LastName:   "One",
 					TelegramID: 100,
 					BotID:      1,
 				})
 				assert.DeepEqual(t, user2, &types.User{
+					ChatType:   types.ChatTypePrivate,
 					FirstName:  "User",
 					LastName:   "Two",
 					TelegramID: 200,
@@ -186,12 +205,14 @@ func createIntegrationTestInvocation(t *testing.T) fx.Option {
 				user2, err = userDao.Get(2, 200)
 				assert.NilError(t, err)
 				assert.DeepEqual(t, user1, &types.User{
+					ChatType:   types.ChatTypePrivate,
 					FirstName:  "User",
 					LastName:   "Um",
 					TelegramID: 100,
 					BotID:      1,
 				})
 				assert.DeepEqual(t, user2, &types.User{
+					ChatType:   types.ChatTypePrivate,
 					FirstName:  "User",
 					LastName:   "Dois",
 					TelegramID: 200,
@@ -210,19 +231,23 @@ func createIntegrationTestInvocation(t *testing.T) fx.Option {
 				})
 				assert.NilError(t, err)
 				assert.DeepEqual(t, campaign1Created, &types.Campaign{
-					ID:      1,
-					BotID:   1,
-					Active:  true,
-					Title:   "hello world",
-					Message: "hello, user",
+					ID:       1,
+					BotID:    1,
+					Active:   true,
+					State:    types.CampaignStateRunning,
+					Revision: 1,
+					Title:    "hello world",
+					Message:  "hello, user",
 				})
 				campaign1, err := campaignDao.Get(1, 1)
 				assert.DeepEqual(t, campaign1, &types.Campaign{
-					ID:      1,
-					BotID:   1,
-					Active:  true,
-					Title:   "hello world",
-					Message: "hello, user",
+					ID:       1,
+					BotID:    1,
+					Active:   true,
+					State:    types.CampaignStateRunning,
+					Revision: 1,
+					Title:    "hello world",
+					Message:  "hello, user",
 				})
 
 				campaign2Created, err := campaignDao.Create(&types.Campaign{
@@ -233,19 +258,23 @@ func createIntegrationTestInvocation(t *testing.T) fx.Option {
 				})
 				assert.NilError(t, err)
 				assert.DeepEqual(t, campaign2Created, &types.Campaign{
-					ID:      2,
-					BotID:   1,
-					Active:  true,
-					Title:   "foo",
-					Message: "bar",
+					ID:       2,
+					BotID:    1,
+					Active:   true,
+					State:    types.CampaignStateRunning,
+					Revision: 1,
+					Title:    "foo",
+					Message:  "bar",
 				})
 				campaign2, err := campaignDao.Get(1, 2)
 				assert.DeepEqual(t, campaign2, &types.Campaign{
-					ID:      2,
-					BotID:   1,
-					Active:  true,
-					Title:   "foo",
-					Message: "bar",
+					ID:       2,
+					BotID:    1,
+					Active:   true,
+					State:    types.CampaignStateRunning,
+					Revision: 1,
+					Title:    "foo",
+					Message:  "bar",
 				})
 			})
 			// #endregion
@@ -271,18 +300,22 @@ func createIntegrationTestInvocation(t *testing.T) fx.Option {
 				assert.NilError(t, errorWrongBotID)
 
 				assert.DeepEqual(t, campaign1Updated, &types.Campaign{
-					ID:      1,
-					BotID:   1,
-					Active:  false,
-					Message: "hello",
-					Title:   "world",
+					ID:       1,
+					BotID:    1,
+					Active:   false,
+					State:    types.CampaignStatePaused,
+					Revision: 2,
+					Message:  "hello",
+					Title:    "world",
 				})
 				assert.DeepEqual(t, campaign2Updated, &types.Campaign{
-					ID:      2,
-					BotID:   1,
-					Active:  false,
-					Message: "qwerty",
-					Title:   "uiop",
+					ID:       2,
+					BotID:    1,
+					Active:   false,
+					State:    types.CampaignStatePaused,
+					Revision: 2,
+					Message:  "qwerty",
+					Title:    "uiop",
 				})
 
 				_, errorWrongBotID = campaignDao.Update(&types.Campaign{
@@ -300,18 +333,22 @@ func createIntegrationTestInvocation(t *testing.T) fx.Option {
 				assert.NilError(t, err)
 
 				assert.DeepEqual(t, campaign1, &types.Campaign{
-					ID:      1,
-					BotID:   1,
-					Active:  false,
-					Message: "hello",
-					Title:   "world",
+					ID:       1,
+					BotID:    1,
+					Active:   false,
+					State:    types.CampaignStatePaused,
+					Revision: 2,
+					Message:  "hello",
+					Title:    "world",
 				})
 				assert.DeepEqual(t, campaign2, &types.Campaign{
-					ID:      2,
-					BotID:   1,
-					Active:  false,
-					Message: "qwerty",
-					Title:   "uiop",
+					ID:       2,
+					BotID:    1,
+					Active:   false,
+					State:    types.CampaignStatePaused,
+					Revision: 2,
+					Message:  "qwerty",
+					Title:    "uiop",
 				})
 			})
 			// #endregion
@@ -383,6 +420,7 @@ func createIntegrationTestInvocation(t *testing.T) fx.Option {
 					CampaignID: campaignA.ID,
 					State:      types.DeliveryStateProgress,
 					TelegramID: userA1.TelegramID,
+					Revision:   1,
 				})
 				assert.DeepEqual(t, resultA1.User, userA1)
 
@@ -393,6 +431,7 @@ func createIntegrationTestInvocation(t *testing.T) fx.Option {
 					CampaignID: campaignA.ID,
 					State:      types.DeliveryStateProgress,
 					TelegramID: userA2.TelegramID,
+					Revision:   1,
 				})
 				assert.DeepEqual(t, resultA2.User, userA2)
 
@@ -409,6 +448,7 @@ func createIntegrationTestInvocation(t *testing.T) fx.Option {
 					CampaignID: campaignB.ID,
 					State:      types.DeliveryStateProgress,
 					TelegramID: userB1.TelegramID,
+					Revision:   1,
 				})
 				assert.DeepEqual(t, resultB1.User, userB1)
 
@@ -419,6 +459,7 @@ func createIntegrationTestInvocation(t *testing.T) fx.Option {
 					CampaignID: campaignB.ID,
 					State:      types.DeliveryStateProgress,
 					TelegramID: userB2.TelegramID,
+					Revision:   1,
 				})
 				assert.DeepEqual(t, resultB2.User, userB2)
 
@@ -634,12 +675,319 @@ func createIntegrationTestInvocation(t *testing.T) fx.Option {
 			})
 			// #endregion
 
+			// #region(collapsed) [take deliveries in batches]
+			t.Run("take deliveries in batches", func(t *testing.T) {
+				bot, err := botDao.Create(&types.Bot{
+					Title: "Bot Batch",
+					Token: "bot:batch",
+				})
+				assert.NilError(t, err)
+
+				for i := 0; i < 5; i++ {
+					_, err := userDao.Put(&types.User{
+						DisplayName: fmt.Sprintf("Batch user %d", i),
+						TelegramID:  int64(5000 + i),
+						BotID:       bot.ID,
+					})
+					assert.NilError(t, err)
+				}
+				campaignIDs := map[int64]bool{}
+				for i := 0; i < 2; i++ {
+					campaign, err := campaignDao.Create(&types.Campaign{
+						BotID:   bot.ID,
+						Active:  true,
+						Title:   fmt.Sprintf("Batch campaign %d", i),
+						Message: fmt.Sprintf("Batch message %d", i),
+					})
+					assert.NilError(t, err)
+					campaignIDs[campaign.ID] = true
+				}
+
+				firstBatch, err := deliveryDao.TakeBatch("batch-worker", bot.ID, 4)
+				assert.NilError(t, err)
+				assert.Assert(t, len(firstBatch) == 4)
+
+				secondBatch, err := deliveryDao.TakeBatch("batch-worker", bot.ID, 10)
+				assert.NilError(t, err)
+				assert.Assert(t, len(secondBatch) == 6)
+
+				emptyBatch, err := deliveryDao.TakeBatch("batch-worker", bot.ID, 10)
+				assert.NilError(t, err)
+				assert.Assert(t, len(emptyBatch) == 0)
+
+				taken := map[types.Delivery]bool{}
+				reports := []types.Delivery{}
+				for i, result := range append(firstBatch, secondBatch...) {
+					assert.Assert(t, result.Delivery.BotID == bot.ID)
+					assert.Assert(t, result.Delivery.WorkerID == "batch-worker")
+					assert.Assert(t, result.Delivery.State == types.DeliveryStateProgress)
+					assert.Assert(t, result.User.TelegramID == result.Delivery.TelegramID)
+					assert.Assert(t, result.Campaign.ID == result.Delivery.CampaignID)
+					assert.Assert(t, campaignIDs[result.Campaign.ID])
+					assert.Assert(t, !taken[*result.Delivery])
+					taken[*result.Delivery] = true
+
+					report := *result.Delivery
+					report.State = types.DeliveryStateSuccess
+					if i%2 == 1 {
+						report.State = types.DeliveryStateFail
+						report.Reason = "blocked"
+					}
+					reports = append(reports, report)
+				}
+
+				err = deliveryDao.SetStates(reports)
+				assert.NilError(t, err)
+
+				for _, report := range reports {
+					state, err := deliveryDao.GetState(&report)
+					assert.NilError(t, err)
+					assert.Assert(t, state == report.State)
+				}
+
+				err = deliveryDao.SetStates([]types.Delivery{{
+					BotID:      bot.ID,
+					CampaignID: reports[0].CampaignID,
+					TelegramID: reports[0].TelegramID,
+					State:      42,
+				}})
+				assert.Assert(t, err != nil)
+			})
+			// #endregion
+
+			// #region(collapsed) [recipients of activated campaigns]
+			t.Run("recipients of activated campaigns", func(t *testing.T) {
+				bot, err := botDao.Create(&types.Bot{
+					Title: "Bot Queue",
+					Token: "bot:queue",
+				})
+				assert.NilError(t, err)
+
+				earlyUser, err := userDao.Put(&types.User{
+					DisplayName: "Early user",
+					TelegramID:  6001,
+					BotID:       bot.ID,
+				})
+				assert.NilError(t, err)
+
+				campaign, err := campaignDao.Create(&types.Campaign{
+					BotID:   bot.ID,
+					Active:  true,
+					Title:   "Queue campaign",
+					Message: "Queue message",
+				})
+				assert.NilError(t, err)
+
+				lateUser, err := userDao.Put(&types.User{
+					DisplayName: "Late user",
+					TelegramID:  6002,
+					BotID:       bot.ID,
+				})
+				assert.NilError(t, err)
+
+				result, err := deliveryDao.Take(bot.ID, 0, 0)
+				assert.NilError(t, err)
+				assert.DeepEqual(t, result.User, earlyUser)
+				result, err = deliveryDao.Take(bot.ID, 0, 0)
+				assert.NilError(t, err)
+				assert.DeepEqual(t, result.User, lateUser)
+
+				campaign.Active = false
+				_, err = campaignDao.Update(campaign)
+				assert.NilError(t, err)
+
+				pausedUser, err := userDao.Put(&types.User{
+					DisplayName: "User registered during a pause",
+					TelegramID:  6003,
+					BotID:       bot.ID,
+				})
+				assert.NilError(t, err)
+
+				result, err = deliveryDao.Take(bot.ID, 0, 0)
+				assert.NilError(t, err)
+				assert.Assert(t, result == nil)
+
+				campaign.Active = true
+				_, err = campaignDao.Update(campaign)
+				assert.NilError(t, err)
+
+				result, err = deliveryDao.Take(bot.ID, 0, 0)
+				assert.NilError(t, err)
+				assert.DeepEqual(t, result.User, pausedUser)
+				result, err = deliveryDao.Take(bot.ID, 0, 0)
+				assert.NilError(t, err)
+				assert.Assert(t, result == nil)
+			})
+			// #endregion
+
+			// #region(collapsed) [blocked users]
+			t.Run("blocked users", func(t *testing.T) {
+				bot, err := botDao.Create(&types.Bot{
+					Title: "Bot Blocked",
+					Token: "bot:blocked",
+				})
+				assert.NilError(t, err)
+				for telegramID := int64(7001); telegramID <= 7002; telegramID++ {
+					_, err := userDao.Put(&types.User{
+						DisplayName: "User who may block the bot",
+						TelegramID:  telegramID,
+						BotID:       bot.ID,
+					})
+					assert.NilError(t, err)
+				}
+
+				user, err := userDao.SetBlocked(bot.ID, 7001, true)
+				assert.NilError(t, err)
+				assert.Assert(t, user.Blocked)
+
+				//Put does not unblock a user
+				user, err = userDao.Put(&types.User{
+					DisplayName: "User who has blocked the bot",
+					TelegramID:  7001,
+					BotID:       bot.ID,
+				})
+				assert.NilError(t, err)
+				user, err = userDao.Get(bot.ID, 7001)
+				assert.NilError(t, err)
+				assert.Assert(t, user.Blocked)
+				assert.Equal(t, user.DisplayName, "User who has blocked the bot")
+
+				campaign, err := campaignDao.Create(&types.Campaign{
+					BotID:   bot.ID,
+					Active:  true,
+					Title:   "Campaign for unblocked users",
+					Message: "Message for unblocked users",
+				})
+				assert.NilError(t, err)
+
+				_, err = userDao.SetBlocked(bot.ID, 7002, true)
+				assert.NilError(t, err)
+				result, err := deliveryDao.Take(bot.ID, campaign.ID, 0)
+				assert.NilError(t, err)
+				assert.Assert(t, result == nil)
+
+				user, err = userDao.SetBlocked(bot.ID, 7002, false)
+				assert.NilError(t, err)
+				assert.Assert(t, !user.Blocked)
+				result, err = deliveryDao.Take(bot.ID, campaign.ID, 0)
+				assert.NilError(t, err)
+				assert.Equal(t, result.User.TelegramID, int64(7002))
+				result, err = deliveryDao.Take(bot.ID, campaign.ID, 0)
+				assert.NilError(t, err)
+				assert.Assert(t, result == nil)
+			})
+			// #endregion
+
+			// #region(collapsed) [conversions]
+			t.Run("conversions", func(t *testing.T) {
+				bot, err := botDao.Create(&types.Bot{
+					Title:                 "Bot Conversions",
+					Token:                 "bot:conversions",
+					ConversionWindowHours: 24,
+				})
+				assert.NilError(t, err)
+				assert.Equal(t, bot.ConversionWindowHours, 24)
+				for telegramID := int64(8001); telegramID <= 8002; telegramID++ {
+					_, err := userDao.Put(&types.User{
+						DisplayName: "Buyer",
+						TelegramID:  telegramID,
+						BotID:       bot.ID,
+					})
+					assert.NilError(t, err)
+				}
+
+				deliver := func(campaign *types.Campaign, telegramID int64, state types.DeliveryState) {
+					result, err := deliveryDao.Take(bot.ID, campaign.ID, telegramID)
+					assert.NilError(t, err)
+					assert.NilError(t, deliveryDao.SetState(result.Delivery, state))
+				}
+				older, err := campaignDao.Create(&types.Campaign{
+					BotID:   bot.ID,
+					Active:  true,
+					Title:   "Older sale",
+					Message: "Older sale",
+				})
+				assert.NilError(t, err)
+				deliver(older, 8001, types.DeliveryStateSuccess)
+				deliver(older, 8002, types.DeliveryStateSuccess)
+				newer, err := campaignDao.Create(&types.Campaign{
+					BotID:   bot.ID,
+					Active:  true,
+					Title:   "Newer sale",
+					Message: "Newer sale",
+				})
+				assert.NilError(t, err)
+				deliver(newer, 8001, types.DeliveryStateSuccess)
+				//A failed delivery is not a touch point
+				deliver(newer, 8002, types.DeliveryStateFail)
+
+				conversion, err := conversionDao.Create(&types.Conversion{
+					BotID:      bot.ID,
+					TelegramID: 8001,
+					Event:      "purchase",
+					Value:      10.5,
+				})
+				assert.NilError(t, err)
+				assert.Equal(t, conversion.CampaignID, newer.ID)
+				assert.Equal(t, conversion.Event, "purchase")
+				_, err = conversionDao.Create(&types.Conversion{
+					BotID:      bot.ID,
+					TelegramID: 8001,
+					Event:      "purchase",
+					Value:      4.5,
+				})
+				assert.NilError(t, err)
+
+				conversion, err = conversionDao.Create(&types.Conversion{
+					BotID:      bot.ID,
+					TelegramID: 8002,
+					Event:      "signup",
+				})
+				assert.NilError(t, err)
+				assert.Equal(t, conversion.CampaignID, older.ID)
+
+				//A repeated report does not make an older delivery the last touch point
+				assert.NilError(t, deliveryDao.SetState(&types.Delivery{
+					BotID:      bot.ID,
+					CampaignID: older.ID,
+					TelegramID: 8001,
+				}, types.DeliveryStateSuccess))
+				conversion, err = conversionDao.Create(&types.Conversion{
+					BotID:      bot.ID,
+					TelegramID: 8001,
+					Event:      "signup",
+				})
+				assert.NilError(t, err)
+				assert.Equal(t, conversion.CampaignID, newer.ID)
+
+				//No campaign has been delivered to this user
+				conversion, err = conversionDao.Create(&types.Conversion{
+					BotID:      bot.ID,
+					TelegramID: 8003,
+					Event:      "purchase",
+					Value:      100,
+				})
+				assert.NilError(t, err)
+				assert.Equal(t, conversion.CampaignID, int64(0))
+
+				stat, err := campaignDao.GetAggregatedStatistics(bot.ID, newer.ID)
+				assert.NilError(t, err)
+				assert.Equal(t, stat.Conversions, int64(3))
+				assert.Equal(t, stat.Revenue, 15.0)
+				stat, err = campaignDao.GetAggregatedStatistics(bot.ID, older.ID)
+				assert.NilError(t, err)
+				assert.Equal(t, stat.Conversions, int64(1))
+				assert.Equal(t, stat.Revenue, 0.0)
+			})
+			// #endregion
+
 		},
 	)
 }
 
 func createIntegrationTestRoundRobinInvocation(t *testing.T) fx.Option {
 	return fx.Invoke(func(
+		db *gorm.DB,
 		botDao dao.BotDao,
 		deliveryDao dao.DeliveryDao,
 		userDao dao.UserDao,
@@ -697,5 +1045,2769 @@ func createIntegrationTestRoundRobinInvocation(t *testing.T) fx.Option {
 			//Make sure the bot with a failed delivery is not taken again
 			assert.Assert(t, nextCycleFirstBot.ID != 1)
 		})
+
+		t.Run("Take next jobs for any bot, least recently served bots first", func(t *testing.T) {
+			//Bot 1 has one pending delivery left, bots 2...10 have two
+			firstRound := map[int64]bool{}
+			for i := 0; i < 10; i++ {
+				job, err := deliveryDao.NextJob("")
+				assert.NilError(t, err)
+				assert.Assert(t, job.Bot.Token != "")
+				assert.Assert(t, job.Delivery.BotID == job.Bot.ID)
+				assert.Assert(t, job.User.BotID == job.Bot.ID)
+				assert.Assert(t, job.Campaign.BotID == job.Bot.ID)
+				assert.Assert(t, !firstRound[job.Bot.ID])
+				firstRound[job.Bot.ID] = true
+			}
+
+			secondRound := map[int64]bool{}
+			for i := 0; i < 9; i++ {
+				job, err := deliveryDao.NextJob("")
+				assert.NilError(t, err)
+				assert.Assert(t, job.Bot.ID != 1)
+				assert.Assert(t, !secondRound[job.Bot.ID])
+				secondRound[job.Bot.ID] = true
+			}
+
+			job, err := deliveryDao.NextJob("")
+			assert.NilError(t, err)
+			assert.Assert(t, job == nil)
+		})
+
+		t.Run("Skip bots whose queue holds only stale recipients", func(t *testing.T) {
+			//Bot 1 is served least recently, but its only queued recipient does not exist
+			assert.NilError(t, db.Create(&database.QueuedRecipient{CampaignID: 2, BotID: 1, TelegramID: 9999}).Error)
+			assert.NilError(t, db.Table("bots").Where("id = ?", 1).Update("rr_access_time", time.Time{}).Error)
+			_, err := userDao.Put(&types.User{DisplayName: "Late user", TelegramID: 2000, BotID: 2})
+			assert.NilError(t, err)
+
+			job, err := deliveryDao.NextJob("")
+			assert.NilError(t, err)
+			assert.Assert(t, job != nil)
+			assert.Equal(t, job.Bot.ID, int64(2))
+			assert.Equal(t, job.User.TelegramID, int64(2000))
+		})
+
+		t.Run("Take a bot marked as possibly empty again once it gets new recipients", func(t *testing.T) {
+			rrTakeIDs := func() map[int64]bool {
+				ids := map[int64]bool{}
+				for i := 0; i < 10; i++ {
+					bot, err := botDao.RRTake()
+					assert.NilError(t, err)
+					ids[bot.ID] = true
+				}
+				return ids
+			}
+
+			//An empty take marks bot 1 as possibly empty
+			results, err := deliveryDao.TakeBatch("", 1, 10)
+			assert.NilError(t, err)
+			assert.Equal(t, len(results), 0)
+			assert.Assert(t, !rrTakeIDs()[1])
+
+			_, err = userDao.Put(&types.User{DisplayName: "New user", TelegramID: 3000, BotID: 1})
+			assert.NilError(t, err)
+			assert.Assert(t, rrTakeIDs()[1])
+		})
+	})
+}
+
+func createIntegrationTestWorkersInvocation(t *testing.T) fx.Option {
+	return fx.Invoke(func(
+		botDao dao.BotDao,
+		deliveryDao dao.DeliveryDao,
+		userDao dao.UserDao,
+		campaignDao dao.CampaignDao,
+		workerDao dao.WorkerDao) {
+		bot, err := botDao.Create(&types.Bot{
+			Title: "Worker bot",
+			Token: "worker:bot",
+		})
+		assert.NilError(t, err)
+		for i := 0; i < 3; i++ {
+			_, err := userDao.Put(&types.User{
+				DisplayName: fmt.Sprintf("User %d", i),
+				TelegramID:  int64(100 + i),
+				BotID:       bot.ID,
+			})
+			assert.NilError(t, err)
+		}
+		_, err = campaignDao.Create(&types.Campaign{
+			BotID:   bot.ID,
+			Title:   "Campaign",
+			Message: "Message",
+			Active:  true,
+		})
+		assert.NilError(t, err)
+
+		t.Run("register and heartbeat", func(t *testing.T) {
+			_, err := workerDao.Heartbeat(&types.Worker{ID: "w1"})
+			assert.Assert(t, err != nil)
+
+			worker, err := workerDao.Register(&types.Worker{
+				ID:      "w1",
+				Version: "1.0.0",
+				Host:    "host-1",
+			})
+			assert.NilError(t, err)
+			assert.Assert(t, worker.ID == "w1")
+			assert.Assert(t, !worker.LastHeartbeat.IsZero())
+
+			worker, err = workerDao.Heartbeat(&types.Worker{
+				ID:         "w1",
+				Version:    "1.0.0",
+				Host:       "host-1",
+				BotID:      bot.ID,
+				Throughput: 12.5,
+			})
+			assert.NilError(t, err)
+
+			worker, err = workerDao.Get("w1")
+			assert.NilError(t, err)
+			assert.Assert(t, worker.BotID == bot.ID)
+			assert.Assert(t, worker.Throughput == 12.5)
+			assert.Assert(t, worker.Host == "host-1")
+
+			workers, err := workerDao.List()
+			assert.NilError(t, err)
+			assert.Assert(t, len(workers) == 1)
+			assert.Assert(t, workers[0].ID == "w1")
+		})
+
+		t.Run("release deliveries of an expired worker", func(t *testing.T) {
+			leased, err := deliveryDao.TakeForWorker("w1", bot.ID, 0, 0)
+			assert.NilError(t, err)
+			assert.Assert(t, leased.Delivery.WorkerID == "w1")
+
+			anonymous, err := deliveryDao.Take(bot.ID, 0, 0)
+			assert.NilError(t, err)
+			assert.Assert(t, anonymous.Delivery.WorkerID == "")
+
+			released, err := workerDao.ReleaseExpired(time.Hour)
+			assert.NilError(t, err)
+			assert.Assert(t, len(released) == 0)
+
+			released, err = workerDao.ReleaseExpired(0)
+			assert.NilError(t, err)
+			assert.Assert(t, len(released) == 1)
+			assert.Assert(t, released[0].ID == "w1")
+
+			state, err := deliveryDao.GetState(leased.Delivery)
+			assert.NilError(t, err)
+			assert.Assert(t, state == 0)
+
+			state, err = deliveryDao.GetState(anonymous.Delivery)
+			assert.NilError(t, err)
+			assert.Assert(t, state == types.DeliveryStateProgress)
+
+			worker, err := workerDao.Get("w1")
+			assert.NilError(t, err)
+			assert.Assert(t, worker == nil)
+
+			retaken, err := deliveryDao.Take(bot.ID, 0, leased.User.TelegramID)
+			assert.NilError(t, err)
+			assert.Assert(t, retaken.Delivery.CampaignID == leased.Delivery.CampaignID)
+		})
+	})
+}
+
+func createIntegrationTestLongPollingInvocation(t *testing.T) fx.Option {
+	return fx.Invoke(func(
+		r *gin.Engine,
+		botDao dao.BotDao,
+		userDao dao.UserDao,
+	) {
+		httpServer := httptest.NewServer(r)
+		defer httpServer.Close()
+		restyClient := resty.New().SetHostURL(httpServer.URL)
+
+		campaignDao := client.NewCampaignDaoImplResty(restyClient)
+		deliveryDao := client.NewDeliveryDaoImplRestyLongPolling(restyClient, 5*time.Second)
+
+		bot, err := botDao.Create(&types.Bot{
+			Title: "Long polling bot",
+			Token: "long:polling",
+		})
+		assert.NilError(t, err)
+		_, err = userDao.Put(&types.User{
+			DisplayName: "Long polling user",
+			TelegramID:  1,
+			BotID:       bot.ID,
+		})
+		assert.NilError(t, err)
+
+		t.Run("wake up a waiting take when a campaign is created", func(t *testing.T) {
+			taken := make(chan *dao.DeliveryTakeResult)
+			takeErrors := make(chan error, 1)
+			go func() {
+				result, err := deliveryDao.Take(bot.ID, 0, 0)
+				takeErrors <- err
+				taken <- result
+			}()
+
+			time.Sleep(300 * time.Millisecond)
+			started := time.Now()
+			campaign, err := campaignDao.Create(&types.Campaign{
+				BotID:   bot.ID,
+				Title:   "Long polling campaign",
+				Message: "Long polling message",
+				Active:  true,
+			})
+			assert.NilError(t, err)
+
+			assert.NilError(t, <-takeErrors)
+			result := <-taken
+			assert.Assert(t, result != nil)
+			assert.Assert(t, result.Delivery.CampaignID == campaign.ID)
+			assert.Assert(t, time.Since(started) < 2*time.Second)
+		})
+
+		t.Run("give up when the wait time elapses", func(t *testing.T) {
+			shortPollingDao := client.NewDeliveryDaoImplRestyLongPolling(restyClient, time.Second)
+			started := time.Now()
+			result, err := shortPollingDao.Take(bot.ID, 0, 0)
+			assert.NilError(t, err)
+			assert.Assert(t, result == nil)
+			assert.Assert(t, time.Since(started) >= time.Second)
+		})
 	})
 }
+
+func createIntegrationTestSenderInvocation(t *testing.T) fx.Option {
+	return fx.Invoke(func(
+		r *gin.Engine,
+		db *gorm.DB,
+		userDao dao.UserDao,
+		ingester *ingest.Ingester,
+		fake *telegramtest.Server,
+	) {
+		defer fake.Close()
+		httpServer := httptest.NewServer(r)
+		defer httpServer.Close()
+		restyClient := resty.New().SetHostURL(httpServer.URL)
+
+		botDao := client.NewBotDaoImplResty(restyClient)
+		campaignDao := client.NewCampaignDaoImplResty(restyClient)
+		deliveryDao := client.NewDeliveryDaoImplResty(restyClient)
+		workerDao := client.NewWorkerDaoImplResty(restyClient)
+		operationDao := client.NewCampaignOperationDaoImplResty(restyClient)
+		clickDao := client.NewClickDaoImplResty(restyClient)
+		linkDao := client.NewLinkDaoImplResty(restyClient)
+		answerDao := client.NewAnswerDaoImplResty(restyClient)
+		inboxDao := client.NewInboxDaoImplResty(restyClient)
+		autoResponderDao := client.NewAutoResponderDaoImplResty(restyClient)
+		mediaDao := client.NewMediaDaoImplResty(restyClient)
+		testerDao := client.NewTesterDaoImplResty(restyClient)
+		sequenceDao := client.NewSequenceDaoImplResty(restyClient)
+		telegramClient := telegram.NewClient(fake.URL).SetTimeout(200 * time.Millisecond)
+
+		fake.AddBot("sender:token", telegram.User{FirstName: "Sender bot", UserName: "sender_bot"})
+		bot, err := botDao.Create(&types.Bot{
+			Title: "Sender bot",
+			Token: "sender:token",
+		})
+		assert.NilError(t, err)
+		for telegramID := int64(1); telegramID <= 6; telegramID++ {
+			_, err := userDao.Put(&types.User{
+				DisplayName: fmt.Sprintf("Sender user %d", telegramID),
+				TelegramID:  telegramID,
+				BotID:       bot.ID,
+			})
+			assert.NilError(t, err)
+		}
+
+		t.Run("validate bot tokens", func(t *testing.T) {
+			assert.Equal(t, bot.TelegramUserName, "sender_bot")
+			assert.Assert(t, bot.TelegramID != 0)
+			assert.Assert(t, !bot.ValidatedAt.IsZero())
+
+			_, err := botDao.Create(&types.Bot{
+				Title: "Bot with a wrong token",
+				Token: "wrong:token",
+			})
+			assert.Error(t, err, "Invalid bot token")
+
+			revokedBot := fake.AddBot("revoked:token", telegram.User{UserName: "revoked_bot"})
+			created, err := botDao.Create(&types.Bot{
+				Title: "Bot to be revoked",
+				Token: "revoked:token",
+			})
+			assert.NilError(t, err)
+			assert.Equal(t, created.TelegramID, revokedBot.ID)
+			fake.RemoveBot("revoked:token")
+
+			created.Title = "Renamed bot to be revoked"
+			_, err = botDao.Update(created)
+			assert.Error(t, err, "Invalid bot token")
+
+			resultWrapper := &struct{ Data *types.Bot }{}
+			res, err := restyClient.R().
+				SetResult(resultWrapper).
+				SetPathParams(map[string]string{
+					"BotID": strconv.FormatInt(created.ID, 10),
+				}).
+				Post("/bot/{BotID}/validate")
+			assert.NilError(t, err)
+			assert.Equal(t, res.StatusCode(), http.StatusOK)
+			assert.Assert(t, resultWrapper.Data.TokenRevoked)
+			assert.Equal(t, resultWrapper.Data.TelegramUserName, "revoked_bot")
+
+			stored, err := botDao.Get(created.ID)
+			assert.NilError(t, err)
+			assert.Assert(t, stored.TokenRevoked)
+		})
+
+		t.Run("heartbeat with the worker ID in the path only", func(t *testing.T) {
+			_, err := workerDao.Register(&types.Worker{ID: "path-worker", Version: "1.0.0"})
+			assert.NilError(t, err)
+			res, err := restyClient.R().
+				SetBody(map[string]interface{}{"Version": "1.0.1", "Throughput": 3}).
+				Put("/worker/path-worker/heartbeat")
+			assert.NilError(t, err)
+			assert.Equal(t, res.StatusCode(), http.StatusOK)
+			worker, err := workerDao.Get("path-worker")
+			assert.NilError(t, err)
+			assert.Equal(t, worker.Version, "1.0.1")
+		})
+
+		t.Run("reject wrong states in a bulk report", func(t *testing.T) {
+			for _, state := range []interface{}{"delivered", 2} {
+				res, err := restyClient.R().
+					SetBody([]map[string]interface{}{{"CampaignID": 1, "TelegramID": 1, "State": state}}).
+					SetPathParams(map[string]string{
+						"BotID": strconv.FormatInt(bot.ID, 10),
+					}).
+					Put("/bot/{BotID}/delivery/state")
+				assert.NilError(t, err)
+				assert.Equal(t, res.StatusCode(), http.StatusBadRequest)
+			}
+		})
+
+		t.Run("[fake telegram]", func(t *testing.T) {
+			me, err := telegramClient.GetMe(bot.Token)
+			assert.NilError(t, err)
+			assert.Equal(t, me.UserName, "sender_bot")
+			assert.Assert(t, me.IsBot)
+
+			_, err = telegramClient.GetMe("unknown:token")
+			telegramErr, ok := err.(*telegram.Error)
+			assert.Assert(t, ok)
+			assert.Equal(t, telegramErr.Code, http.StatusUnauthorized)
+
+			photo, err := telegramClient.SendPhoto(bot.Token, &telegram.SendPhotoRequest{
+				ChatID:  100,
+				Photo:   "https://example.com/photo.jpg",
+				Caption: "Photo caption",
+			})
+			assert.NilError(t, err)
+			assert.Equal(t, len(photo.Photo), 1)
+			assert.Equal(t, photo.Caption, "Photo caption")
+			sent := fake.Sent()
+			assert.Equal(t, sent[len(sent)-1].Method, "sendPhoto")
+			assert.Equal(t, sent[len(sent)-1].ChatID, int64(100))
+
+			first := fake.PushUpdate(bot.Token, telegram.Update{
+				Message: &telegram.Message{Chat: &telegram.Chat{ID: 100, Type: "private"}, Text: "first"},
+			})
+			fake.PushUpdate(bot.Token, telegram.Update{
+				Message: &telegram.Message{Chat: &telegram.Chat{ID: 100, Type: "private"}, Text: "second"},
+			})
+			updates, err := telegramClient.GetUpdates(context.Background(), bot.Token, &telegram.GetUpdatesRequest{})
+			assert.NilError(t, err)
+			assert.Equal(t, len(updates), 2)
+			updates, err = telegramClient.GetUpdates(context.Background(), bot.Token, &telegram.GetUpdatesRequest{Offset: first.UpdateID + 1})
+			assert.NilError(t, err)
+			assert.Equal(t, len(updates), 1)
+			assert.Equal(t, updates[0].Message.Text, "second")
+
+			assert.NilError(t, telegramClient.SetWebhook(bot.Token, &telegram.SetWebhookRequest{
+				URL:         "https://example.com/webhook",
+				SecretToken: "secret",
+			}))
+			assert.Equal(t, fake.Webhook(bot.Token).SecretToken, "secret")
+			_, err = telegramClient.GetUpdates(context.Background(), bot.Token, &telegram.GetUpdatesRequest{})
+			telegramErr, ok = err.(*telegram.Error)
+			assert.Assert(t, ok)
+			assert.Equal(t, telegramErr.Code, http.StatusConflict)
+			assert.NilError(t, telegramClient.SetWebhook(bot.Token, &telegram.SetWebhookRequest{}))
+		})
+
+		t.Run("send a campaign and classify failures", func(t *testing.T) {
+			fake.Fail(2, telegramtest.Blocked())
+			fake.Fail(3, telegramtest.TooManyRequests(1))
+			fake.Fail(4, telegramtest.BadEntity())
+			timeout := telegramtest.Timeout(500 * time.Millisecond)
+			timeout.Times = 2
+			fake.Fail(5, timeout)
+			fake.Fail(6, telegramtest.Deactivated())
+
+			campaign, err := campaignDao.Create(&types.Campaign{
+				BotID:   bot.ID,
+				Title:   "Sender campaign",
+				Message: "Sender message",
+				Active:  true,
+			})
+			assert.NilError(t, err)
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID:   "sender-worker",
+				MaxRetries: 1,
+				RetryDelay: 10 * time.Millisecond,
+			})
+			processed, err := s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.Equal(t, processed, 6)
+
+			deliveries := []database.Delivery{}
+			assert.NilError(t, db.Where("campaign_id = ?", campaign.ID).Order("telegram_id").Find(&deliveries).Error)
+			states := map[int64]string{}
+			for _, delivery := range deliveries {
+				state, err := types.DeliveryState(delivery.State).ToString()
+				assert.NilError(t, err)
+				states[delivery.TelegramID] = state + " " + delivery.Reason
+			}
+			assert.DeepEqual(t, states, map[int64]string{
+				1: "Success ",
+				2: "Fail " + types.DeliveryReasonBlocked,
+				3: "Success ",
+				4: "Fail " + types.DeliveryReasonBadRequest,
+				5: "Fail " + types.DeliveryReasonTemporaryError,
+				6: "Fail " + types.DeliveryReasonDeactivated,
+			})
+
+			texts := map[int64]string{}
+			for _, sent := range fake.Sent() {
+				if sent.Text == campaign.Message {
+					texts[sent.ChatID] = sent.Text
+				}
+			}
+			assert.DeepEqual(t, texts, map[int64]string{1: campaign.Message, 3: campaign.Message})
+		})
+
+		t.Run("run until cancelled", func(t *testing.T) {
+			campaign, err := campaignDao.Create(&types.Campaign{
+				BotID:   bot.ID,
+				Title:   "Second sender campaign",
+				Message: "Second sender message",
+				Active:  true,
+			})
+			assert.NilError(t, err)
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "running-sender-worker",
+				IdleWait: 50 * time.Millisecond,
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			stopped := make(chan error, 1)
+			go func() {
+				stopped <- s.Run(ctx)
+			}()
+
+			countSent := func() int {
+				count := 0
+				for _, sent := range fake.Sent() {
+					if sent.Text == campaign.Message {
+						count++
+					}
+				}
+				return count
+			}
+			for deadline := time.Now().Add(5 * time.Second); countSent() < 6 && time.Now().Before(deadline); {
+				time.Sleep(50 * time.Millisecond)
+			}
+			cancel()
+			assert.NilError(t, <-stopped)
+
+			worker, err := workerDao.Get("running-sender-worker")
+			assert.NilError(t, err)
+			assert.Assert(t, worker != nil)
+
+			assert.Equal(t, countSent(), 6)
+			state, err := deliveryDao.GetState(&types.Delivery{
+				BotID:      bot.ID,
+				CampaignID: campaign.ID,
+				TelegramID: 6,
+			})
+			assert.NilError(t, err)
+			assert.Equal(t, state, types.DeliveryState(types.DeliveryStateSuccess))
+		})
+
+		t.Run("return the unprocessed rest of a batch to the queue when cancelled", func(t *testing.T) {
+			campaign, err := campaignDao.Create(&types.Campaign{
+				BotID:   bot.ID,
+				Title:   "Interrupted sender campaign",
+				Message: "Interrupted sender message",
+				Active:  true,
+			})
+			assert.NilError(t, err)
+
+			campaignSent := func(deleted bool) int {
+				count := 0
+				for _, sent := range fake.Sent() {
+					if sent.Text == campaign.Message && sent.Deleted == deleted {
+						count++
+					}
+				}
+				return count
+			}
+			runUntil := func(done func() bool) {
+				s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+					WorkerID:  "interrupted-sender-worker",
+					RateLimit: 4,
+					IdleWait:  50 * time.Millisecond,
+				})
+				ctx, cancel := context.WithCancel(context.Background())
+				stopped := make(chan error, 1)
+				go func() {
+					stopped <- s.Run(ctx)
+				}()
+				for deadline := time.Now().Add(5 * time.Second); !done() && time.Now().Before(deadline); {
+					time.Sleep(10 * time.Millisecond)
+				}
+				cancel()
+				assert.NilError(t, <-stopped)
+			}
+
+			runUntil(func() bool { return campaignSent(false) > 0 })
+			sent := campaignSent(false)
+			assert.Assert(t, sent < 6)
+			var count int64
+			assert.NilError(t, db.Model(&database.Delivery{}).
+				Where("campaign_id = ? AND state = ?", campaign.ID, types.DeliveryStateProgress).
+				Count(&count).Error)
+			assert.Equal(t, count, int64(0))
+			assert.NilError(t, db.Model(&database.QueuedRecipient{}).Where("campaign_id = ?", campaign.ID).Count(&count).Error)
+			assert.Equal(t, count, int64(6-sent))
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "interrupted-sender-worker",
+			})
+			processed, err := s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.Equal(t, processed, 6-sent)
+			assert.Equal(t, campaignSent(false), 6)
+
+			recall, err := operationDao.Recall(bot.ID, campaign.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, recall.Queued, int64(6))
+			runUntil(func() bool { return campaignSent(true) > 0 })
+			deleted := campaignSent(true)
+			assert.Assert(t, deleted < 6)
+			assert.NilError(t, db.Model(&database.MessageJob{}).
+				Where("operation_id = ? AND state = ?", recall.ID, types.MessageJobStateProgress).
+				Count(&count).Error)
+			assert.Equal(t, count, int64(0))
+
+			processed, err = s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.Equal(t, processed, 6-deleted)
+			assert.Equal(t, campaignSent(true), 6)
+		})
+
+		t.Run("edit and recall a campaign", func(t *testing.T) {
+			campaign, err := campaignDao.Create(&types.Campaign{
+				BotID:   bot.ID,
+				Title:   "Operations campaign",
+				Message: "Message with a typo",
+				Active:  true,
+			})
+			assert.NilError(t, err)
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "operations-sender-worker",
+			})
+			processed, err := s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.Equal(t, processed, 6)
+
+			deliveries := []database.Delivery{}
+			assert.NilError(t, db.Where("campaign_id = ?", campaign.ID).Find(&deliveries).Error)
+			assert.Equal(t, len(deliveries), 6)
+			for _, delivery := range deliveries {
+				assert.Assert(t, delivery.MessageID != 0)
+			}
+
+			campaignSent := func() []telegramtest.Sent {
+				result := []telegramtest.Sent{}
+				for _, sent := range fake.Sent() {
+					if sent.Text == campaign.Message || sent.Text == "Fixed message" {
+						result = append(result, sent)
+					}
+				}
+				return result
+			}
+
+			_, err = operationDao.Edit(bot.ID, campaign.ID, "Fixed message")
+			assert.ErrorContains(t, err, "The message of a running campaign cannot be changed")
+			_, err = campaignDao.SetState(bot.ID, campaign.ID, types.CampaignStatePaused)
+			assert.NilError(t, err)
+
+			edit, err := operationDao.Edit(bot.ID, campaign.ID, "Fixed message")
+			assert.NilError(t, err)
+			assert.Equal(t, edit.Kind, types.CampaignOperationEdit)
+			assert.Equal(t, edit.Queued, int64(6))
+
+			updatedCampaign, err := campaignDao.Get(bot.ID, campaign.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, updatedCampaign.Message, "Fixed message")
+
+			processed, err = s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.Equal(t, processed, 6)
+
+			edit, err = operationDao.Get(bot.ID, campaign.ID, edit.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, edit.Success, int64(6))
+			assert.Equal(t, edit.Queued, int64(0))
+			for _, sent := range campaignSent() {
+				assert.Equal(t, sent.Text, "Fixed message")
+				assert.Assert(t, sent.Edited)
+			}
+
+			//The message ID of an edit is kept whatever kind the report claims
+			editJob := &database.MessageJob{}
+			assert.NilError(t, db.Where("operation_id = ?", edit.ID).First(editJob).Error)
+			assert.Assert(t, editJob.MessageID != 0)
+			assert.NilError(t, operationDao.SetJobStates([]types.MessageJob{{
+				ID:        editJob.ID,
+				BotID:     bot.ID,
+				Kind:      types.MessageJobReply,
+				MessageID: editJob.MessageID + 1000,
+				State:     types.MessageJobStateSuccess,
+			}}))
+			reportedJob := &database.MessageJob{}
+			assert.NilError(t, db.First(reportedJob, "id = ?", editJob.ID).Error)
+			assert.Equal(t, reportedJob.MessageID, editJob.MessageID)
+
+			recall, err := operationDao.Recall(bot.ID, campaign.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, recall.Queued, int64(6))
+
+			updatedCampaign, err = campaignDao.Get(bot.ID, campaign.ID)
+			assert.NilError(t, err)
+			assert.Assert(t, !updatedCampaign.Active)
+			assert.Equal(t, updatedCampaign.State, types.CampaignStateCompleted)
+
+			processed, err = s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.Equal(t, processed, 6)
+
+			for _, sent := range campaignSent() {
+				assert.Assert(t, sent.Deleted)
+			}
+
+			operations, err := operationDao.List(bot.ID, campaign.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, len(operations), 2)
+
+			recall, err = operationDao.Get(bot.ID, campaign.ID, recall.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, recall.Success, int64(6))
+
+			//Messages which are already deleted are not recalled twice
+			_, err = operationDao.Recall(bot.ID, campaign.ID)
+			assert.NilError(t, err)
+			processed, err = s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.Equal(t, processed, 0)
+		})
+
+		t.Run("count button clicks", func(t *testing.T) {
+			campaign, err := campaignDao.Create(&types.Campaign{
+				BotID:   bot.ID,
+				Title:   "Buttons campaign",
+				Message: "Do you like it?",
+				Active:  true,
+				Buttons: []types.CampaignButton{
+					{Text: "Yes"},
+					{Text: "No"},
+					{Text: "Tell me more", Row: 1},
+				},
+			})
+			assert.NilError(t, err)
+			assert.Equal(t, len(campaign.Buttons), 3)
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "buttons-sender-worker",
+			})
+			processed, err := s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.Equal(t, processed, 6)
+
+			var message *telegramtest.Sent
+			for _, sent := range fake.Sent() {
+				if sent.Text == campaign.Message && sent.ChatID == 1 {
+					message = &sent
+				}
+			}
+			assert.Assert(t, message != nil)
+			assert.DeepEqual(t, message.ReplyMarkup, &telegram.InlineKeyboardMarkup{
+				InlineKeyboard: [][]telegram.InlineKeyboardButton{
+					{
+						{Text: "Yes", CallbackData: types.EncodeCallbackData(campaign.ID, 0, 0)},
+						{Text: "No", CallbackData: types.EncodeCallbackData(campaign.ID, 0, 1)},
+					},
+					{
+						{Text: "Tell me more", CallbackData: types.EncodeCallbackData(campaign.ID, 0, 2)},
+					},
+				},
+			})
+
+			press := func(queryID string, telegramID int64, data string) {
+				assert.NilError(t, ingester.Ingest(bot, &telegram.Update{
+					CallbackQuery: &telegram.CallbackQuery{
+						ID:   queryID,
+						From: &telegram.User{ID: telegramID, FirstName: "Clicker"},
+						Data: data,
+					},
+				}))
+			}
+			press("query-1", 1, types.EncodeCallbackData(campaign.ID, 0, 0))
+			press("query-2", 1, types.EncodeCallbackData(campaign.ID, 0, 0))
+			//Telegram redelivers an update
+			press("query-2", 1, types.EncodeCallbackData(campaign.ID, 0, 0))
+			press("query-3", 2, types.EncodeCallbackData(campaign.ID, 0, 0))
+			press("query-4", 2, types.EncodeCallbackData(campaign.ID, 0, 2))
+			//The message has not been delivered to this user
+			press("query-5", 99, types.EncodeCallbackData(campaign.ID, 0, 1))
+			//A button of another bot feature
+			press("query-6", 3, "something else")
+
+			click, err := clickDao.Add(&types.Click{
+				BotID:           bot.ID,
+				CampaignID:      campaign.ID,
+				TelegramID:      4,
+				Button:          1,
+				CallbackQueryID: "query-7",
+			})
+			assert.NilError(t, err)
+			assert.Equal(t, click.Button, 1)
+			click, err = clickDao.Add(&types.Click{
+				BotID:           bot.ID,
+				CampaignID:      campaign.ID,
+				TelegramID:      4,
+				Button:          1,
+				CallbackQueryID: "query-7",
+			})
+			assert.NilError(t, err)
+			assert.Assert(t, click == nil)
+
+			assert.DeepEqual(t, fake.Answered(), []string{"query-1", "query-2", "query-2", "query-3", "query-4", "query-5"})
+
+			stat, err := campaignDao.GetAggregatedStatistics(bot.ID, campaign.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, stat.Delivered, int64(6))
+			assert.Equal(t, stat.Clicks, int64(5))
+			assert.Equal(t, stat.Clickers, int64(3))
+			assert.Equal(t, stat.ClickThroughRate, 0.5)
+			assert.DeepEqual(t, stat.Buttons, []types.ButtonStatistics{
+				{Button: 0, Text: "Yes", Clicks: 3, Clickers: 2, ClickThroughRate: 2.0 / 6},
+				{Button: 1, Text: "No", Clicks: 1, Clickers: 1, ClickThroughRate: 1.0 / 6},
+				{Button: 2, Text: "Tell me more", Clicks: 1, Clickers: 1, ClickThroughRate: 1.0 / 6},
+			})
+		})
+
+		t.Run("track links", func(t *testing.T) {
+			campaign, err := campaignDao.Create(&types.Campaign{
+				BotID:      bot.ID,
+				Title:      "Links campaign",
+				Message:    "Read https://example.com/news?id=1. Or http://example.com/about",
+				Active:     true,
+				TrackLinks: true,
+			})
+			assert.NilError(t, err)
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID:    "links-sender-worker",
+				LinkBaseURL: httpServer.URL + "/",
+			})
+			processed, err := s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.Equal(t, processed, 6)
+
+			trackedPattern := regexp.MustCompile(`^Read (\S+/l/(\S+))\. Or (\S+/l/\S+)$`)
+			trackedURLs := map[int64]string{}
+			codes := map[string]bool{}
+			for _, sent := range fake.Sent() {
+				if !strings.HasPrefix(sent.Text, "Read ") {
+					continue
+				}
+				match := trackedPattern.FindStringSubmatch(sent.Text)
+				assert.Assert(t, match != nil, sent.Text)
+				assert.Assert(t, strings.HasPrefix(match[1], httpServer.URL+"/l/"))
+				trackedURLs[sent.ChatID] = match[1]
+				codes[match[2]] = true
+			}
+			assert.Equal(t, len(trackedURLs), 6)
+			assert.Equal(t, len(codes), 6)
+
+			httpClient := &http.Client{
+				CheckRedirect: func(req *http.Request, via []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}
+			open := func(url string) *http.Response {
+				res, err := httpClient.Get(url)
+				assert.NilError(t, err)
+				res.Body.Close()
+				return res
+			}
+			res := open(trackedURLs[1])
+			assert.Equal(t, res.StatusCode, http.StatusFound)
+			assert.Equal(t, res.Header.Get("Location"), "https://example.com/news?id=1")
+			open(trackedURLs[1])
+			open(trackedURLs[2])
+			assert.Equal(t, open(httpServer.URL+"/l/unknown").StatusCode, http.StatusNotFound)
+
+			link, err := linkDao.Open(strings.TrimPrefix(trackedURLs[3], httpServer.URL+"/l/"))
+			assert.NilError(t, err)
+			assert.Equal(t, link.TelegramID, int64(3))
+			assert.Equal(t, link.CampaignID, campaign.ID)
+
+			stat, err := campaignDao.GetAggregatedStatistics(bot.ID, campaign.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, stat.LinkClicks, int64(4))
+			assert.Equal(t, stat.UniqueLinkClicks, int64(3))
+		})
+
+		t.Run("collect survey and poll answers", func(t *testing.T) {
+			_, err := campaignDao.Create(&types.Campaign{
+				BotID:   bot.ID,
+				Title:   "Poll with a single option",
+				Message: "Yes?",
+				Kind:    types.CampaignKindPoll,
+				Options: []string{"Yes"},
+			})
+			assert.ErrorContains(t, err, "Options")
+
+			survey, err := campaignDao.Create(&types.Campaign{
+				BotID:   bot.ID,
+				Title:   "Survey campaign",
+				Message: "Which colour do you like?",
+				Active:  true,
+				Kind:    types.CampaignKindSurvey,
+				Options: []string{"Red", "Green"},
+			})
+			assert.NilError(t, err)
+			poll, err := campaignDao.Create(&types.Campaign{
+				BotID:   bot.ID,
+				Title:   "Poll campaign",
+				Message: "Which day is the best?",
+				Active:  true,
+				Kind:    types.CampaignKindPoll,
+				Options: []string{"Monday", "Friday", "Sunday"},
+			})
+			assert.NilError(t, err)
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "answers-sender-worker",
+			})
+			processed, err := s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.Equal(t, processed, 12)
+
+			pollIDs := map[int64]string{}
+			for _, sent := range fake.Sent() {
+				switch sent.Text {
+				case survey.Message:
+					assert.DeepEqual(t, sent.ReplyMarkup, &telegram.InlineKeyboardMarkup{
+						InlineKeyboard: [][]telegram.InlineKeyboardButton{
+							{{Text: "Red", CallbackData: types.EncodeAnswerCallbackData(survey.ID, 0)}},
+							{{Text: "Green", CallbackData: types.EncodeAnswerCallbackData(survey.ID, 1)}},
+						},
+					})
+				case poll.Message:
+					assert.Equal(t, sent.Method, "sendPoll")
+					assert.DeepEqual(t, sent.PollOptions, poll.Options)
+					pollIDs[sent.ChatID] = sent.PollID
+				}
+			}
+			assert.Equal(t, len(pollIDs), 6)
+
+			_, err = operationDao.Edit(bot.ID, poll.ID, "Which day is the worst?")
+			assert.ErrorContains(t, err, "Polls cannot be edited")
+			res, err := restyClient.R().
+				SetPathParams(map[string]string{
+					"BotID":      strconv.FormatInt(bot.ID, 10),
+					"CampaignID": strconv.FormatInt(poll.ID, 10),
+				}).
+				SetBody(map[string]string{"Text": "Which day is the worst?"}).
+				Post("/bot/{BotID}/campaign/{CampaignID}/edit")
+			assert.NilError(t, err)
+			assert.Equal(t, res.StatusCode(), http.StatusConflict)
+
+			choose := func(queryID string, telegramID int64, option int) {
+				assert.NilError(t, ingester.Ingest(bot, &telegram.Update{
+					CallbackQuery: &telegram.CallbackQuery{
+						ID:   queryID,
+						From: &telegram.User{ID: telegramID, FirstName: "Respondent"},
+						Data: types.EncodeAnswerCallbackData(survey.ID, option),
+					},
+				}))
+			}
+			choose("survey-1", 1, 0)
+			choose("survey-2", 2, 0)
+			//The user changes their mind
+			choose("survey-3", 2, 1)
+			choose("survey-4", 3, 1)
+			//The survey has not been delivered to this user
+			choose("survey-5", 99, 0)
+			//There is no such option
+			choose("survey-6", 4, 5)
+
+			vote := func(telegramID int64, optionIDs ...int) {
+				assert.NilError(t, ingester.Ingest(bot, &telegram.Update{
+					PollAnswer: &telegram.PollAnswer{
+						PollID:    pollIDs[telegramID],
+						User:      &telegram.User{ID: telegramID, FirstName: "Voter"},
+						OptionIDs: optionIDs,
+					},
+				}))
+			}
+			vote(1, 1)
+			vote(2, 2)
+			vote(3, 1)
+			//The vote is retracted
+			vote(3)
+			answer, err := answerDao.Put(&types.Answer{
+				BotID:      bot.ID,
+				CampaignID: poll.ID,
+				TelegramID: 4,
+				Option:     1,
+			})
+			assert.NilError(t, err)
+			assert.Equal(t, answer.CampaignID, poll.ID)
+
+			results, err := answerDao.Results(bot.ID, survey.ID)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, results, &types.SurveyResults{
+				Answers: 3,
+				Options: []types.OptionResult{
+					{Option: 0, Text: "Red", Answers: 1, Share: 1.0 / 3},
+					{Option: 1, Text: "Green", Answers: 2, Share: 2.0 / 3},
+				},
+			})
+			results, err = answerDao.Results(bot.ID, poll.ID)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, results, &types.SurveyResults{
+				Answers: 3,
+				Options: []types.OptionResult{
+					{Option: 0, Text: "Monday"},
+					{Option: 1, Text: "Friday", Answers: 2, Share: 2.0 / 3},
+					{Option: 2, Text: "Sunday", Answers: 1, Share: 1.0 / 3},
+				},
+			})
+
+			answers, err := answerDao.List(bot.ID, survey.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, len(answers), 3)
+			assert.Equal(t, answers[0].TelegramID, int64(1))
+
+			res, err = restyClient.R().
+				SetPathParams(map[string]string{
+					"BotID":      strconv.FormatInt(bot.ID, 10),
+					"CampaignID": strconv.FormatInt(survey.ID, 10),
+				}).
+				Get("/bot/{BotID}/campaign/{CampaignID}/answer.csv")
+			assert.NilError(t, err)
+			assert.Equal(t, res.StatusCode(), http.StatusOK)
+			assert.Equal(t, res.Header().Get("Content-Type"), "text/csv; charset=utf-8")
+			lines := strings.Split(strings.TrimSpace(res.String()), "\n")
+			assert.Equal(t, len(lines), 4)
+			assert.Equal(t, lines[0], "TelegramID,Option,OptionText,AnsweredAt")
+			assert.Assert(t, strings.HasPrefix(lines[1], "1,0,Red,"), lines[1])
+		})
+
+		t.Run("reply to users from the inbox", func(t *testing.T) {
+			campaign, err := campaignDao.Create(&types.Campaign{
+				BotID:   bot.ID,
+				Title:   "Inbox campaign",
+				Message: "Write us back",
+				Active:  true,
+			})
+			assert.NilError(t, err)
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "inbox-sender-worker",
+			})
+			processed, err := s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.Equal(t, processed, 6)
+
+			write := func(telegramID int64, message *telegram.Message) {
+				message.From = &telegram.User{ID: telegramID, FirstName: fmt.Sprintf("Sender user %d", telegramID)}
+				message.Chat = &telegram.Chat{ID: telegramID, Type: "private"}
+				assert.NilError(t, ingester.Ingest(bot, &telegram.Update{Message: message}))
+			}
+			write(1, &telegram.Message{MessageID: 1001, Text: "Thank you!"})
+			//Telegram redelivers an update
+			write(1, &telegram.Message{MessageID: 1001, Text: "Thank you!"})
+			write(2, &telegram.Message{MessageID: 1002, Caption: "Look at this"})
+			//A user who has got no campaign
+			write(7, &telegram.Message{MessageID: 1003, Text: "Hello"})
+
+			conversations, paging, err := inboxDao.List(bot.ID, &types.PaginatorRequest{Page: 1, Size: 2})
+			assert.NilError(t, err)
+			assert.Equal(t, paging.TotalItems, 3)
+			assert.Equal(t, len(conversations), 2)
+			assert.Equal(t, conversations[0].TelegramID, int64(7))
+			assert.Equal(t, conversations[0].DisplayName, "Sender user 7")
+			assert.Equal(t, conversations[0].LastMessage.CampaignID, int64(0))
+			assert.Equal(t, conversations[1].TelegramID, int64(2))
+			assert.Equal(t, conversations[1].LastMessage.Text, "Look at this")
+			assert.Equal(t, conversations[1].LastMessage.CampaignID, campaign.ID)
+
+			reply, err := inboxDao.Reply(bot.ID, 1, "You are welcome")
+			assert.NilError(t, err)
+			assert.Equal(t, reply.Direction, types.InboxDirectionOutgoing)
+			assert.Equal(t, reply.State, types.MessageJobStateQueued)
+			_, err = inboxDao.Reply(bot.ID, 12345, "Who are you?")
+			assert.ErrorContains(t, err, "User not found")
+
+			processed, err = s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.Equal(t, processed, 1)
+			sent := fake.Sent()
+			lastSent := sent[len(sent)-1]
+			assert.Equal(t, lastSent.ChatID, int64(1))
+			assert.Equal(t, lastSent.Text, "You are welcome")
+
+			messages, paging, err := inboxDao.Conversation(bot.ID, 1, &types.PaginatorRequest{})
+			assert.NilError(t, err)
+			assert.Equal(t, paging.TotalItems, 2)
+			assert.Equal(t, len(messages), 2)
+			assert.Equal(t, messages[0].Direction, types.InboxDirectionOutgoing)
+			assert.Assert(t, messages[0].State == types.MessageJobStateSuccess)
+			assert.Equal(t, messages[0].MessageID, lastSent.MessageID)
+			assert.Equal(t, messages[1].Direction, types.InboxDirectionIncoming)
+			assert.Equal(t, messages[1].Text, "Thank you!")
+			assert.Equal(t, messages[1].MessageID, int64(1001))
+			assert.Equal(t, messages[1].CampaignID, campaign.ID)
+
+			conversations, _, err = inboxDao.List(bot.ID, &types.PaginatorRequest{})
+			assert.NilError(t, err)
+			assert.Equal(t, conversations[0].TelegramID, int64(1))
+			assert.Equal(t, conversations[0].Messages, int64(2))
+
+			//A repeated report does not make an older delivery the last campaign of the user
+			older := database.Delivery{}
+			assert.NilError(t, db.
+				Where("bot_id = ? AND telegram_id = ? AND state = ? AND campaign_id <> ?",
+					bot.ID, 3, types.DeliveryStateSuccess, campaign.ID).
+				First(&older).Error)
+			assert.NilError(t, deliveryDao.SetState(&types.Delivery{
+				BotID:      bot.ID,
+				CampaignID: older.CampaignID,
+				TelegramID: 3,
+			}, types.DeliveryStateSuccess))
+			write(3, &telegram.Message{MessageID: 1004, Text: "Which sale?"})
+			messages, _, err = inboxDao.Conversation(bot.ID, 3, &types.PaginatorRequest{})
+			assert.NilError(t, err)
+			assert.Equal(t, messages[0].CampaignID, campaign.ID)
+		})
+
+		t.Run("auto-respond to messages", func(t *testing.T) {
+			_, err := autoResponderDao.Create(&types.AutoResponderRule{
+				BotID:   bot.ID,
+				Match:   types.AutoResponderMatchRegex,
+				Pattern: "order #(",
+				Reply:   "Broken",
+			})
+			assert.ErrorContains(t, err, "error parsing regexp")
+
+			genericPrice, err := autoResponderDao.Create(&types.AutoResponderRule{
+				BotID:    bot.ID,
+				Match:    types.AutoResponderMatchKeyword,
+				Pattern:  "price",
+				Reply:    "See our price list",
+				Priority: 10,
+			})
+			assert.NilError(t, err)
+			exactPrice, err := autoResponderDao.Create(&types.AutoResponderRule{
+				BotID:   bot.ID,
+				Match:   types.AutoResponderMatchExact,
+				Pattern: "price",
+				Reply:   "It costs $10",
+			})
+			assert.NilError(t, err)
+			stop, err := autoResponderDao.Create(&types.AutoResponderRule{
+				BotID:       bot.ID,
+				Match:       types.AutoResponderMatchKeyword,
+				Pattern:     "stop",
+				Reply:       "You will not get our news anymore",
+				AddTag:      "stopped",
+				Unsubscribe: true,
+			})
+			assert.NilError(t, err)
+			_, err = autoResponderDao.Create(&types.AutoResponderRule{
+				BotID:   bot.ID,
+				Match:   types.AutoResponderMatchRegex,
+				Pattern: `(?i)^order #\d+`,
+				Reply:   "We are checking your order",
+				AddTag:  "orders",
+			})
+			assert.NilError(t, err)
+
+			rules, err := autoResponderDao.List(bot.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, len(rules), 4)
+			assert.Equal(t, rules[0].ID, exactPrice.ID)
+			assert.Equal(t, rules[3].ID, genericPrice.ID)
+
+			write := func(telegramID int64, messageID int64, text string) {
+				assert.NilError(t, ingester.Ingest(bot, &telegram.Update{Message: &telegram.Message{
+					MessageID: messageID,
+					From:      &telegram.User{ID: telegramID, FirstName: fmt.Sprintf("Sender user %d", telegramID)},
+					Chat:      &telegram.Chat{ID: telegramID, Type: "private"},
+					Text:      text,
+				}}))
+			}
+			write(1, 2001, " Price ")
+			//Telegram redelivers an update
+			write(1, 2001, " Price ")
+			write(2, 2002, "Please STOP sending this")
+			write(3, 2003, "Order #123 is late")
+			write(4, 2004, "What is the price of a stopwatch?")
+			write(5, 2005, "Hello")
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "auto-responder-sender-worker",
+			})
+			processed, err := s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.Equal(t, processed, 4)
+			replies := map[int64]string{}
+			sent := fake.Sent()
+			for _, message := range sent[len(sent)-4:] {
+				replies[message.ChatID] = message.Text
+			}
+			assert.DeepEqual(t, replies, map[int64]string{
+				1: "It costs $10",
+				2: "You will not get our news anymore",
+				3: "We are checking your order",
+				4: "See our price list",
+			})
+
+			user, err := userDao.Get(bot.ID, 2)
+			assert.NilError(t, err)
+			assert.Assert(t, user.Unsubscribed)
+			assert.DeepEqual(t, user.Tags, []string{"stopped"})
+			user, err = userDao.Get(bot.ID, 3)
+			assert.NilError(t, err)
+			assert.Assert(t, !user.Unsubscribed)
+			assert.DeepEqual(t, user.Tags, []string{"orders"})
+
+			_, err = campaignDao.Create(&types.Campaign{
+				BotID:   bot.ID,
+				Title:   "Campaign after unsubscribing",
+				Message: "News",
+				Active:  true,
+			})
+			assert.NilError(t, err)
+			_, err = s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			news := 0
+			for _, message := range fake.Sent()[len(sent):] {
+				assert.Assert(t, message.ChatID != 2)
+				if message.Text == "News" {
+					news++
+				}
+			}
+			//Users 1 to 7 but the unsubscribed one
+			assert.Equal(t, news, 6)
+
+			assert.NilError(t, autoResponderDao.Delete(bot.ID, stop.ID))
+			_, err = autoResponderDao.Get(bot.ID, stop.ID)
+			assert.ErrorContains(t, err, "Rule not found")
+		})
+
+		t.Run("attribute users to deep-link sources", func(t *testing.T) {
+			fake.AddBot("sources:token", telegram.User{FirstName: "Sources bot", UserName: "sources_bot"})
+			sourcesBot, err := botDao.Create(&types.Bot{
+				Title: "Sources bot",
+				Token: "sources:token",
+			})
+			assert.NilError(t, err)
+			restyUserDao := client.NewUserDaoImplResty(restyClient)
+			conversionDao := client.NewConversionDaoImplResty(restyClient)
+
+			messageID := int64(0)
+			write := func(telegramID int64, text string) {
+				messageID++
+				assert.NilError(t, ingester.Ingest(sourcesBot, &telegram.Update{Message: &telegram.Message{
+					MessageID: messageID,
+					From:      &telegram.User{ID: telegramID, FirstName: "Visitor"},
+					Chat:      &telegram.Chat{ID: telegramID, Type: "private"},
+					Text:      text,
+				}}))
+			}
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "sources-sender-worker",
+			})
+			sentTo := func(text string) []int64 {
+				for i := 0; i < 4; i++ {
+					_, err := s.RunOnce(context.Background())
+					assert.NilError(t, err)
+				}
+				chatIDs := []int64{}
+				for _, sent := range fake.Sent() {
+					if sent.Token == sourcesBot.Token && sent.Text == text {
+						chatIDs = append(chatIDs, sent.ChatID)
+					}
+				}
+				sort.Slice(chatIDs, func(i, j int) bool { return chatIDs[i] < chatIDs[j] })
+				return chatIDs
+			}
+
+			write(101, "/start ads_fb")
+			write(101, "/start ads_vk")
+			write(102, "/start@sources_bot ads_vk")
+			write(103, "/start")
+			write(103, "hello")
+			_, err = restyUserDao.Put(&types.User{
+				BotID:       sourcesBot.ID,
+				TelegramID:  104,
+				DisplayName: "Imported",
+				LastSource:  "partner",
+			})
+			assert.NilError(t, err)
+
+			user, err := userDao.Get(sourcesBot.ID, 101)
+			assert.NilError(t, err)
+			assert.Equal(t, user.FirstSource, "ads_fb")
+			assert.Equal(t, user.LastSource, "ads_vk")
+			user, err = userDao.Get(sourcesBot.ID, 103)
+			assert.NilError(t, err)
+			assert.Equal(t, user.FirstSource, "")
+			assert.Equal(t, user.LastSource, "")
+
+			sources, err := restyUserDao.Sources(sourcesBot.ID)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, sources, []types.SourceUsers{
+				{Source: "", FirstTouch: 1, LastTouch: 1},
+				{Source: "ads_fb", FirstTouch: 1},
+				{Source: "ads_vk", FirstTouch: 1, LastTouch: 2},
+				{Source: "partner", FirstTouch: 1, LastTouch: 1},
+			})
+
+			_, err = campaignDao.Create(&types.Campaign{
+				BotID:      sourcesBot.ID,
+				Title:      "Last touch campaign",
+				Message:    "For VK visitors",
+				Active:     true,
+				LastSource: "ads_vk",
+			})
+			assert.NilError(t, err)
+			_, err = campaignDao.Create(&types.Campaign{
+				BotID:       sourcesBot.ID,
+				Title:       "First touch campaign",
+				Message:     "For FB visitors",
+				Active:      true,
+				FirstSource: "ads_fb",
+			})
+			assert.NilError(t, err)
+			//A user who comes while the campaigns are active
+			write(105, "/start ads_vk")
+			assert.DeepEqual(t, sentTo("For VK visitors"), []int64{101, 102, 105})
+			assert.DeepEqual(t, sentTo("For FB visitors"), []int64{101})
+
+			campaign, err := campaignDao.Create(&types.Campaign{
+				BotID:   sourcesBot.ID,
+				Title:   "Breakdown campaign",
+				Message: "For everyone",
+				Active:  true,
+				Buttons: []types.CampaignButton{{Text: "Like"}},
+			})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, sentTo("For everyone"), []int64{101, 102, 103, 104, 105})
+			for _, telegramID := range []int64{101, 102} {
+				assert.NilError(t, ingester.Ingest(sourcesBot, &telegram.Update{
+					CallbackQuery: &telegram.CallbackQuery{
+						ID:   fmt.Sprintf("sources-query-%d", telegramID),
+						From: &telegram.User{ID: telegramID, FirstName: "Visitor"},
+						Data: types.EncodeCallbackData(campaign.ID, 0, 0),
+					},
+				}))
+			}
+			_, err = conversionDao.Create(&types.Conversion{
+				BotID:      sourcesBot.ID,
+				TelegramID: 102,
+				Event:      "purchase",
+				Value:      10,
+			})
+			assert.NilError(t, err)
+
+			stat, err := campaignDao.GetSourceStatistics(sourcesBot.ID, campaign.ID, types.SourceTouchFirst)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, stat, []types.CampaignSourceStatistics{
+				{Source: "", Delivered: 1},
+				{Source: "ads_fb", Delivered: 1, Clickers: 1, ClickThroughRate: 1},
+				{Source: "ads_vk", Delivered: 2, Clickers: 1, ClickThroughRate: 0.5, Conversions: 1, Revenue: 10},
+				{Source: "partner", Delivered: 1},
+			})
+			stat, err = campaignDao.GetSourceStatistics(sourcesBot.ID, campaign.ID, types.SourceTouchLast)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, stat, []types.CampaignSourceStatistics{
+				{Source: "", Delivered: 1},
+				{Source: "ads_vk", Delivered: 3, Clickers: 2, ClickThroughRate: 2.0 / 3, Conversions: 1, Revenue: 10},
+				{Source: "partner", Delivered: 1},
+			})
+			_, err = campaignDao.GetSourceStatistics(sourcesBot.ID, campaign.ID, "middle")
+			assert.ErrorContains(t, err, "Touch")
+		})
+
+		t.Run("follow changes of sources and targeting of queued recipients", func(t *testing.T) {
+			fake.AddBot("retargeting:token", telegram.User{FirstName: "Retargeting bot", UserName: "retargeting_bot"})
+			retargetingBot, err := botDao.Create(&types.Bot{
+				Title: "Retargeting bot",
+				Token: "retargeting:token",
+			})
+			assert.NilError(t, err)
+			messageID := int64(0)
+			write := func(telegramID int64, text string) {
+				messageID++
+				assert.NilError(t, ingester.Ingest(retargetingBot, &telegram.Update{Message: &telegram.Message{
+					MessageID: messageID,
+					From:      &telegram.User{ID: telegramID, FirstName: "Visitor"},
+					Chat:      &telegram.Chat{ID: telegramID, Type: "private"},
+					Text:      text,
+				}}))
+			}
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "retargeting-sender-worker",
+			})
+			sentTo := func(text string) []int64 {
+				for i := 0; i < 4; i++ {
+					_, err := s.RunOnce(context.Background())
+					assert.NilError(t, err)
+				}
+				chatIDs := []int64{}
+				for _, sent := range fake.Sent() {
+					if sent.Token == retargetingBot.Token && sent.Text == text {
+						chatIDs = append(chatIDs, sent.ChatID)
+					}
+				}
+				sort.Slice(chatIDs, func(i, j int) bool { return chatIDs[i] < chatIDs[j] })
+				return chatIDs
+			}
+
+			write(111, "/start ads_vk")
+			write(112, "/start ads_fb")
+			write(113, "/start")
+			_, err = campaignDao.Create(&types.Campaign{
+				BotID:      retargetingBot.ID,
+				Title:      "Campaign for changing users",
+				Message:    "For VK visitors",
+				Active:     true,
+				LastSource: "ads_vk",
+			})
+			assert.NilError(t, err)
+			//The users come again through other links while they are queued
+			write(111, "/start ads_ok")
+			write(112, "/start ads_vk")
+			assert.DeepEqual(t, sentTo("For VK visitors"), []int64{112})
+
+			campaign, err := campaignDao.Create(&types.Campaign{
+				BotID:      retargetingBot.ID,
+				Title:      "Retargeted campaign",
+				Message:    "For retargeted visitors",
+				Active:     true,
+				LastSource: "ads_ok",
+			})
+			assert.NilError(t, err)
+			campaign.LastSource = "ads_vk"
+			_, err = campaignDao.Update(campaign)
+			assert.NilError(t, err)
+			//A stale entry is not taken, the user is not targeted by the campaign
+			assert.NilError(t, db.Create(&database.QueuedRecipient{
+				CampaignID: campaign.ID,
+				BotID:      retargetingBot.ID,
+				TelegramID: 113,
+			}).Error)
+			assert.DeepEqual(t, sentTo("For retargeted visitors"), []int64{112})
+		})
+
+		t.Run("broadcast to groups and channels", func(t *testing.T) {
+			fake.AddBot("chats:token", telegram.User{FirstName: "Chats bot", UserName: "chats_bot"})
+			chatsBot, err := botDao.Create(&types.Bot{
+				Title: "Chats bot",
+				Token: "chats:token",
+			})
+			assert.NilError(t, err)
+
+			admin := &telegram.User{ID: 301, FirstName: "Admin"}
+			addBot := func(chat *telegram.Chat, status string) {
+				assert.NilError(t, ingester.Ingest(chatsBot, &telegram.Update{MyChatMember: &telegram.ChatMemberUpdated{
+					Chat:          chat,
+					From:          admin,
+					NewChatMember: &telegram.ChatMember{Status: status},
+				}}))
+			}
+			addBot(&telegram.Chat{ID: -201, Type: "group", Title: "Group"}, telegram.ChatMemberStatusMember)
+			addBot(&telegram.Chat{ID: -1001, Type: "channel", Title: "Channel", UserName: "news"}, telegram.ChatMemberStatusAdministrator)
+			addBot(&telegram.Chat{ID: -1002, Type: "supergroup", Title: "Left group"}, telegram.ChatMemberStatusMember)
+			addBot(&telegram.Chat{ID: -1002, Type: "supergroup", Title: "Left group"}, telegram.ChatMemberStatusLeft)
+			assert.NilError(t, ingester.Ingest(chatsBot, &telegram.Update{Message: &telegram.Message{
+				MessageID: 1,
+				From:      admin,
+				Chat:      &telegram.Chat{ID: admin.ID, Type: "private"},
+				Text:      "/start",
+			}}))
+
+			group, err := userDao.Get(chatsBot.ID, -201)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, group, &types.User{
+				BotID:       chatsBot.ID,
+				TelegramID:  -201,
+				DisplayName: "Group",
+				ChatType:    types.ChatTypeGroup,
+			})
+			//A client which does not know chat types keeps the type of an existing chat
+			group, err = userDao.Put(&types.User{BotID: chatsBot.ID, TelegramID: -201, DisplayName: "Group"})
+			assert.NilError(t, err)
+			assert.Equal(t, group.ChatType, types.ChatTypeGroup)
+			group, err = userDao.Get(chatsBot.ID, -201)
+			assert.NilError(t, err)
+			assert.Equal(t, group.ChatType, types.ChatTypeGroup)
+			channel, err := userDao.Get(chatsBot.ID, -1001)
+			assert.NilError(t, err)
+			assert.Equal(t, channel.UserName, "news")
+			assert.Equal(t, channel.ChatType, types.ChatTypeChannel)
+			leftGroup, err := userDao.Get(chatsBot.ID, -1002)
+			assert.NilError(t, err)
+			assert.Assert(t, leftGroup.Blocked)
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "chats-sender-worker",
+			})
+			sentTo := func(text string) []int64 {
+				for i := 0; i < 4; i++ {
+					_, err := s.RunOnce(context.Background())
+					assert.NilError(t, err)
+				}
+				chatIDs := []int64{}
+				for _, sent := range fake.Sent() {
+					if sent.Token == chatsBot.Token && sent.Text == text {
+						chatIDs = append(chatIDs, sent.ChatID)
+					}
+				}
+				sort.Slice(chatIDs, func(i, j int) bool { return chatIDs[i] < chatIDs[j] })
+				return chatIDs
+			}
+			for _, campaign := range []types.Campaign{
+				{Title: "Private", Message: "For users"},
+				{Title: "Groups", Message: "For groups", ChatType: types.ChatTypeGroup},
+				{Title: "Channels", Message: "For channels", ChatType: types.ChatTypeChannel},
+			} {
+				campaign.BotID = chatsBot.ID
+				campaign.Active = true
+				_, err := campaignDao.Create(&campaign)
+				assert.NilError(t, err)
+			}
+			assert.DeepEqual(t, sentTo("For users"), []int64{301})
+			assert.DeepEqual(t, sentTo("For groups"), []int64{-201})
+			assert.DeepEqual(t, sentTo("For channels"), []int64{-1001})
+
+			//The group becomes a supergroup before it gets the next campaign
+			fake.Fail(-201, telegramtest.Migrated(-1003))
+			campaign, err := campaignDao.Create(&types.Campaign{
+				BotID:    chatsBot.ID,
+				Title:    "All",
+				Message:  "For all chats",
+				Active:   true,
+				ChatType: types.ChatTypeAll,
+				Buttons:  []types.CampaignButton{{Text: "Like"}},
+			})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, sentTo("For all chats"), []int64{-1003, -1001, 301})
+
+			assert.NilError(t, ingester.Ingest(chatsBot, &telegram.Update{Message: &telegram.Message{
+				MessageID:       2,
+				From:            admin,
+				Chat:            &telegram.Chat{ID: -201, Type: "group", Title: "Group"},
+				MigrateToChatID: -1003,
+			}}))
+			group, err = userDao.Get(chatsBot.ID, -201)
+			assert.NilError(t, err)
+			assert.Assert(t, group == nil)
+			supergroup, err := userDao.Get(chatsBot.ID, -1003)
+			assert.NilError(t, err)
+			assert.Equal(t, supergroup.DisplayName, "Group")
+			assert.Equal(t, supergroup.ChatType, types.ChatTypeSupergroup)
+
+			//A member of the supergroup presses the button, the click is counted for the migrated delivery
+			assert.NilError(t, ingester.Ingest(chatsBot, &telegram.Update{
+				CallbackQuery: &telegram.CallbackQuery{
+					ID:      "chats-query",
+					From:    admin,
+					Message: &telegram.Message{MessageID: 3, Chat: &telegram.Chat{ID: -1003, Type: "supergroup"}},
+					Data:    types.EncodeCallbackData(campaign.ID, 0, 0),
+				},
+			}))
+			stat, err := campaignDao.GetAggregatedStatistics(chatsBot.ID, campaign.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, stat.Delivered, int64(3))
+			assert.Equal(t, stat.Clickers, int64(1))
+		})
+
+		t.Run("send campaigns in the languages of users", func(t *testing.T) {
+			fake.AddBot("languages:token", telegram.User{FirstName: "Languages bot", UserName: "languages_bot"})
+			languagesBot, err := botDao.Create(&types.Bot{
+				Title: "Languages bot",
+				Token: "languages:token",
+			})
+			assert.NilError(t, err)
+
+			languages := map[int64]string{401: "de", 402: "pt-br", 403: "pt", 404: "fr", 405: ""}
+			for telegramID, languageCode := range languages {
+				assert.NilError(t, ingester.Ingest(languagesBot, &telegram.Update{Message: &telegram.Message{
+					MessageID: telegramID,
+					From:      &telegram.User{ID: telegramID, FirstName: "Reader", LanguageCode: languageCode},
+					Chat:      &telegram.Chat{ID: telegramID, Type: "private"},
+					Text:      "/start",
+				}}))
+			}
+			user, err := userDao.Get(languagesBot.ID, 402)
+			assert.NilError(t, err)
+			assert.Equal(t, user.LanguageCode, "pt-br")
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "languages-sender-worker",
+			})
+			sentTexts := func() map[int64]string {
+				for i := 0; i < 4; i++ {
+					_, err := s.RunOnce(context.Background())
+					assert.NilError(t, err)
+				}
+				texts := map[int64]string{}
+				for _, sent := range fake.Sent() {
+					if sent.Token == languagesBot.Token {
+						texts[sent.ChatID] = sent.Text
+					}
+				}
+				return texts
+			}
+
+			campaign, err := campaignDao.Create(&types.Campaign{
+				BotID:   languagesBot.ID,
+				Title:   "Greeting",
+				Message: "Hello",
+				Active:  true,
+				Buttons: []types.CampaignButton{{Text: "Like"}},
+				Localizations: []types.CampaignLocalization{
+					{Language: "de", Message: "Hallo"},
+					{Language: "pt", Message: "Olá"},
+					{Language: "pt-BR", Message: "Oi"},
+				},
+			})
+			assert.NilError(t, err)
+			assert.Equal(t, len(campaign.Localizations), 3)
+			assert.DeepEqual(t, sentTexts(), map[int64]string{
+				401: "Hallo",
+				402: "Oi",
+				403: "Olá",
+				404: "Hello",
+				405: "Hello",
+			})
+
+			for _, telegramID := range []int64{401, 404} {
+				assert.NilError(t, ingester.Ingest(languagesBot, &telegram.Update{
+					CallbackQuery: &telegram.CallbackQuery{
+						ID:   fmt.Sprintf("languages-query-%d", telegramID),
+						From: &telegram.User{ID: telegramID, FirstName: "Reader"},
+						Data: types.EncodeCallbackData(campaign.ID, 0, 0),
+					},
+				}))
+			}
+			stat, err := campaignDao.GetLanguageStatistics(languagesBot.ID, campaign.ID)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, stat, []types.CampaignLanguageStatistics{
+				{Language: "", Delivered: 2, Clickers: 1, ClickThroughRate: 0.5},
+				{Language: "de", Delivered: 1, Clickers: 1, ClickThroughRate: 1},
+				{Language: "pt", Delivered: 1},
+				{Language: "pt-br", Delivered: 1},
+			})
+
+			//An edit replaces the default message only
+			_, err = campaignDao.SetState(languagesBot.ID, campaign.ID, types.CampaignStatePaused)
+			assert.NilError(t, err)
+			_, err = operationDao.Edit(languagesBot.ID, campaign.ID, "Hello again")
+			assert.NilError(t, err)
+			assert.DeepEqual(t, sentTexts(), map[int64]string{
+				401: "Hallo",
+				402: "Oi",
+				403: "Olá",
+				404: "Hello again",
+				405: "Hello again",
+			})
+		})
+
+		t.Run("send photos from the media library", func(t *testing.T) {
+			photo := []byte("\x89PNG\r\n\x1a\nphoto")
+			asset, err := mediaDao.Create(&types.MediaAsset{FileName: "photo.png"}, photo)
+			assert.NilError(t, err)
+			assert.Equal(t, asset.ContentType, "image/png")
+			assert.Equal(t, asset.Size, int64(len(photo)))
+			content, err := mediaDao.GetContent(asset.ID)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, content, photo)
+			_, err = mediaDao.Create(&types.MediaAsset{FileName: "empty.png"}, nil)
+			assert.Assert(t, err != nil)
+
+			photoBots := []*types.Bot{}
+			for _, token := range []string{"photos:token", "photos2:token"} {
+				fake.AddBot(token, telegram.User{FirstName: "Photos bot"})
+				photoBot, err := botDao.Create(&types.Bot{Title: "Photos bot", Token: token})
+				assert.NilError(t, err)
+				for _, telegramID := range []int64{501, 502} {
+					assert.NilError(t, ingester.Ingest(photoBot, &telegram.Update{Message: &telegram.Message{
+						MessageID: telegramID,
+						From:      &telegram.User{ID: telegramID, FirstName: "Viewer"},
+						Chat:      &telegram.Chat{ID: telegramID, Type: "private"},
+						Text:      "/start",
+					}}))
+				}
+				photoBots = append(photoBots, photoBot)
+			}
+
+			_, err = campaignDao.Create(&types.Campaign{
+				BotID:   photoBots[0].ID,
+				Title:   "Poll with a photo",
+				Message: "Yes?",
+				Kind:    types.CampaignKindPoll,
+				Options: []string{"Yes", "No"},
+				MediaID: asset.ID,
+			})
+			assert.Error(t, err, "Polls cannot have media")
+			_, err = campaignDao.Create(&types.Campaign{
+				BotID:   photoBots[0].ID,
+				Title:   "Missing photo",
+				Message: "Look",
+				MediaID: asset.ID + 1000,
+			})
+			assert.Error(t, err, "Media not found")
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "photos-sender-worker",
+			})
+			sentPhotos := func(bot *types.Bot) []telegramtest.Sent {
+				for i := 0; i < 4; i++ {
+					_, err := s.RunOnce(context.Background())
+					assert.NilError(t, err)
+				}
+				photos := []telegramtest.Sent{}
+				for _, sent := range fake.Sent() {
+					if sent.Token == bot.Token {
+						photos = append(photos, sent)
+					}
+				}
+				return photos
+			}
+
+			campaigns := []*types.Campaign{}
+			for _, photoBot := range photoBots {
+				campaign, err := campaignDao.Create(&types.Campaign{
+					BotID:   photoBot.ID,
+					Title:   "Photo",
+					Message: "Look at this",
+					Active:  true,
+					MediaID: asset.ID,
+				})
+				assert.NilError(t, err)
+				campaigns = append(campaigns, campaign)
+			}
+
+			//Each bot uploads the photo once, then sends it by file_id
+			for _, photoBot := range photoBots {
+				photos := sentPhotos(photoBot)
+				assert.Equal(t, len(photos), 2)
+				assert.DeepEqual(t, photos[0].Upload, photo)
+				assert.Equal(t, photos[0].Caption, "Look at this")
+				assert.Equal(t, photos[1].Photo, fmt.Sprintf("photo-%d", photos[0].MessageID))
+				assert.Equal(t, len(photos[1].Upload), 0)
+			}
+
+			//Deliveries of new campaigns carry the file_id reported by the sender
+			s = sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "photos-sender-worker-2",
+			})
+			_, err = campaignDao.Create(&types.Campaign{
+				BotID:   photoBots[0].ID,
+				Title:   "Photo again",
+				Message: "Look again",
+				Active:  true,
+				MediaID: asset.ID,
+			})
+			assert.NilError(t, err)
+			photos := sentPhotos(photoBots[0])
+			assert.Equal(t, len(photos), 4)
+			for _, sent := range photos[2:] {
+				assert.Equal(t, sent.Photo, photos[1].Photo)
+				assert.Equal(t, sent.Caption, "Look again")
+			}
+
+			//An edit changes the caption
+			_, err = campaignDao.SetState(photoBots[0].ID, campaigns[0].ID, types.CampaignStatePaused)
+			assert.NilError(t, err)
+			_, err = operationDao.Edit(photoBots[0].ID, campaigns[0].ID, "Look closer")
+			assert.NilError(t, err)
+			for _, sent := range sentPhotos(photoBots[0])[:2] {
+				assert.Assert(t, sent.Edited)
+				assert.Equal(t, sent.Caption, "Look closer")
+			}
+		})
+
+		t.Run("validate and preview formatted messages", func(t *testing.T) {
+			fake.AddBot("formatted:token", telegram.User{FirstName: "Formatted bot"})
+			formattedBot, err := botDao.Create(&types.Bot{Title: "Formatted bot", Token: "formatted:token"})
+			assert.NilError(t, err)
+			assert.NilError(t, ingester.Ingest(formattedBot, &telegram.Update{Message: &telegram.Message{
+				MessageID: 601,
+				From:      &telegram.User{ID: 601, FirstName: "Reader", LanguageCode: "de"},
+				Chat:      &telegram.Chat{ID: 601, Type: "private"},
+				Text:      "/start",
+			}}))
+
+			invalidCampaigns := map[string]*types.Campaign{
+				"Unclosed tag <b>":                  {Message: "<b>Sale", ParseMode: "HTML"},
+				"Unsupported tag <div>":             {Message: "<div>Sale</div>", ParseMode: "HTML"},
+				"Unescaped '&'":                     {Message: "Salt & pepper", ParseMode: "HTML"},
+				"Character '.' at character 4":      {Message: "Sale. *Today*", ParseMode: "MarkdownV2"},
+				"Unclosed entity '_'":               {Message: "_Sale", ParseMode: "MarkdownV2"},
+				"Message is too long: 4097":         {Message: strings.Repeat("a", 4097)},
+				"Localization de: Unclosed tag <i>": {Message: "Sale", ParseMode: "HTML", Localizations: []types.CampaignLocalization{{Language: "de", Message: "<i>Rabatt"}}},
+				"only http, https and tg links":     {Message: "Sale", Buttons: []types.CampaignButton{{Text: "Shop", URL: "ftp://example.com"}}},
+				"Polls cannot have a parse mode":    {Message: "Yes?", ParseMode: "HTML", Kind: types.CampaignKindPoll, Options: []string{"Yes", "No"}},
+			}
+			for expectedErr, campaign := range invalidCampaigns {
+				campaign.BotID = formattedBot.ID
+				campaign.Title = "Invalid"
+				_, err := campaignDao.Create(campaign)
+				assert.ErrorContains(t, err, expectedErr)
+			}
+
+			//Markup does not count towards the limit
+			campaign, err := campaignDao.Create(&types.Campaign{
+				BotID:     formattedBot.ID,
+				Title:     "Formatted",
+				Message:   "<b>Sale</b> &amp; <a href=\"https://example.com\">" + strings.Repeat("a", 4000) + "</a>",
+				ParseMode: "HTML",
+				Active:    true,
+				Buttons:   []types.CampaignButton{{Text: "Shop", URL: "https://example.com/shop"}, {Text: "Like"}},
+				Localizations: []types.CampaignLocalization{
+					{Language: "de", Message: "<b>Rabatt</b>"},
+				},
+			})
+			assert.NilError(t, err)
+			_, err = campaignDao.Update(&types.Campaign{
+				ID:        campaign.ID,
+				BotID:     formattedBot.ID,
+				Title:     "Formatted",
+				Message:   "<b>Sale",
+				ParseMode: "HTML",
+			})
+			assert.ErrorContains(t, err, "Unclosed tag <b>")
+
+			preview := func(telegramID int64, campaign *types.Campaign) (*types.CampaignPreview, int) {
+				resultWrapper := &struct{ Data *types.CampaignPreview }{}
+				res, err := restyClient.R().
+					SetResult(resultWrapper).
+					SetQueryParam("TelegramID", strconv.FormatInt(telegramID, 10)).
+					SetBody(campaign).
+					Post(fmt.Sprintf("/bot/%d/campaign-preview", formattedBot.ID))
+				assert.NilError(t, err)
+				return resultWrapper.Data, res.StatusCode()
+			}
+			result, status := preview(601, campaign)
+			assert.Equal(t, status, http.StatusOK)
+			assert.DeepEqual(t, result, &types.CampaignPreview{
+				Language:  "de",
+				Text:      "<b>Rabatt</b>",
+				ParseMode: "HTML",
+				PlainText: "Rabatt",
+				Length:    6,
+				MaxLength: 4096,
+				Buttons:   campaign.Buttons,
+			})
+			result, status = preview(0, &types.Campaign{Title: "Draft", Message: "*Hi* 👋", ParseMode: "MarkdownV2", MediaID: 1})
+			assert.Equal(t, status, http.StatusOK)
+			assert.Equal(t, result.PlainText, "Hi 👋")
+			assert.Equal(t, result.Length, 5)
+			assert.Equal(t, result.MaxLength, 1024)
+			_, status = preview(0, &types.Campaign{Title: "Draft", Message: "Hi!", ParseMode: "MarkdownV2"})
+			assert.Equal(t, status, http.StatusBadRequest)
+			_, status = preview(699, campaign)
+			assert.Equal(t, status, http.StatusNotFound)
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "formatted-sender-worker",
+			})
+			sentMessages := func() []telegramtest.Sent {
+				for i := 0; i < 4; i++ {
+					_, err := s.RunOnce(context.Background())
+					assert.NilError(t, err)
+				}
+				messages := []telegramtest.Sent{}
+				for _, sent := range fake.Sent() {
+					if sent.Token == formattedBot.Token {
+						messages = append(messages, sent)
+					}
+				}
+				return messages
+			}
+			messages := sentMessages()
+			assert.Equal(t, len(messages), 1)
+			assert.Equal(t, messages[0].Text, "<b>Rabatt</b>")
+			assert.Equal(t, messages[0].ParseMode, "HTML")
+			assert.DeepEqual(t, messages[0].ReplyMarkup.InlineKeyboard[0], []telegram.InlineKeyboardButton{
+				{Text: "Shop", URL: "https://example.com/shop"},
+				{Text: "Like", CallbackData: types.EncodeCallbackData(campaign.ID, 0, 1)},
+			})
+
+			_, err = operationDao.Edit(formattedBot.ID, campaign.ID, "<b>Sale</i>")
+			assert.ErrorContains(t, err, "Unexpected closing tag </i>")
+		})
+
+		t.Run("test-send campaigns to testers", func(t *testing.T) {
+			fake.AddBot("testers:token", telegram.User{FirstName: "Testers bot"})
+			testersBot, err := botDao.Create(&types.Bot{Title: "Testers bot", Token: "testers:token"})
+			assert.NilError(t, err)
+			languages := map[int64]string{701: "de", 702: "en", 703: "en"}
+			for telegramID, languageCode := range languages {
+				assert.NilError(t, ingester.Ingest(testersBot, &telegram.Update{Message: &telegram.Message{
+					MessageID: telegramID,
+					From:      &telegram.User{ID: telegramID, FirstName: "Tester", LanguageCode: languageCode},
+					Chat:      &telegram.Chat{ID: telegramID, Type: "private"},
+					Text:      "/start",
+				}}))
+			}
+
+			campaign, err := campaignDao.Create(&types.Campaign{
+				BotID:         testersBot.ID,
+				Title:         "Draft",
+				Message:       "Hello",
+				Buttons:       []types.CampaignButton{{Text: "Like"}},
+				Localizations: []types.CampaignLocalization{{Language: "de", Message: "Hallo"}},
+			})
+			assert.NilError(t, err)
+			_, err = operationDao.Test(testersBot.ID, campaign.ID)
+			assert.ErrorContains(t, err, "The bot has no testers")
+			res, err := restyClient.R().
+				SetPathParams(map[string]string{
+					"BotID":      strconv.FormatInt(testersBot.ID, 10),
+					"CampaignID": strconv.FormatInt(campaign.ID, 10),
+				}).
+				Post("/bot/{BotID}/campaign/{CampaignID}/test")
+			assert.NilError(t, err)
+			assert.Equal(t, res.StatusCode(), http.StatusConflict)
+
+			_, err = testerDao.Add(testersBot.ID, 799)
+			assert.ErrorContains(t, err, "User not found")
+			res, err = restyClient.R().
+				SetPathParams(map[string]string{
+					"BotID":      strconv.FormatInt(testersBot.ID, 10),
+					"TelegramID": "799",
+				}).
+				Put("/bot/{BotID}/tester/{TelegramID}")
+			assert.NilError(t, err)
+			assert.Equal(t, res.StatusCode(), http.StatusNotFound)
+			for _, telegramID := range []int64{701, 702, 701} {
+				tester, err := testerDao.Add(testersBot.ID, telegramID)
+				assert.NilError(t, err)
+				assert.Equal(t, tester.TelegramID, telegramID)
+			}
+			testers, err := testerDao.List(testersBot.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, len(testers), 2)
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "testers-sender-worker",
+			})
+			sentTexts := func() map[int64][]string {
+				for i := 0; i < 4; i++ {
+					_, err := s.RunOnce(context.Background())
+					assert.NilError(t, err)
+				}
+				texts := map[int64][]string{}
+				for _, sent := range fake.Sent() {
+					if sent.Token == testersBot.Token {
+						texts[sent.ChatID] = append(texts[sent.ChatID], sent.Text)
+					}
+				}
+				return texts
+			}
+
+			operation, err := operationDao.Test(testersBot.ID, campaign.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, operation.Kind, types.CampaignOperationTest)
+			assert.Equal(t, operation.Queued, int64(2))
+			assert.DeepEqual(t, sentTexts(), map[int64][]string{
+				701: {"Hallo"},
+				702: {"Hello"},
+			})
+			operation, err = operationDao.Get(testersBot.ID, campaign.ID, operation.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, operation.Success, int64(2))
+
+			//Test sends are not deliveries
+			stat, err := campaignDao.GetAggregatedStatistics(testersBot.ID, campaign.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, stat.Delivered, int64(0))
+			assert.NilError(t, ingester.Ingest(testersBot, &telegram.Update{
+				CallbackQuery: &telegram.CallbackQuery{
+					ID:   "testers-query",
+					From: &telegram.User{ID: 701, FirstName: "Tester"},
+					Data: types.EncodeCallbackData(campaign.ID, 0, 0),
+				},
+			}))
+
+			assert.NilError(t, testerDao.Remove(testersBot.ID, 702))
+			testers, err = testerDao.List(testersBot.ID)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, testers, []types.Tester{{BotID: testersBot.ID, TelegramID: 701, CreatedAt: testers[0].CreatedAt}})
+
+			//Testers get the real broadcast too
+			campaign.Active = true
+			_, err = campaignDao.Update(campaign)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, sentTexts(), map[int64][]string{
+				701: {"Hallo", "Hallo"},
+				702: {"Hello", "Hello"},
+				703: {"Hello"},
+			})
+			stat, err = campaignDao.GetAggregatedStatistics(testersBot.ID, campaign.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, stat.Delivered, int64(3))
+			assert.Equal(t, stat.Clicks, int64(0))
+		})
+
+		t.Run("move campaigns through the approval workflow", func(t *testing.T) {
+			fake.AddBot("approval:token", telegram.User{FirstName: "Approval bot"})
+			approvalBot, err := botDao.Create(&types.Bot{Title: "Approval bot", Token: "approval:token", RequireApproval: true})
+			assert.NilError(t, err)
+			assert.Assert(t, approvalBot.RequireApproval)
+			assert.NilError(t, ingester.Ingest(approvalBot, &telegram.Update{Message: &telegram.Message{
+				MessageID: 1,
+				From:      &telegram.User{ID: 801, FirstName: "Reader"},
+				Chat:      &telegram.Chat{ID: 801, Type: "private"},
+				Text:      "/start",
+			}}))
+
+			_, err = campaignDao.Create(&types.Campaign{BotID: approvalBot.ID, Title: "Rushed", Message: "Hello", Active: true})
+			assert.ErrorContains(t, err, "Campaigns of this bot must be approved before they run")
+			campaign, err := campaignDao.Create(&types.Campaign{BotID: approvalBot.ID, Title: "News", Message: "Hello"})
+			assert.NilError(t, err)
+			assert.Equal(t, campaign.State, types.CampaignStateDraft)
+			assert.Assert(t, !campaign.Active)
+
+			_, err = campaignDao.SetState(approvalBot.ID, campaign.ID, types.CampaignStateRunning)
+			assert.ErrorContains(t, err, "Campaigns of this bot must be approved before they run")
+			_, err = campaignDao.Approve(approvalBot.ID, campaign.ID, "editor@example.com")
+			assert.ErrorContains(t, err, "Only campaigns pending approval can be approved, the campaign is draft")
+			campaignParams := map[string]string{
+				"BotID":      strconv.FormatInt(approvalBot.ID, 10),
+				"CampaignID": strconv.FormatInt(campaign.ID, 10),
+			}
+			res, err := restyClient.R().
+				SetPathParams(campaignParams).
+				SetBody(map[string]string{"State": types.CampaignStateRunning}).
+				Put("/bot/{BotID}/campaign/{CampaignID}/state")
+			assert.NilError(t, err)
+			assert.Equal(t, res.StatusCode(), http.StatusConflict)
+			res, err = restyClient.R().
+				SetPathParams(campaignParams).
+				SetBody(map[string]string{"ApprovedBy": "editor@example.com"}).
+				Post("/bot/{BotID}/campaign/{CampaignID}/approve")
+			assert.NilError(t, err)
+			assert.Equal(t, res.StatusCode(), http.StatusConflict)
+			campaign, err = campaignDao.SetState(approvalBot.ID, campaign.ID, types.CampaignStatePendingApproval)
+			assert.NilError(t, err)
+			assert.Equal(t, campaign.State, types.CampaignStatePendingApproval)
+
+			_, err = campaignDao.Approve(approvalBot.ID, campaign.ID, "")
+			assert.ErrorContains(t, err, "ApprovedBy")
+			campaign, err = campaignDao.Approve(approvalBot.ID, campaign.ID, "editor@example.com")
+			assert.NilError(t, err)
+			assert.Equal(t, campaign.State, types.CampaignStateApproved)
+			assert.Equal(t, campaign.ApprovedBy, "editor@example.com")
+			assert.Assert(t, !campaign.ApprovedAt.IsZero())
+
+			//A new message needs a new approval
+			campaign.Message = "Hello, world"
+			campaign, err = campaignDao.Update(campaign)
+			assert.NilError(t, err)
+			assert.Equal(t, campaign.State, types.CampaignStateDraft)
+			assert.Equal(t, campaign.ApprovedBy, "")
+			_, err = campaignDao.SetState(approvalBot.ID, campaign.ID, types.CampaignStatePendingApproval)
+			assert.NilError(t, err)
+			_, err = campaignDao.Approve(approvalBot.ID, campaign.ID, "chief@example.com")
+			assert.NilError(t, err)
+
+			//An edit needs a new approval as well
+			_, err = operationDao.Edit(approvalBot.ID, campaign.ID, "Hello, edited world")
+			assert.NilError(t, err)
+			campaign, err = campaignDao.Get(approvalBot.ID, campaign.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, campaign.State, types.CampaignStateDraft)
+			assert.Equal(t, campaign.ApprovedBy, "")
+			assert.Assert(t, campaign.ApprovedAt.IsZero())
+			_, err = campaignDao.SetState(approvalBot.ID, campaign.ID, types.CampaignStatePendingApproval)
+			assert.NilError(t, err)
+			_, err = campaignDao.Approve(approvalBot.ID, campaign.ID, "chief@example.com")
+			assert.NilError(t, err)
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "approval-sender-worker",
+			})
+			sentTexts := func() []string {
+				for i := 0; i < 4; i++ {
+					_, err := s.RunOnce(context.Background())
+					assert.NilError(t, err)
+				}
+				texts := []string{}
+				for _, sent := range fake.Sent() {
+					if sent.Token == approvalBot.Token {
+						texts = append(texts, sent.Text)
+					}
+				}
+				return texts
+			}
+			assert.DeepEqual(t, sentTexts(), []string{})
+
+			campaign, err = campaignDao.SetState(approvalBot.ID, campaign.ID, types.CampaignStateRunning)
+			assert.NilError(t, err)
+			assert.Assert(t, campaign.Active)
+			assert.Equal(t, campaign.ApprovedBy, "chief@example.com")
+			assert.DeepEqual(t, sentTexts(), []string{"Hello, edited world"})
+
+			campaign.Message = "Goodbye"
+			_, err = campaignDao.Update(campaign)
+			assert.ErrorContains(t, err, "The message of a running campaign cannot be changed, pause it first")
+
+			//Active still pauses and resumes a campaign
+			campaign.Message = "Hello, edited world"
+			campaign.Active = false
+			campaign, err = campaignDao.Update(campaign)
+			assert.NilError(t, err)
+			assert.Equal(t, campaign.State, types.CampaignStatePaused)
+
+			_, err = campaignDao.SetState(approvalBot.ID, campaign.ID, types.CampaignStateArchived)
+			assert.ErrorContains(t, err, "A campaign cannot go from paused to archived")
+			campaign, err = campaignDao.SetState(approvalBot.ID, campaign.ID, types.CampaignStateCompleted)
+			assert.NilError(t, err)
+			_, err = campaignDao.SetState(approvalBot.ID, campaign.ID, types.CampaignStateRunning)
+			assert.ErrorContains(t, err, "A campaign cannot go from completed to running")
+			campaign, err = campaignDao.SetState(approvalBot.ID, campaign.ID, types.CampaignStateArchived)
+			assert.NilError(t, err)
+			assert.Equal(t, campaign.State, types.CampaignStateArchived)
+
+			campaign.Title = "Old news"
+			_, err = campaignDao.Update(campaign)
+			assert.ErrorContains(t, err, "Archived campaigns cannot be changed")
+			_, err = operationDao.Edit(approvalBot.ID, campaign.ID, "Old news")
+			assert.ErrorContains(t, err, "Archived campaigns cannot be changed")
+		})
+
+		t.Run("keep revisions of campaigns", func(t *testing.T) {
+			fake.AddBot("revisions:token", telegram.User{FirstName: "Revisions bot"})
+			revisionsBot, err := botDao.Create(&types.Bot{Title: "Revisions bot", Token: "revisions:token"})
+			assert.NilError(t, err)
+			for _, telegramID := range []int64{901, 902} {
+				assert.NilError(t, ingester.Ingest(revisionsBot, &telegram.Update{Message: &telegram.Message{
+					MessageID: telegramID,
+					From:      &telegram.User{ID: telegramID, FirstName: "Reader"},
+					Chat:      &telegram.Chat{ID: telegramID, Type: "private"},
+					Text:      "/start",
+				}}))
+			}
+
+			campaign, err := campaignDao.Create(&types.Campaign{
+				BotID:   revisionsBot.ID,
+				Title:   "Sale",
+				Message: "Big sale\nEverything -10%",
+			})
+			assert.NilError(t, err)
+			assert.Equal(t, campaign.Revision, int64(1))
+
+			//Only the title and the message make revisions
+			campaign.Title = "Summer sale"
+			campaign.Message = "Big sale\nEverything -20%"
+			campaign.Localizations = []types.CampaignLocalization{{Language: "de", Message: "Ausverkauf"}}
+			campaign, err = campaignDao.Update(campaign)
+			assert.NilError(t, err)
+			assert.Equal(t, campaign.Revision, int64(2))
+			campaign.ChatType = types.ChatTypeAll
+			campaign, err = campaignDao.Update(campaign)
+			assert.NilError(t, err)
+			assert.Equal(t, campaign.Revision, int64(2))
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "revisions-sender-worker",
+			})
+			runSender := func() {
+				for i := 0; i < 4; i++ {
+					_, err := s.RunOnce(context.Background())
+					assert.NilError(t, err)
+				}
+			}
+			campaign, err = campaignDao.SetState(revisionsBot.ID, campaign.ID, types.CampaignStateRunning)
+			assert.NilError(t, err)
+			runSender()
+			received, err := campaignDao.GetDeliveredRevision(revisionsBot.ID, campaign.ID, 901)
+			assert.NilError(t, err)
+			assert.Equal(t, received.Revision, int64(2))
+			assert.Equal(t, received.Message, "Big sale\nEverything -20%")
+
+			_, err = campaignDao.SetState(revisionsBot.ID, campaign.ID, types.CampaignStatePaused)
+			assert.NilError(t, err)
+			reported := database.Delivery{}
+			assert.NilError(t, db.Where("campaign_id = ? AND telegram_id = ?", campaign.ID, 902).First(&reported).Error)
+			edit, err := operationDao.Edit(revisionsBot.ID, campaign.ID, "Big sale\nEverything -30%\nOnly today")
+			assert.NilError(t, err)
+			assert.Equal(t, edit.Revision, int64(3))
+			runSender()
+			received, err = campaignDao.GetDeliveredRevision(revisionsBot.ID, campaign.ID, 902)
+			assert.NilError(t, err)
+			assert.Equal(t, received.Revision, int64(3))
+			//Reaching the user with an edit is not a report of the delivery
+			edited := database.Delivery{}
+			assert.NilError(t, db.Where("campaign_id = ? AND telegram_id = ?", campaign.ID, 902).First(&edited).Error)
+			assert.Assert(t, edited.UpdatedAt.Equal(reported.UpdatedAt))
+
+			revisions, err := campaignDao.ListRevisions(revisionsBot.ID, campaign.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, len(revisions), 3)
+			assert.Equal(t, revisions[0].Title, "Sale")
+			assert.Equal(t, revisions[0].Message, "Big sale\nEverything -10%")
+			assert.Equal(t, revisions[2].Title, "Summer sale")
+			revision, err := campaignDao.GetRevision(revisionsBot.ID, campaign.ID, 3)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, revision, &revisions[2])
+
+			diff, err := campaignDao.DiffRevisions(revisionsBot.ID, campaign.ID, 1, 3)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, diff, &types.CampaignRevisionDiff{
+				CampaignID: campaign.ID,
+				From:       1,
+				To:         3,
+				Changes: []types.CampaignRevisionChange{
+					{Field: "Title", From: "Sale", To: "Summer sale"},
+					{
+						Field: "Message",
+						From:  "Big sale\nEverything -10%",
+						To:    "Big sale\nEverything -30%\nOnly today",
+						Lines: []types.DiffLine{
+							{Op: "=", Text: "Big sale"},
+							{Op: "-", Text: "Everything -10%"},
+							{Op: "+", Text: "Everything -30%"},
+							{Op: "+", Text: "Only today"},
+						},
+					},
+					{
+						Field: "Localizations.de",
+						From:  "",
+						To:    "Ausverkauf",
+						Lines: []types.DiffLine{{Op: "+", Text: "Ausverkauf"}},
+					},
+				},
+			})
+			_, err = campaignDao.DiffRevisions(revisionsBot.ID, campaign.ID, 1, 4)
+			assert.ErrorContains(t, err, "Revision not found")
+			campaignParams := map[string]string{
+				"BotID":      strconv.FormatInt(revisionsBot.ID, 10),
+				"CampaignID": strconv.FormatInt(campaign.ID, 10),
+			}
+			res, err := restyClient.R().
+				SetPathParams(campaignParams).
+				SetQueryParam("From", "4").
+				Get("/bot/{BotID}/campaign/{CampaignID}/revision-diff")
+			assert.NilError(t, err)
+			assert.Equal(t, res.StatusCode(), http.StatusNotFound)
+			res, err = restyClient.R().
+				SetPathParams(campaignParams).
+				Get("/bot/{BotID}/campaign/{CampaignID}/revision/4")
+			assert.NilError(t, err)
+			assert.Equal(t, res.StatusCode(), http.StatusNotFound)
+		})
+
+		//serveFirst makes round-robin pick a bot before all the others
+		serveFirst := func(botID int64) {
+			assert.NilError(t, db.Table("bots").Where("id <> ?", botID).Update("rr_access_time", time.Now()).Error)
+			assert.NilError(t, db.Table("bots").Where("id = ?", botID).Update("rr_access_time", time.Time{}).Error)
+		}
+
+		t.Run("stop sending for a bot whose token is revoked", func(t *testing.T) {
+			fake.AddBot("expiring:token", telegram.User{FirstName: "Expiring bot"})
+			expiringBot, err := botDao.Create(&types.Bot{Title: "Expiring bot", Token: "expiring:token"})
+			assert.NilError(t, err)
+			for telegramID := int64(1001); telegramID <= 1003; telegramID++ {
+				_, err := userDao.Put(&types.User{TelegramID: telegramID, BotID: expiringBot.ID})
+				assert.NilError(t, err)
+			}
+			campaign, err := campaignDao.Create(&types.Campaign{
+				BotID:   expiringBot.ID,
+				Title:   "Campaign of an expiring bot",
+				Message: "Message of an expiring bot",
+				Active:  true,
+			})
+			assert.NilError(t, err)
+			fake.RemoveBot("expiring:token")
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "expiring-sender-worker",
+			})
+			serveFirst(expiringBot.ID)
+			processed, err := s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.Equal(t, processed, 0)
+
+			stored, err := botDao.Get(expiringBot.ID)
+			assert.NilError(t, err)
+			assert.Assert(t, stored.TokenRevoked)
+			var count int64
+			assert.NilError(t, db.Model(&database.Delivery{}).Where("campaign_id = ?", campaign.ID).Count(&count).Error)
+			assert.Equal(t, count, int64(0))
+			assert.NilError(t, db.Model(&database.QueuedRecipient{}).Where("campaign_id = ?", campaign.ID).Count(&count).Error)
+			assert.Equal(t, count, int64(3))
+
+			//A revoked bot is skipped by round-robin
+			serveFirst(expiringBot.ID)
+			next, err := botDao.RRTake()
+			assert.NilError(t, err)
+			assert.Assert(t, next == nil || next.ID != expiringBot.ID)
+
+			//The campaign goes on once the bot is validated with a working token
+			fake.AddBot("expiring:token", telegram.User{FirstName: "Expiring bot"})
+			res, err := restyClient.R().
+				SetPathParams(map[string]string{
+					"BotID": strconv.FormatInt(expiringBot.ID, 10),
+				}).
+				Post("/bot/{BotID}/validate")
+			assert.NilError(t, err)
+			assert.Equal(t, res.StatusCode(), http.StatusOK)
+			serveFirst(expiringBot.ID)
+			processed, err = s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.Equal(t, processed, 3)
+			assert.NilError(t, db.Model(&database.Delivery{}).
+				Where("campaign_id = ? AND state = ?", campaign.ID, types.DeliveryStateSuccess).
+				Count(&count).Error)
+			assert.Equal(t, count, int64(3))
+		})
+
+		t.Run("report states again after a failed report", func(t *testing.T) {
+			fake.AddBot("reporting:token", telegram.User{FirstName: "Reporting bot"})
+			reportingBot, err := botDao.Create(&types.Bot{Title: "Reporting bot", Token: "reporting:token"})
+			assert.NilError(t, err)
+			for telegramID := int64(1101); telegramID <= 1102; telegramID++ {
+				_, err := userDao.Put(&types.User{TelegramID: telegramID, BotID: reportingBot.ID})
+				assert.NilError(t, err)
+			}
+			campaign, err := campaignDao.Create(&types.Campaign{
+				BotID:   reportingBot.ID,
+				Title:   "Reported campaign",
+				Message: "Reported message",
+				Active:  true,
+			})
+			assert.NilError(t, err)
+
+			unreliableDeliveryDao := &failingDeliveryDao{DeliveryDao: deliveryDao, failures: 3}
+			s := sender.NewSender(botDao, unreliableDeliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID:   "reporting-sender-worker",
+				MaxRetries: 1,
+				RetryDelay: 10 * time.Millisecond,
+			})
+			serveFirst(reportingBot.ID)
+			_, err = s.RunOnce(context.Background())
+			assert.ErrorContains(t, err, "Barker is unavailable")
+			var count int64
+			assert.NilError(t, db.Model(&database.Delivery{}).
+				Where("campaign_id = ? AND state = ?", campaign.ID, types.DeliveryStateProgress).
+				Count(&count).Error)
+			assert.Equal(t, count, int64(2))
+
+			//The states are stored before anything new is taken
+			_, err = s.RunOnce(context.Background())
+			assert.ErrorContains(t, err, "Barker is unavailable")
+			_, err = s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.NilError(t, db.Model(&database.Delivery{}).
+				Where("campaign_id = ? AND state = ?", campaign.ID, types.DeliveryStateSuccess).
+				Count(&count).Error)
+			assert.Equal(t, count, int64(2))
+
+			//Nothing is sent twice
+			sent := 0
+			for _, message := range fake.Sent() {
+				if message.Text == campaign.Message {
+					sent++
+				}
+			}
+			assert.Equal(t, sent, 2)
+		})
+
+		t.Run("keep message IDs when a state is reported again", func(t *testing.T) {
+			fake.AddBot("rereporting:token", telegram.User{FirstName: "Rereporting bot"})
+			rereportingBot, err := botDao.Create(&types.Bot{Title: "Rereporting bot", Token: "rereporting:token"})
+			assert.NilError(t, err)
+			for telegramID := int64(1201); telegramID <= 1202; telegramID++ {
+				_, err := userDao.Put(&types.User{TelegramID: telegramID, BotID: rereportingBot.ID})
+				assert.NilError(t, err)
+			}
+			_, err = campaignDao.Create(&types.Campaign{
+				BotID:   rereportingBot.ID,
+				Title:   "Rereported campaign",
+				Message: "Rereported message",
+				Active:  true,
+			})
+			assert.NilError(t, err)
+			batch, err := deliveryDao.TakeBatch("rereporting-worker", rereportingBot.ID, 2)
+			assert.NilError(t, err)
+			assert.Equal(t, len(batch), 2)
+			single := *batch[0].Delivery
+			bulk := *batch[1].Delivery
+
+			single.MessageID = 501
+			assert.NilError(t, deliveryDao.SetState(&single, types.DeliveryStateSuccess))
+			bulk.State = types.DeliveryStateSuccess
+			bulk.MessageID = 502
+			bulk.PollID = "poll-502"
+			assert.NilError(t, deliveryDao.SetStates([]types.Delivery{bulk}))
+
+			single.MessageID = 0
+			assert.NilError(t, deliveryDao.SetState(&single, types.DeliveryStateSuccess))
+			bulk.MessageID = 0
+			bulk.PollID = ""
+			assert.NilError(t, deliveryDao.SetStates([]types.Delivery{bulk}))
+
+			stored := database.Delivery{}
+			assert.NilError(t, db.Where("bot_id = ? AND telegram_id = ?", rereportingBot.ID, single.TelegramID).First(&stored).Error)
+			assert.Equal(t, stored.MessageID, int64(501))
+			stored = database.Delivery{}
+			assert.NilError(t, db.Where("bot_id = ? AND telegram_id = ?", rereportingBot.ID, bulk.TelegramID).First(&stored).Error)
+			assert.Equal(t, stored.MessageID, int64(502))
+			assert.Equal(t, stored.PollID, "poll-502")
+		})
+
+		t.Run("respond again to a message redelivered after a failed response", func(t *testing.T) {
+			fake.AddBot("responding:token", telegram.User{FirstName: "Responding bot"})
+			respondingBot, err := botDao.Create(&types.Bot{Title: "Responding bot", Token: "responding:token"})
+			assert.NilError(t, err)
+			_, err = autoResponderDao.Create(&types.AutoResponderRule{
+				BotID:   respondingBot.ID,
+				Match:   types.AutoResponderMatchKeyword,
+				Pattern: "refund",
+				Reply:   "Refunds take 3 days",
+				AddTag:  "refund",
+			})
+			assert.NilError(t, err)
+
+			unreliableIngester := ingest.NewIngester(
+				userDao, clickDao, answerDao,
+				&failingInboxDao{InboxDao: inboxDao, failures: 1},
+				autoResponderDao, sequenceDao, telegramClient,
+			)
+			update := &telegram.Update{Message: &telegram.Message{
+				MessageID: 3001,
+				From:      &telegram.User{ID: 1301, FirstName: "Responding user"},
+				Chat:      &telegram.Chat{ID: 1301, Type: "private"},
+				Text:      "I want a refund",
+			}}
+			assert.ErrorContains(t, unreliableIngester.Ingest(respondingBot, update), "Barker is unavailable")
+			//Telegram redelivers the update which has not been handled
+			assert.NilError(t, unreliableIngester.Ingest(respondingBot, update))
+			assert.NilError(t, unreliableIngester.Ingest(respondingBot, update))
+
+			messages, _, err := inboxDao.Conversation(respondingBot.ID, 1301, &types.PaginatorRequest{})
+			assert.NilError(t, err)
+			assert.Equal(t, len(messages), 2)
+			assert.Equal(t, messages[0].Direction, types.InboxDirectionOutgoing)
+			assert.Equal(t, messages[0].Text, "Refunds take 3 days")
+			assert.Equal(t, messages[1].Direction, types.InboxDirectionIncoming)
+
+			user, err := userDao.Get(respondingBot.ID, 1301)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, user.Tags, []string{"refund"})
+		})
+
+		t.Run("enroll users in sequences", func(t *testing.T) {
+			fake.AddBot("sequences:token", telegram.User{FirstName: "Sequences bot"})
+			sequencesBot, err := botDao.Create(&types.Bot{Title: "Sequences bot", Token: "sequences:token"})
+			assert.NilError(t, err)
+			for telegramID := int64(1401); telegramID <= 1402; telegramID++ {
+				_, err := userDao.Put(&types.User{TelegramID: telegramID, BotID: sequencesBot.ID})
+				assert.NilError(t, err)
+			}
+
+			_, err = sequenceDao.Create(&types.Sequence{BotID: sequencesBot.ID, Title: "Empty course"})
+			assert.ErrorContains(t, err, "Steps")
+			course, err := sequenceDao.Create(&types.Sequence{
+				BotID: sequencesBot.ID,
+				Title: "Course",
+				Steps: []types.SequenceStep{
+					{Message: "Lesson 1"},
+					{DelayMinutes: 60, Message: "Lesson 2"},
+				},
+			})
+			assert.NilError(t, err)
+			sequences, err := sequenceDao.List(sequencesBot.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, len(sequences), 1)
+			assert.DeepEqual(t, sequences[0].Steps, course.Steps)
+
+			_, err = autoResponderDao.Create(&types.AutoResponderRule{
+				BotID:            sequencesBot.ID,
+				Match:            types.AutoResponderMatchKeyword,
+				Pattern:          "course",
+				Reply:            "You are enrolled",
+				EnrollSequenceID: course.ID,
+			})
+			assert.NilError(t, err)
+			update := &telegram.Update{Message: &telegram.Message{
+				MessageID: 4001,
+				From:      &telegram.User{ID: 1401, FirstName: "Student"},
+				Chat:      &telegram.Chat{ID: 1401, Type: "private"},
+				Text:      "I want the course",
+			}}
+			assert.NilError(t, ingester.Ingest(sequencesBot, update))
+			//Telegram redelivers an update
+			assert.NilError(t, ingester.Ingest(sequencesBot, update))
+
+			s := sender.NewSender(botDao, deliveryDao, workerDao, operationDao, mediaDao, telegramClient, sender.Options{
+				WorkerID: "sequences-sender-worker",
+			})
+			sentBefore := len(fake.Sent())
+			serveFirst(sequencesBot.ID)
+			processed, err := s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.Equal(t, processed, 2)
+			texts := []string{}
+			for _, message := range fake.Sent()[sentBefore:] {
+				assert.Equal(t, message.ChatID, int64(1401))
+				texts = append(texts, message.Text)
+			}
+			assert.DeepEqual(t, texts, []string{"You are enrolled", "Lesson 1"})
+
+			//The second lesson waits for its delay
+			serveFirst(sequencesBot.ID)
+			processed, err = s.RunOnce(context.Background())
+			assert.NilError(t, err)
+			assert.Equal(t, processed, 0)
+			queued := int64(0)
+			assert.NilError(t, db.Model(&database.MessageJob{}).
+				Where("bot_id = ? AND kind = ? AND state = ?", sequencesBot.ID, types.MessageJobSequence, types.MessageJobStateQueued).
+				Count(&queued).Error)
+			assert.Equal(t, queued, int64(1))
+
+			enrolled, err := sequenceDao.Enroll(sequencesBot.ID, course.ID, 1402)
+			assert.NilError(t, err)
+			assert.Equal(t, enrolled.ID, course.ID)
+			_, err = userDao.SetUnsubscribed(sequencesBot.ID, 1402, true)
+			assert.NilError(t, err)
+			assert.NilError(t, db.Model(&database.MessageJob{}).
+				Where("bot_id = ? AND telegram_id = ? AND state = ?", sequencesBot.ID, 1402, types.MessageJobStateQueued).
+				Count(&queued).Error)
+			assert.Equal(t, queued, int64(0))
+
+			enrolled, err = sequenceDao.Enroll(sequencesBot.ID, course.ID, 1499)
+			assert.NilError(t, err)
+			assert.Assert(t, enrolled == nil)
+
+			assert.NilError(t, sequenceDao.Delete(sequencesBot.ID, course.ID))
+			assert.NilError(t, db.Model(&database.MessageJob{}).
+				Where("bot_id = ? AND state = ?", sequencesBot.ID, types.MessageJobStateQueued).
+				Count(&queued).Error)
+			assert.Equal(t, queued, int64(0))
+		})
+	})
+}
+
+// failingDeliveryDao fails to store delivery states a number of times, like an unreachable barker
+type failingDeliveryDao struct {
+	dao.DeliveryDao
+	failures int
+}
+
+func (dao *failingDeliveryDao) SetStates(deliveries []types.Delivery) error {
+	if dao.failures > 0 {
+		dao.failures--
+		return errors.New("Barker is unavailable")
+	}
+	return dao.DeliveryDao.SetStates(deliveries)
+}
+
+// failingInboxDao fails to queue automatic replies a number of times
+type failingInboxDao struct {
+	dao.InboxDao
+	failures int
+}
+
+func (dao *failingInboxDao) AutoReply(botID int64, telegramID int64, replyTo int64, text string) (*types.InboxMessage, error) {
+	if dao.failures > 0 {
+		dao.failures--
+		return nil, errors.New("Barker is unavailable")
+	}
+	return dao.InboxDao.AutoReply(botID, telegramID, replyTo, text)
+}
+
+func createIntegrationTestWebhookInvocation(t *testing.T) fx.Option {
+	return fx.Invoke(func(
+		r *gin.Engine,
+		botDao dao.BotDao,
+		userDao dao.UserDao,
+		campaignDao dao.CampaignDao,
+		deliveryDao dao.DeliveryDao,
+	) {
+		httpServer := httptest.NewServer(r)
+		defer httpServer.Close()
+		restyClient := resty.New().SetHostURL(httpServer.URL)
+
+		bot, err := botDao.Create(&types.Bot{
+			Title:         "Webhook bot",
+			Token:         "webhook:token",
+			WebhookSecret: "webhook-secret",
+		})
+		assert.NilError(t, err)
+		campaign, err := campaignDao.Create(&types.Campaign{
+			BotID:   bot.ID,
+			Title:   "Webhook campaign",
+			Message: "Webhook message",
+			Active:  true,
+		})
+		assert.NilError(t, err)
+
+		postUpdate := func(secret string, update *telegram.Update) int {
+			res, err := restyClient.R().
+				SetHeader("X-Telegram-Bot-Api-Secret-Token", secret).
+				SetBody(update).
+				SetPathParams(map[string]string{
+					"BotID": strconv.FormatInt(bot.ID, 10),
+				}).
+				Post("/telegram/{BotID}/webhook")
+			assert.NilError(t, err)
+			return res.StatusCode()
+		}
+		from := &telegram.User{ID: 8001, FirstName: "Webhook", LastName: "User", UserName: "webhook_user"}
+		privateChat := &telegram.Chat{ID: 8001, Type: "private"}
+
+		t.Run("reject a wrong secret token", func(t *testing.T) {
+			status := postUpdate("wrong", &telegram.Update{
+				UpdateID: 1,
+				Message:  &telegram.Message{MessageID: 1, From: from, Chat: privateChat, Text: "/start"},
+			})
+			assert.Equal(t, status, http.StatusForbidden)
+			user, err := userDao.Get(bot.ID, from.ID)
+			assert.NilError(t, err)
+			assert.Assert(t, user == nil)
+		})
+
+		t.Run("register a user on /start", func(t *testing.T) {
+			status := postUpdate(bot.WebhookSecret, &telegram.Update{
+				UpdateID: 2,
+				Message:  &telegram.Message{MessageID: 2, From: from, Chat: privateChat, Text: "/start"},
+			})
+			assert.Equal(t, status, http.StatusOK)
+			user, err := userDao.Get(bot.ID, from.ID)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, user, &types.User{
+				ChatType:    types.ChatTypePrivate,
+				BotID:       bot.ID,
+				TelegramID:  from.ID,
+				FirstName:   "Webhook",
+				LastName:    "User",
+				DisplayName: "Webhook User",
+				UserName:    "webhook_user",
+			})
+		})
+
+		t.Run("ignore group messages", func(t *testing.T) {
+			status := postUpdate(bot.WebhookSecret, &telegram.Update{
+				UpdateID: 3,
+				Message: &telegram.Message{
+					From: &telegram.User{ID: 8002, FirstName: "Group member"},
+					Chat: &telegram.Chat{ID: -100, Type: "group"},
+					Text: "hello",
+				},
+			})
+			assert.Equal(t, status, http.StatusOK)
+			user, err := userDao.Get(bot.ID, 8002)
+			assert.NilError(t, err)
+			assert.Assert(t, user == nil)
+		})
+
+		t.Run("deactivate a user who has blocked the bot", func(t *testing.T) {
+			status := postUpdate(bot.WebhookSecret, &telegram.Update{
+				UpdateID: 4,
+				MyChatMember: &telegram.ChatMemberUpdated{
+					Chat:          privateChat,
+					From:          from,
+					OldChatMember: &telegram.ChatMember{Status: telegram.ChatMemberStatusMember},
+					NewChatMember: &telegram.ChatMember{Status: telegram.ChatMemberStatusKicked},
+				},
+			})
+			assert.Equal(t, status, http.StatusOK)
+			user, err := userDao.Get(bot.ID, from.ID)
+			assert.NilError(t, err)
+			assert.Assert(t, user.Blocked)
+
+			result, err := deliveryDao.Take(bot.ID, campaign.ID, 0)
+			assert.NilError(t, err)
+			assert.Assert(t, result == nil)
+		})
+
+		t.Run("reactivate a user who writes again", func(t *testing.T) {
+			status := postUpdate(bot.WebhookSecret, &telegram.Update{
+				UpdateID: 5,
+				Message:  &telegram.Message{MessageID: 5, From: from, Chat: privateChat, Text: "/start"},
+			})
+			assert.Equal(t, status, http.StatusOK)
+			user, err := userDao.Get(bot.ID, from.ID)
+			assert.NilError(t, err)
+			assert.Assert(t, !user.Blocked)
+
+			result, err := deliveryDao.Take(bot.ID, campaign.ID, 0)
+			assert.NilError(t, err)
+			assert.Equal(t, result.User.TelegramID, from.ID)
+		})
+	})
+}
+
+func createIntegrationTestPollingInvocation(t *testing.T) fx.Option {
+	return fx.Invoke(func(
+		botDao dao.BotDao,
+		userDao dao.UserDao,
+		ingester *ingest.Ingester,
+		notifier *notify.Notifier,
+		fake *telegramtest.Server,
+	) {
+		defer fake.Close()
+
+		pollingBot, err := botDao.Create(&types.Bot{
+			Title:         "Polling bot",
+			Token:         "polling:token",
+			IngestionMode: types.IngestionModePolling,
+		})
+		assert.NilError(t, err)
+		webhookBot, err := botDao.Create(&types.Bot{
+			Title:         "Webhook bot",
+			Token:         "polling:webhook",
+			IngestionMode: types.IngestionModeWebhook,
+		})
+		assert.NilError(t, err)
+		fake.AddBot(pollingBot.Token, telegram.User{FirstName: "Polling bot"})
+		fake.AddBot(webhookBot.Token, telegram.User{FirstName: "Webhook bot"})
+
+		//A webhook left from the webhook mode is removed by the poller
+		telegramClient := telegram.NewClient(fake.URL)
+		assert.NilError(t, telegramClient.SetWebhook(pollingBot.Token, &telegram.SetWebhookRequest{
+			URL: "https://example.com/webhook",
+		}))
+
+		startMessage := func(telegramID int64) telegram.Update {
+			from := &telegram.User{ID: telegramID, FirstName: fmt.Sprintf("Polled user %d", telegramID)}
+			return telegram.Update{Message: &telegram.Message{
+				MessageID: 1,
+				From:      from,
+				Chat:      &telegram.Chat{ID: telegramID, Type: "private"},
+				Text:      "/start",
+			}}
+		}
+		fake.PushUpdate(pollingBot.Token, startMessage(9001))
+		lastUpdate := fake.PushUpdate(pollingBot.Token, telegram.Update{MyChatMember: &telegram.ChatMemberUpdated{
+			Chat:          &telegram.Chat{ID: 9001, Type: "private"},
+			From:          &telegram.User{ID: 9001, FirstName: "Polled user 9001"},
+			NewChatMember: &telegram.ChatMember{Status: telegram.ChatMemberStatusKicked},
+		}})
+		fake.PushUpdate(webhookBot.Token, startMessage(9002))
+
+		poller := ingest.NewPoller(botDao, ingester, telegramClient, notifier, ingest.PollerOptions{
+			PollTimeout:     time.Second,
+			RefreshInterval: 100 * time.Millisecond,
+			ErrorDelay:      50 * time.Millisecond,
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			poller.Run(ctx)
+		}()
+		defer func() {
+			cancel()
+			<-stopped
+		}()
+
+		waitForUser := func(botID int64, telegramID int64) *types.User {
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+				user, err := userDao.Get(botID, telegramID)
+				assert.NilError(t, err)
+				if user != nil {
+					return user
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+			return nil
+		}
+
+		t.Run("ingest updates of bots in polling mode", func(t *testing.T) {
+			user := waitForUser(pollingBot.ID, 9001)
+			assert.Assert(t, user != nil)
+			assert.Equal(t, fake.Webhook(pollingBot.Token).URL, "")
+
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+				bot, err := botDao.Get(pollingBot.ID)
+				assert.NilError(t, err)
+				if bot.UpdateOffset == lastUpdate.UpdateID+1 {
+					break
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+			user, err := userDao.Get(pollingBot.ID, 9001)
+			assert.NilError(t, err)
+			assert.Assert(t, user.Blocked)
+			bot, err := botDao.Get(pollingBot.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, bot.UpdateOffset, lastUpdate.UpdateID+1)
+
+			user, err = userDao.Get(webhookBot.ID, 9002)
+			assert.NilError(t, err)
+			assert.Assert(t, user == nil)
+		})
+
+		t.Run("switch a bot to polling mode", func(t *testing.T) {
+			webhookBot.IngestionMode = types.IngestionModePolling
+			_, err := botDao.Update(webhookBot)
+			assert.NilError(t, err)
+
+			user := waitForUser(webhookBot.ID, 9002)
+			assert.Assert(t, user != nil)
+		})
+
+		t.Run("keep the offset when a bot is updated", func(t *testing.T) {
+			bot, err := botDao.Get(pollingBot.ID)
+			assert.NilError(t, err)
+			bot.Title = "Renamed polling bot"
+			bot.UpdateOffset = 0
+			_, err = botDao.Update(bot)
+			assert.NilError(t, err)
+			bot, err = botDao.Get(pollingBot.ID)
+			assert.NilError(t, err)
+			assert.Equal(t, bot.UpdateOffset, lastUpdate.UpdateID+1)
+		})
+	})
+}
+
+// withoutIdentity drops what the server learns from getMe, so bots created with
+// and without the server can be compared
+func withoutIdentity(bot *types.Bot) *types.Bot {
+	if bot == nil {
+		return nil
+	}
+	stripped := *bot
+	stripped.TelegramID = 0
+	stripped.TelegramUserName = ""
+	stripped.CanJoinGroups = false
+	stripped.CanReadAllGroupMessages = false
+	stripped.SupportsInlineQueries = false
+	stripped.ValidatedAt = time.Time{}
+	return &stripped
+}
@@ -1,10 +1,37 @@
 import { AxiosInstance } from 'axios';
-import { BotDao, CampaignDao, DeliveryDao, UserDao } from './dao';
+import {
+    BotDao,
+    CampaignDao,
+    DeliveryDao,
+    UserDao,
+    WorkerDao,
+    CampaignOperationDao,
+    ClickDao,
+    LinkDao,
+    ConversionDao,
+    AnswerDao,
+    InboxDao,
+    AutoResponderDao,
+    MediaDao,
+    TesterDao,
+    SequenceDao,
+} from './dao';
 import {
     BotDaoImplAxios,
     CampaignDaoImplAxios,
     UserDaoImplAxios,
     DeliveryDaoImplAxios,
+    WorkerDaoImplAxios,
+    CampaignOperationDaoImplAxios,
+    ClickDaoImplAxios,
+    LinkDaoImplAxios,
+    ConversionDaoImplAxios,
+    AnswerDaoImplAxios,
+    InboxDaoImplAxios,
+    AutoResponderDaoImplAxios,
+    MediaDaoImplAxios,
+    TesterDaoImplAxios,
+    SequenceDaoImplAxios,
 } from './dao_impl_axios';
 
 export class BarkerClient {
@@ -12,12 +39,34 @@ export class BarkerClient {
     public readonly user: UserDao;
     public readonly campaign: CampaignDao;
     public readonly delivery: DeliveryDao;
+    public readonly worker: WorkerDao;
+    public readonly campaignOperation: CampaignOperationDao;
+    public readonly click: ClickDao;
+    public readonly link: LinkDao;
+    public readonly conversion: ConversionDao;
+    public readonly answer: AnswerDao;
+    public readonly inbox: InboxDao;
+    public readonly autoResponder: AutoResponderDao;
+    public readonly media: MediaDao;
+    public readonly tester: TesterDao;
+    public readonly sequence: SequenceDao;
 
     constructor(private http: AxiosInstance) {
         this.bot = new BotDaoImplAxios(http);
         this.campaign = new CampaignDaoImplAxios(http);
         this.user = new UserDaoImplAxios(http);
         this.delivery = new DeliveryDaoImplAxios(http);
+        this.worker = new WorkerDaoImplAxios(http);
+        this.campaignOperation = new CampaignOperationDaoImplAxios(http);
+        this.click = new ClickDaoImplAxios(http);
+        this.link = new LinkDaoImplAxios(http);
+        this.conversion = new ConversionDaoImplAxios(http);
+        this.answer = new AnswerDaoImplAxios(http);
+        this.inbox = new InboxDaoImplAxios(http);
+        this.autoResponder = new AutoResponderDaoImplAxios(http);
+        this.media = new MediaDaoImplAxios(http);
+        this.tester = new TesterDaoImplAxios(http);
+        this.sequence = new SequenceDaoImplAxios(http);
     }
 }
 
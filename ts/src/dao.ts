@@ -8,6 +8,26 @@ import {
     PaginatorResponse,
     PaginatorRequest,
     CampaignAggregatedStatistics,
+    Worker,
+    CampaignOperation,
+    MessageJob,
+    Click,
+    TrackedLink,
+    Conversion,
+    Answer,
+    SurveyResults,
+    InboxMessage,
+    Conversation,
+    AutoResponderRule,
+    SourceUsers,
+    CampaignSourceStatistics,
+    CampaignLanguageStatistics,
+    MediaAsset,
+    CampaignPreview,
+    Tester,
+    CampaignRevision,
+    CampaignRevisionDiff,
+    Sequence,
 } from './types';
 
 export interface BotDao {
@@ -17,6 +37,8 @@ export interface BotDao {
     GetByToken(token: string): Promise<Bot>;
     List(pageRequest: PaginatorRequest): Promise<[Bot[], PaginatorResponse]>;
     RRTake(): Promise<Bot>;
+    SetUpdateOffset(botID: number, offset: number): Promise<void>;
+    SetTokenRevoked(botID: number): Promise<void>;
 }
 
 export interface CampaignDao {
@@ -27,6 +49,46 @@ export interface CampaignDao {
         botID: number,
         campaignID: number
     ): Promise<CampaignAggregatedStatistics>;
+    GetSourceStatistics(
+        botID: number,
+        campaignID: number,
+        touch?: 'first' | 'last'
+    ): Promise<CampaignSourceStatistics[]>;
+    GetLanguageStatistics(
+        botID: number,
+        campaignID: number
+    ): Promise<CampaignLanguageStatistics[]>;
+    Preview(campaign: Campaign, telegramID?: number): Promise<CampaignPreview>;
+    SetState(
+        botID: number,
+        campaignID: number,
+        state: string
+    ): Promise<Campaign>;
+    Approve(
+        botID: number,
+        campaignID: number,
+        approvedBy: string
+    ): Promise<Campaign>;
+    ListRevisions(
+        botID: number,
+        campaignID: number
+    ): Promise<CampaignRevision[]>;
+    GetRevision(
+        botID: number,
+        campaignID: number,
+        revision: number
+    ): Promise<CampaignRevision>;
+    DiffRevisions(
+        botID: number,
+        campaignID: number,
+        from: number,
+        to?: number
+    ): Promise<CampaignRevisionDiff>;
+    GetDeliveredRevision(
+        botID: number,
+        campaignID: number,
+        telegramID: number
+    ): Promise<CampaignRevision>;
     List(
         botID: number,
         pageRequest: PaginatorRequest
@@ -40,14 +102,166 @@ export interface UserDao {
         botID: number,
         pageRequest: PaginatorRequest
     ): Promise<[User[], PaginatorResponse]>;
+    SetBlocked(
+        botID: number,
+        telegramID: number,
+        blocked: boolean
+    ): Promise<User>;
+    SetUnsubscribed(
+        botID: number,
+        telegramID: number,
+        unsubscribed: boolean
+    ): Promise<User>;
+    AddTag(botID: number, telegramID: number, tag: string): Promise<User>;
+    Migrate(
+        botID: number,
+        telegramID: number,
+        newTelegramID: number
+    ): Promise<User>;
+    Sources(botID: number): Promise<SourceUsers[]>;
 }
 
 export interface DeliveryDao {
     Take(
         botID: number,
         campaignID: number,
-        telegramID: number
+        telegramID: number,
+        waitSeconds?: number
+    ): Promise<DeliveryTakeResult>;
+    TakeForWorker(
+        workerID: string,
+        botID: number,
+        campaignID: number,
+        telegramID: number,
+        waitSeconds?: number
     ): Promise<DeliveryTakeResult>;
+    NextJob(
+        workerID: string,
+        waitSeconds?: number
+    ): Promise<DeliveryTakeResult>;
+    TakeBatch(
+        workerID: string,
+        botID: number,
+        size: number,
+        waitSeconds?: number
+    ): Promise<DeliveryTakeResult[]>;
     SetState(delivery: Delivery, state: DeliveryState): Promise<void>;
+    SetStates(deliveries: Delivery[]): Promise<void>;
     GetState(delivery: Delivery): Promise<DeliveryState>;
+    Release(deliveries: Delivery[]): Promise<void>;
+}
+
+export interface WorkerDao {
+    Register(worker: Worker): Promise<Worker>;
+    Heartbeat(worker: Worker): Promise<Worker>;
+    Get(workerID: string): Promise<Worker>;
+    List(): Promise<Worker[]>;
+    ReleaseExpired(timeoutSeconds: number): Promise<Worker[]>;
+}
+
+export interface CampaignOperationDao {
+    Edit(
+        botID: number,
+        campaignID: number,
+        text: string
+    ): Promise<CampaignOperation>;
+    Recall(botID: number, campaignID: number): Promise<CampaignOperation>;
+    Test(botID: number, campaignID: number): Promise<CampaignOperation>;
+    Get(
+        botID: number,
+        campaignID: number,
+        operationID: number
+    ): Promise<CampaignOperation>;
+    List(botID: number, campaignID: number): Promise<CampaignOperation[]>;
+    TakeJobs(
+        workerID: string,
+        botID: number,
+        size: number
+    ): Promise<MessageJob[]>;
+    SetJobStates(jobs: MessageJob[]): Promise<void>;
+    ReleaseJobs(jobs: MessageJob[]): Promise<void>;
+}
+
+export interface ClickDao {
+    Add(click: Click): Promise<Click | null>;
+}
+
+export interface LinkDao {
+    Open(code: string): Promise<TrackedLink>;
+}
+
+export interface ConversionDao {
+    Create(conversion: Conversion): Promise<Conversion>;
+}
+
+export interface AnswerDao {
+    Put(answer: Answer): Promise<Answer | null>;
+    Retract(answer: Answer): Promise<void>;
+    Results(botID: number, campaignID: number): Promise<SurveyResults>;
+    List(botID: number, campaignID: number): Promise<Answer[]>;
+}
+
+export interface InboxDao {
+    Receive(message: InboxMessage): Promise<InboxMessage | null>;
+    Reply(
+        botID: number,
+        telegramID: number,
+        text: string
+    ): Promise<InboxMessage>;
+    AutoReply(
+        botID: number,
+        telegramID: number,
+        replyTo: number,
+        text: string
+    ): Promise<InboxMessage>;
+    List(
+        botID: number,
+        pageRequest: PaginatorRequest
+    ): Promise<[Conversation[], PaginatorResponse]>;
+    Conversation(
+        botID: number,
+        telegramID: number,
+        pageRequest: PaginatorRequest
+    ): Promise<[InboxMessage[], PaginatorResponse]>;
+}
+
+export interface AutoResponderDao {
+    Create(rule: AutoResponderRule): Promise<AutoResponderRule>;
+    Update(rule: AutoResponderRule): Promise<AutoResponderRule>;
+    Get(botID: number, ruleID: number): Promise<AutoResponderRule>;
+    Delete(botID: number, ruleID: number): Promise<void>;
+    List(botID: number): Promise<AutoResponderRule[]>;
+}
+
+export interface MediaDao {
+    Create(
+        file: Blob,
+        fileName: string,
+        contentType?: string
+    ): Promise<MediaAsset>;
+    Get(mediaID: number): Promise<MediaAsset>;
+    GetContent(mediaID: number): Promise<ArrayBuffer>;
+    List(
+        pageRequest: PaginatorRequest
+    ): Promise<[MediaAsset[], PaginatorResponse]>;
+    SetFileID(botID: number, mediaID: number, fileID: string): Promise<void>;
+}
+
+export interface TesterDao {
+    Add(botID: number, telegramID: number): Promise<Tester>;
+    Remove(botID: number, telegramID: number): Promise<void>;
+    List(botID: number): Promise<Tester[]>;
+}
+
+export interface SequenceDao {
+    Create(sequence: Sequence): Promise<Sequence>;
+    Update(sequence: Sequence): Promise<Sequence>;
+    Get(botID: number, sequenceID: number): Promise<Sequence>;
+    Delete(botID: number, sequenceID: number): Promise<void>;
+    List(botID: number): Promise<Sequence[]>;
+    Enroll(
+        botID: number,
+        sequenceID: number,
+        telegramID: number
+    ): Promise<Sequence>;
 }
@@ -1,5 +1,21 @@
 import { AxiosInstance } from 'axios';
-import { BotDao, UserDao, CampaignDao, DeliveryDao } from './dao';
+import {
+    BotDao,
+    UserDao,
+    CampaignDao,
+    DeliveryDao,
+    WorkerDao,
+    CampaignOperationDao,
+    ClickDao,
+    LinkDao,
+    ConversionDao,
+    AnswerDao,
+    InboxDao,
+    AutoResponderDao,
+    MediaDao,
+    TesterDao,
+    SequenceDao,
+} from './dao';
 import {
     Bot,
     PaginatorRequest,
@@ -10,6 +26,27 @@ import {
     Delivery,
     DeliveryState,
     CampaignAggregatedStatistics,
+    Worker,
+    CampaignOperation,
+    MessageJob,
+    Click,
+    TrackedLink,
+    Conversion,
+    Answer,
+    SurveyResults,
+    InboxMessage,
+    Conversation,
+    AutoResponderRule,
+    SourceUsers,
+    CampaignSourceStatistics,
+    CampaignLanguageStatistics,
+    MediaAsset,
+    CampaignPreview,
+    Tester,
+    CampaignRevision,
+    CampaignRevisionDiff,
+    DeliveryStateReport,
+    Sequence,
 } from './types';
 import U from 'url-template';
 
@@ -68,6 +105,19 @@ export class BotDaoImplAxios implements BotDao {
         } = await this.http.post('/rr/bot');
         return data;
     }
+
+    public async SetUpdateOffset(botID: number, offset: number): Promise<void> {
+        await this.http.put(
+            U.parse('/bot/{botID}/update-offset').expand({ botID }),
+            { Offset: offset }
+        );
+    }
+
+    public async SetTokenRevoked(botID: number): Promise<void> {
+        await this.http.put(
+            U.parse('/bot/{botID}/token-revoked').expand({ botID })
+        );
+    }
 }
 
 export class UserDaoImplAxios implements UserDao {
@@ -109,6 +159,83 @@ export class UserDaoImplAxios implements UserDao {
         );
         return [data, paging];
     }
+
+    public async SetBlocked(
+        botID: number,
+        telegramID: number,
+        blocked: boolean
+    ): Promise<User> {
+        const {
+            data: { data },
+        } = await this.http.put(
+            U.parse('/bot/{botID}/user/{telegramID}/blocked').expand({
+                botID,
+                telegramID,
+            }),
+            { Blocked: blocked }
+        );
+        return data;
+    }
+
+    public async SetUnsubscribed(
+        botID: number,
+        telegramID: number,
+        unsubscribed: boolean
+    ): Promise<User> {
+        const {
+            data: { data },
+        } = await this.http.put(
+            U.parse('/bot/{botID}/user/{telegramID}/unsubscribed').expand({
+                botID,
+                telegramID,
+            }),
+            { Unsubscribed: unsubscribed }
+        );
+        return data;
+    }
+
+    public async AddTag(
+        botID: number,
+        telegramID: number,
+        tag: string
+    ): Promise<User> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            U.parse('/bot/{botID}/user/{telegramID}/tag').expand({
+                botID,
+                telegramID,
+            }),
+            { Tag: tag }
+        );
+        return data;
+    }
+
+    public async Migrate(
+        botID: number,
+        telegramID: number,
+        newTelegramID: number
+    ): Promise<User> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            U.parse('/bot/{botID}/user/{telegramID}/migrate').expand({
+                botID,
+                telegramID,
+            }),
+            { NewTelegramID: newTelegramID }
+        );
+        return data;
+    }
+
+    public async Sources(botID: number): Promise<SourceUsers[]> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse('/bot/{botID}/sources').expand({ botID })
+        );
+        return data;
+    }
 }
 
 export class CampaignDaoImplAxios implements CampaignDao {
@@ -166,6 +293,167 @@ export class CampaignDaoImplAxios implements CampaignDao {
         return data;
     }
 
+    public async GetSourceStatistics(
+        botID: number,
+        campaignID: number,
+        touch?: 'first' | 'last'
+    ): Promise<CampaignSourceStatistics[]> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse(
+                '/bot/{botID}/campaign/{campaignID}/sourceStatistics'
+            ).expand({
+                botID,
+                campaignID,
+            }),
+            {
+                params: { Touch: touch },
+            }
+        );
+        return data;
+    }
+
+    public async GetLanguageStatistics(
+        botID: number,
+        campaignID: number
+    ): Promise<CampaignLanguageStatistics[]> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse(
+                '/bot/{botID}/campaign/{campaignID}/languageStatistics'
+            ).expand({
+                botID,
+                campaignID,
+            })
+        );
+        return data;
+    }
+
+    public async Preview(
+        campaign: Campaign,
+        telegramID?: number
+    ): Promise<CampaignPreview> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            U.parse('/bot/{botID}/campaign-preview').expand({
+                botID: campaign.BotID,
+            }),
+            campaign,
+            { params: { TelegramID: telegramID } }
+        );
+        return data;
+    }
+
+    public async SetState(
+        botID: number,
+        campaignID: number,
+        state: string
+    ): Promise<Campaign> {
+        const {
+            data: { data },
+        } = await this.http.put(
+            U.parse('/bot/{botID}/campaign/{campaignID}/state').expand({
+                botID,
+                campaignID,
+            }),
+            { State: state }
+        );
+        return data;
+    }
+
+    public async Approve(
+        botID: number,
+        campaignID: number,
+        approvedBy: string
+    ): Promise<Campaign> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            U.parse('/bot/{botID}/campaign/{campaignID}/approve').expand({
+                botID,
+                campaignID,
+            }),
+            { ApprovedBy: approvedBy }
+        );
+        return data;
+    }
+
+    public async ListRevisions(
+        botID: number,
+        campaignID: number
+    ): Promise<CampaignRevision[]> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse('/bot/{botID}/campaign/{campaignID}/revision').expand({
+                botID,
+                campaignID,
+            })
+        );
+        return data;
+    }
+
+    public async GetRevision(
+        botID: number,
+        campaignID: number,
+        revision: number
+    ): Promise<CampaignRevision> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse(
+                '/bot/{botID}/campaign/{campaignID}/revision/{revision}'
+            ).expand({
+                botID,
+                campaignID,
+                revision,
+            })
+        );
+        return data;
+    }
+
+    public async DiffRevisions(
+        botID: number,
+        campaignID: number,
+        from: number,
+        to?: number
+    ): Promise<CampaignRevisionDiff> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse(
+                '/bot/{botID}/campaign/{campaignID}/revision-diff'
+            ).expand({
+                botID,
+                campaignID,
+            }),
+            { params: { From: from, To: to } }
+        );
+        return data;
+    }
+
+    public async GetDeliveredRevision(
+        botID: number,
+        campaignID: number,
+        telegramID: number
+    ): Promise<CampaignRevision> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse(
+                '/bot/{botID}/campaign/{campaignID}/delivery/{telegramID}/revision'
+            ).expand({
+                botID,
+                campaignID,
+                telegramID,
+            })
+        );
+        return data;
+    }
+
     public async List(
         botID: number,
         pageRequest: PaginatorRequest
@@ -188,7 +476,24 @@ export class DeliveryDaoImplAxios implements DeliveryDao {
     public async Take(
         botID: number,
         campaignID: number,
-        telegramID: number
+        telegramID: number,
+        waitSeconds: number = 0
+    ): Promise<DeliveryTakeResult> {
+        return this.TakeForWorker(
+            '',
+            botID,
+            campaignID,
+            telegramID,
+            waitSeconds
+        );
+    }
+
+    public async TakeForWorker(
+        workerID: string,
+        botID: number,
+        campaignID: number,
+        telegramID: number,
+        waitSeconds: number = 0
     ): Promise<DeliveryTakeResult> {
         const url =
             campaignID === 0
@@ -202,11 +507,47 @@ export class DeliveryDaoImplAxios implements DeliveryDao {
                 campaignID,
             }),
             {},
-            { params: { TelegramID: telegramID } }
+            {
+                params: {
+                    TelegramID: telegramID,
+                    WorkerID: workerID,
+                    Wait: waitSeconds,
+                },
+            }
+        );
+        return data;
+    }
+
+    public async NextJob(
+        workerID: string,
+        waitSeconds: number = 0
+    ): Promise<DeliveryTakeResult> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            '/rr/delivery',
+            {},
+            { params: { WorkerID: workerID, Wait: waitSeconds } }
         );
         return data;
     }
 
+    public async TakeBatch(
+        workerID: string,
+        botID: number,
+        size: number,
+        waitSeconds: number = 0
+    ): Promise<DeliveryTakeResult[]> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            U.parse('/bot/{botID}/delivery/batch').expand({ botID }),
+            {},
+            { params: { Size: size, WorkerID: workerID, Wait: waitSeconds } }
+        );
+        return data || [];
+    }
+
     public async SetState(
         delivery: Delivery,
         state: DeliveryState
@@ -219,10 +560,43 @@ export class DeliveryDaoImplAxios implements DeliveryDao {
                 campaignID: delivery.CampaignID,
                 telegramID: delivery.TelegramID,
                 state: DeliveryState[state],
-            })
+            }),
+            null,
+            {
+                params: {
+                    Reason: delivery.Reason,
+                    MessageID: delivery.MessageID,
+                    PollID: delivery.PollID,
+                },
+            }
         );
     }
 
+    public async SetStates(deliveries: Delivery[]): Promise<void> {
+        const reportsByBot = new Map<number, DeliveryStateReport[]>();
+        for (const delivery of deliveries) {
+            const botID = delivery.BotID || 0;
+            const report: DeliveryStateReport = {
+                CampaignID: delivery.CampaignID,
+                TelegramID: delivery.TelegramID,
+                State: DeliveryState[delivery.State || 0],
+                Reason: delivery.Reason,
+                MessageID: delivery.MessageID,
+                PollID: delivery.PollID,
+            };
+            reportsByBot.set(botID, [
+                ...(reportsByBot.get(botID) || []),
+                report,
+            ]);
+        }
+        for (const [botID, botReports] of reportsByBot) {
+            await this.http.put(
+                U.parse('/bot/{botID}/delivery/state').expand({ botID }),
+                botReports
+            );
+        }
+    }
+
     public async GetState(delivery: Delivery): Promise<DeliveryState> {
         const {
             data: { data },
@@ -237,4 +611,617 @@ export class DeliveryDaoImplAxios implements DeliveryDao {
         );
         return DeliveryState[data as keyof typeof DeliveryState];
     }
+
+    public async Release(deliveries: Delivery[]): Promise<void> {
+        const deliveriesByBot = new Map<number, Delivery[]>();
+        for (const delivery of deliveries) {
+            const botID = delivery.BotID || 0;
+            deliveriesByBot.set(botID, [
+                ...(deliveriesByBot.get(botID) || []),
+                {
+                    CampaignID: delivery.CampaignID,
+                    TelegramID: delivery.TelegramID,
+                },
+            ]);
+        }
+        for (const [botID, botDeliveries] of deliveriesByBot) {
+            await this.http.post(
+                U.parse('/bot/{botID}/delivery/release').expand({ botID }),
+                botDeliveries
+            );
+        }
+    }
+}
+
+export class WorkerDaoImplAxios implements WorkerDao {
+    constructor(private http: AxiosInstance) {}
+
+    public async Register(worker: Worker): Promise<Worker> {
+        const {
+            data: { data },
+        } = await this.http.post('/worker', worker);
+        return data;
+    }
+
+    public async Heartbeat(worker: Worker): Promise<Worker> {
+        const {
+            data: { data },
+        } = await this.http.put(
+            U.parse('/worker/{workerID}/heartbeat').expand({
+                workerID: worker.ID,
+            }),
+            worker
+        );
+        return data;
+    }
+
+    public async Get(workerID: string): Promise<Worker> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse('/worker/{workerID}').expand({ workerID })
+        );
+        return data;
+    }
+
+    public async List(): Promise<Worker[]> {
+        const {
+            data: { data },
+        } = await this.http.get('/worker');
+        return data;
+    }
+
+    public async ReleaseExpired(timeoutSeconds: number): Promise<Worker[]> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            '/worker-expired/release',
+            {},
+            { params: { Timeout: timeoutSeconds } }
+        );
+        return data;
+    }
+}
+
+export class CampaignOperationDaoImplAxios implements CampaignOperationDao {
+    constructor(private http: AxiosInstance) {}
+
+    public async Edit(
+        botID: number,
+        campaignID: number,
+        text: string
+    ): Promise<CampaignOperation> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            U.parse('/bot/{botID}/campaign/{campaignID}/edit').expand({
+                botID,
+                campaignID,
+            }),
+            { Text: text }
+        );
+        return data;
+    }
+
+    public async Recall(
+        botID: number,
+        campaignID: number
+    ): Promise<CampaignOperation> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            U.parse('/bot/{botID}/campaign/{campaignID}/recall').expand({
+                botID,
+                campaignID,
+            })
+        );
+        return data;
+    }
+
+    public async Test(
+        botID: number,
+        campaignID: number
+    ): Promise<CampaignOperation> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            U.parse('/bot/{botID}/campaign/{campaignID}/test').expand({
+                botID,
+                campaignID,
+            })
+        );
+        return data;
+    }
+
+    public async Get(
+        botID: number,
+        campaignID: number,
+        operationID: number
+    ): Promise<CampaignOperation> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse(
+                '/bot/{botID}/campaign/{campaignID}/operation/{operationID}'
+            ).expand({
+                botID,
+                campaignID,
+                operationID,
+            })
+        );
+        return data;
+    }
+
+    public async List(
+        botID: number,
+        campaignID: number
+    ): Promise<CampaignOperation[]> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse('/bot/{botID}/campaign/{campaignID}/operation').expand({
+                botID,
+                campaignID,
+            })
+        );
+        return data || [];
+    }
+
+    public async TakeJobs(
+        workerID: string,
+        botID: number,
+        size: number
+    ): Promise<MessageJob[]> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            U.parse('/bot/{botID}/message-job/batch').expand({ botID }),
+            {},
+            { params: { Size: size, WorkerID: workerID } }
+        );
+        return data || [];
+    }
+
+    public async SetJobStates(jobs: MessageJob[]): Promise<void> {
+        const jobsByBot = new Map<number, MessageJob[]>();
+        for (const job of jobs) {
+            const botID = job.BotID || 0;
+            jobsByBot.set(botID, [...(jobsByBot.get(botID) || []), job]);
+        }
+        for (const [botID, botJobs] of jobsByBot) {
+            await this.http.put(
+                U.parse('/bot/{botID}/message-job/state').expand({ botID }),
+                botJobs
+            );
+        }
+    }
+
+    public async ReleaseJobs(jobs: MessageJob[]): Promise<void> {
+        const jobsByBot = new Map<number, MessageJob[]>();
+        for (const job of jobs) {
+            const botID = job.BotID || 0;
+            jobsByBot.set(botID, [...(jobsByBot.get(botID) || []), job]);
+        }
+        for (const [botID, botJobs] of jobsByBot) {
+            await this.http.post(
+                U.parse('/bot/{botID}/message-job/release').expand({ botID }),
+                botJobs
+            );
+        }
+    }
+}
+
+export class ClickDaoImplAxios implements ClickDao {
+    constructor(private http: AxiosInstance) {}
+
+    public async Add(click: Click): Promise<Click | null> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            U.parse('/bot/{botID}/campaign/{campaignID}/click').expand({
+                botID: click.BotID,
+                campaignID: click.CampaignID,
+            }),
+            click
+        );
+        return data;
+    }
+}
+
+export class LinkDaoImplAxios implements LinkDao {
+    constructor(private http: AxiosInstance) {}
+
+    public async Open(code: string): Promise<TrackedLink> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            U.parse('/link/{code}/open').expand({ code })
+        );
+        return data;
+    }
+}
+
+export class ConversionDaoImplAxios implements ConversionDao {
+    constructor(private http: AxiosInstance) {}
+
+    public async Create(conversion: Conversion): Promise<Conversion> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            U.parse('/bot/{botID}/conversion').expand({
+                botID: conversion.BotID,
+            }),
+            conversion
+        );
+        return data;
+    }
+}
+
+export class AnswerDaoImplAxios implements AnswerDao {
+    constructor(private http: AxiosInstance) {}
+
+    public async Put(answer: Answer): Promise<Answer | null> {
+        const {
+            data: { data },
+        } = await this.http.put(
+            U.parse('/bot/{botID}/answer').expand({ botID: answer.BotID }),
+            answer
+        );
+        return data;
+    }
+
+    public async Retract(answer: Answer): Promise<void> {
+        await this.http.post(
+            U.parse('/bot/{botID}/answer/retract').expand({
+                botID: answer.BotID,
+            }),
+            answer
+        );
+    }
+
+    public async Results(
+        botID: number,
+        campaignID: number
+    ): Promise<SurveyResults> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse('/bot/{botID}/campaign/{campaignID}/results').expand({
+                botID,
+                campaignID,
+            })
+        );
+        return data;
+    }
+
+    public async List(botID: number, campaignID: number): Promise<Answer[]> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse('/bot/{botID}/campaign/{campaignID}/answer').expand({
+                botID,
+                campaignID,
+            })
+        );
+        return data;
+    }
+}
+
+export class InboxDaoImplAxios implements InboxDao {
+    constructor(private http: AxiosInstance) {}
+
+    public async Receive(message: InboxMessage): Promise<InboxMessage | null> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            U.parse('/bot/{botID}/inbox').expand({ botID: message.BotID }),
+            message
+        );
+        return data;
+    }
+
+    public async Reply(
+        botID: number,
+        telegramID: number,
+        text: string
+    ): Promise<InboxMessage> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            U.parse('/bot/{botID}/inbox/{telegramID}/reply').expand({
+                botID,
+                telegramID,
+            }),
+            { Text: text }
+        );
+        return data;
+    }
+
+    public async AutoReply(
+        botID: number,
+        telegramID: number,
+        replyTo: number,
+        text: string
+    ): Promise<InboxMessage> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            U.parse('/bot/{botID}/inbox/{telegramID}/reply').expand({
+                botID,
+                telegramID,
+            }),
+            { Text: text, ReplyTo: replyTo }
+        );
+        return data;
+    }
+
+    public async List(
+        botID: number,
+        pageRequest: PaginatorRequest
+    ): Promise<[Conversation[], PaginatorResponse]> {
+        const {
+            data: { data, paging },
+        } = await this.http.get(
+            U.parse('/bot/{botID}/inbox').expand({ botID }),
+            {
+                params: pageRequest,
+            }
+        );
+        return [data, paging];
+    }
+
+    public async Conversation(
+        botID: number,
+        telegramID: number,
+        pageRequest: PaginatorRequest
+    ): Promise<[InboxMessage[], PaginatorResponse]> {
+        const {
+            data: { data, paging },
+        } = await this.http.get(
+            U.parse('/bot/{botID}/inbox/{telegramID}').expand({
+                botID,
+                telegramID,
+            }),
+            {
+                params: pageRequest,
+            }
+        );
+        return [data, paging];
+    }
+}
+
+export class AutoResponderDaoImplAxios implements AutoResponderDao {
+    constructor(private http: AxiosInstance) {}
+
+    public async Create(rule: AutoResponderRule): Promise<AutoResponderRule> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            U.parse('/bot/{botID}/auto-responder').expand({
+                botID: rule.BotID,
+            }),
+            rule
+        );
+        return data;
+    }
+
+    public async Update(rule: AutoResponderRule): Promise<AutoResponderRule> {
+        const {
+            data: { data },
+        } = await this.http.put(
+            U.parse('/bot/{botID}/auto-responder/{ruleID}').expand({
+                botID: rule.BotID,
+                ruleID: rule.ID,
+            }),
+            rule
+        );
+        return data;
+    }
+
+    public async Get(
+        botID: number,
+        ruleID: number
+    ): Promise<AutoResponderRule> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse('/bot/{botID}/auto-responder/{ruleID}').expand({
+                botID,
+                ruleID,
+            })
+        );
+        return data;
+    }
+
+    public async Delete(botID: number, ruleID: number): Promise<void> {
+        await this.http.delete(
+            U.parse('/bot/{botID}/auto-responder/{ruleID}').expand({
+                botID,
+                ruleID,
+            })
+        );
+    }
+
+    public async List(botID: number): Promise<AutoResponderRule[]> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse('/bot/{botID}/auto-responder').expand({ botID })
+        );
+        return data;
+    }
+}
+
+export class MediaDaoImplAxios implements MediaDao {
+    constructor(private http: AxiosInstance) {}
+
+    public async Create(
+        file: Blob,
+        fileName: string,
+        contentType?: string
+    ): Promise<MediaAsset> {
+        const form = new FormData();
+        form.append('File', file, fileName);
+        if (contentType) {
+            form.append('ContentType', contentType);
+        }
+        const {
+            data: { data },
+        } = await this.http.post('/media', form);
+        return data;
+    }
+
+    public async Get(mediaID: number): Promise<MediaAsset> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse('/media/{mediaID}').expand({ mediaID })
+        );
+        return data;
+    }
+
+    public async GetContent(mediaID: number): Promise<ArrayBuffer> {
+        const { data } = await this.http.get(
+            U.parse('/media/{mediaID}/content').expand({ mediaID }),
+            { responseType: 'arraybuffer' }
+        );
+        return data;
+    }
+
+    public async List(
+        pageRequest: PaginatorRequest
+    ): Promise<[MediaAsset[], PaginatorResponse]> {
+        const {
+            data: { data, paging },
+        } = await this.http.get('/media', {
+            params: pageRequest,
+        });
+        return [data, paging];
+    }
+
+    public async SetFileID(
+        botID: number,
+        mediaID: number,
+        fileID: string
+    ): Promise<void> {
+        await this.http.put(
+            U.parse('/bot/{botID}/media/{mediaID}/file-id').expand({
+                botID,
+                mediaID,
+            }),
+            { FileID: fileID }
+        );
+    }
+}
+
+export class TesterDaoImplAxios implements TesterDao {
+    constructor(private http: AxiosInstance) {}
+
+    public async Add(botID: number, telegramID: number): Promise<Tester> {
+        const {
+            data: { data },
+        } = await this.http.put(
+            U.parse('/bot/{botID}/tester/{telegramID}').expand({
+                botID,
+                telegramID,
+            })
+        );
+        return data;
+    }
+
+    public async Remove(botID: number, telegramID: number): Promise<void> {
+        await this.http.delete(
+            U.parse('/bot/{botID}/tester/{telegramID}').expand({
+                botID,
+                telegramID,
+            })
+        );
+    }
+
+    public async List(botID: number): Promise<Tester[]> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse('/bot/{botID}/tester').expand({ botID })
+        );
+        return data;
+    }
+}
+
+export class SequenceDaoImplAxios implements SequenceDao {
+    constructor(private http: AxiosInstance) {}
+
+    public async Create(sequence: Sequence): Promise<Sequence> {
+        const {
+            data: { data },
+        } = await this.http.post(
+            U.parse('/bot/{botID}/sequence').expand({
+                botID: sequence.BotID,
+            }),
+            sequence
+        );
+        return data;
+    }
+
+    public async Update(sequence: Sequence): Promise<Sequence> {
+        const {
+            data: { data },
+        } = await this.http.put(
+            U.parse('/bot/{botID}/sequence/{sequenceID}').expand({
+                botID: sequence.BotID,
+                sequenceID: sequence.ID,
+            }),
+            sequence
+        );
+        return data;
+    }
+
+    public async Get(botID: number, sequenceID: number): Promise<Sequence> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse('/bot/{botID}/sequence/{sequenceID}').expand({
+                botID,
+                sequenceID,
+            })
+        );
+        return data;
+    }
+
+    public async Delete(botID: number, sequenceID: number): Promise<void> {
+        await this.http.delete(
+            U.parse('/bot/{botID}/sequence/{sequenceID}').expand({
+                botID,
+                sequenceID,
+            })
+        );
+    }
+
+    public async List(botID: number): Promise<Sequence[]> {
+        const {
+            data: { data },
+        } = await this.http.get(
+            U.parse('/bot/{botID}/sequence').expand({ botID })
+        );
+        return data;
+    }
+
+    public async Enroll(
+        botID: number,
+        sequenceID: number,
+        telegramID: number
+    ): Promise<Sequence> {
+        const {
+            data: { data },
+        } = await this.http.put(
+            U.parse(
+                '/bot/{botID}/sequence/{sequenceID}/enrollment/{telegramID}'
+            ).expand({
+                botID,
+                sequenceID,
+                telegramID,
+            })
+        );
+        return data;
+    }
 }
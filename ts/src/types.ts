@@ -6,19 +6,67 @@ export enum DeliveryState {
     fail = 3,
     success = 2,
 }
+export enum MessageJobState {
+    queued = 1,
+    progress = 2,
+    success = 3,
+    fail = 4,
+}
 export interface Bot {
     ID?: number;
     Title?: string;
     Token?: string;
     RRAccessTime?: string;
     RRPossiblyEmpty?: boolean;
+    WebhookSecret?: string;
+    IngestionMode?: string;
+    UpdateOffset?: number;
+    TelegramID?: number;
+    TelegramUserName?: string;
+    CanJoinGroups?: boolean;
+    CanReadAllGroupMessages?: boolean;
+    SupportsInlineQueries?: boolean;
+    TokenRevoked?: boolean;
+    ValidatedAt?: string;
+    ConversionWindowHours?: number;
+    RequireApproval?: boolean;
+}
+export interface CampaignLocalization {
+    Language?: string;
+    Message?: string;
+}
+export interface CampaignButton {
+    Text?: string;
+    Row?: number;
+    URL?: string;
 }
 export interface Campaign {
     ID?: number;
     BotID?: number;
     Title?: string;
     Message?: string;
+    ParseMode?: string;
     Active?: boolean;
+    State?: string;
+    ApprovedBy?: string;
+    ApprovedAt?: string;
+    Revision?: number;
+    TrackLinks?: boolean;
+    Buttons?: CampaignButton[];
+    Kind?: string;
+    Options?: string[];
+    FirstSource?: string;
+    LastSource?: string;
+    ChatType?: string;
+    Localizations?: CampaignLocalization[];
+    MediaID?: number;
+}
+export interface ButtonStatistics {
+    Button?: number;
+    Text?: string;
+    Clicks?: number;
+    Clickers?: number;
+    ClickThroughRate?: number;
 }
 export interface CampaignAggregatedStatistics {
     Users?: number;
@@ -26,6 +74,14 @@ export interface CampaignAggregatedStatistics {
     Errors?: number;
     Pending?: number;
     TimedOut?: number;
+    Clicks?: number;
+    Clickers?: number;
+    ClickThroughRate?: number;
+    Buttons?: ButtonStatistics[];
+    LinkClicks?: number;
+    UniqueLinkClicks?: number;
+    Conversions?: number;
+    Revenue?: number;
 }
 export interface User {
     FirstName?: string;
@@ -34,12 +90,25 @@ export interface User {
     UserName?: string;
     TelegramID?: number;
     BotID?: number;
+    Blocked?: boolean;
+    Unsubscribed?: boolean;
+    Tags?: string[];
+    FirstSource?: string;
+    LastSource?: string;
+    ChatType?: string;
+    LanguageCode?: string;
 }
 export interface Delivery {
     CampaignID?: number;
     BotID?: number;
     TelegramID?: number;
     State?: DeliveryState;
+    WorkerID?: string;
+    Reason?: string;
+    MessageID?: number;
+    PollID?: string;
+    Language?: string;
+    Revision?: number;
 }
 export interface PaginatorRequest {
     Page?: number;
@@ -51,8 +120,227 @@ export interface PaginatorResponse {
     Total?: number;
     TotalItems?: number;
 }
+export interface Worker {
+    ID?: string;
+    Version?: string;
+    Host?: string;
+    BotID?: number;
+    Throughput?: number;
+    LastHeartbeat?: string;
+}
+export interface CampaignOperation {
+    ID?: number;
+    BotID?: number;
+    CampaignID?: number;
+    Kind?: string;
+    Text?: string;
+    CreatedAt?: string;
+    Revision?: number;
+    Queued?: number;
+    Progress?: number;
+    Success?: number;
+    Fail?: number;
+}
+export interface MediaAsset {
+    ID?: number;
+    FileName?: string;
+    ContentType?: string;
+    Size?: number;
+    CreatedAt?: string;
+}
+export interface MessageJob {
+    ID?: number;
+    OperationID?: number;
+    BotID?: number;
+    CampaignID?: number;
+    SequenceID?: number;
+    TelegramID?: number;
+    MessageID?: number;
+    Kind?: string;
+    Text?: string;
+    State?: MessageJobState;
+    Reason?: string;
+    WorkerID?: string;
+    Buttons?: CampaignButton[];
+    SurveyOptions?: string[];
+    Caption?: boolean;
+    ParseMode?: string;
+    Campaign?: Campaign;
+    FileID?: string;
+    Media?: MediaAsset;
+}
+export interface Click {
+    BotID?: number;
+    CampaignID?: number;
+    TelegramID?: number;
+    Variant?: number;
+    Button?: number;
+    CallbackQueryID?: string;
+    CreatedAt?: string;
+}
+export interface Conversion {
+    ID?: number;
+    BotID?: number;
+    TelegramID?: number;
+    Event?: string;
+    Value?: number;
+    CampaignID?: number;
+    CreatedAt?: string;
+}
+export interface Answer {
+    BotID?: number;
+    CampaignID?: number;
+    PollID?: string;
+    TelegramID?: number;
+    Option?: number;
+    AnsweredAt?: string;
+}
+export interface OptionResult {
+    Option?: number;
+    Text?: string;
+    Answers?: number;
+    Share?: number;
+}
+export interface SurveyResults {
+    Answers?: number;
+    Options?: OptionResult[];
+}
+export interface InboxMessage {
+    ID?: number;
+    BotID?: number;
+    TelegramID?: number;
+    Direction?: string;
+    Text?: string;
+    MessageID?: number;
+    CampaignID?: number;
+    ReplyTo?: number;
+    JobID?: number;
+    State?: MessageJobState;
+    Reason?: string;
+    CreatedAt?: string;
+}
+export interface Conversation {
+    BotID?: number;
+    TelegramID?: number;
+    DisplayName?: string;
+    Messages?: number;
+    LastMessage?: InboxMessage;
+}
+export interface AutoResponderRule {
+    ID?: number;
+    BotID?: number;
+    Match?: string;
+    Pattern?: string;
+    Reply?: string;
+    Priority?: number;
+    AddTag?: string;
+    Unsubscribe?: boolean;
+    EnrollSequenceID?: number;
+    CreatedAt?: string;
+}
+export interface SourceUsers {
+    Source?: string;
+    FirstTouch?: number;
+    LastTouch?: number;
+}
+export interface CampaignSourceStatistics {
+    Source?: string;
+    Delivered?: number;
+    Errors?: number;
+    Clickers?: number;
+    ClickThroughRate?: number;
+    Conversions?: number;
+    Revenue?: number;
+}
+export interface CampaignLanguageStatistics {
+    Language?: string;
+    Delivered?: number;
+    Errors?: number;
+    Clickers?: number;
+    ClickThroughRate?: number;
+    Conversions?: number;
+    Revenue?: number;
+}
+
+export interface CampaignPreview {
+    Language?: string;
+    Text?: string;
+    ParseMode?: string;
+    PlainText?: string;
+    Length?: number;
+    MaxLength?: number;
+    MediaID?: number;
+    Options?: string[];
+    Buttons?: CampaignButton[];
+}
+export interface Tester {
+    BotID?: number;
+    TelegramID?: number;
+    CreatedAt?: string;
+}
+export interface CampaignRevision {
+    CampaignID?: number;
+    BotID?: number;
+    Revision?: number;
+    Title?: string;
+    Message?: string;
+    ParseMode?: string;
+    TrackLinks?: boolean;
+    Buttons?: CampaignButton[];
+    Kind?: string;
+    Options?: string[];
+    Localizations?: CampaignLocalization[];
+    MediaID?: number;
+    CreatedAt?: string;
+}
+export interface DiffLine {
+    Op?: string;
+    Text: string;
+}
+export interface CampaignRevisionChange {
+    Field?: string;
+    From: string;
+    To: string;
+    Lines?: DiffLine[];
+}
+export interface CampaignRevisionDiff {
+    CampaignID?: number;
+    From?: number;
+    To?: number;
+    Changes: CampaignRevisionChange[];
+}
+export interface DeliveryStateReport {
+    CampaignID?: number;
+    TelegramID?: number;
+    State?: string;
+    Reason?: string;
+    MessageID?: number;
+    PollID?: string;
+}
+export interface SequenceStep {
+    DelayMinutes?: number;
+    Message?: string;
+}
+export interface Sequence {
+    ID?: number;
+    BotID?: number;
+    Title?: string;
+    Steps?: SequenceStep[];
+    CreatedAt?: string;
+}
+export interface TrackedLink {
+    Code?: string;
+    BotID?: number;
+    CampaignID?: number;
+    TelegramID?: number;
+    URL?: string;
+}
 export interface DeliveryTakeResult {
     Delivery?: Delivery;
     Campaign?: Campaign;
     User?: User;
+    Bot?: Bot;
+    Links?: TrackedLink[];
+    FileID?: string;
+    Media?: MediaAsset;
 }
\ No newline at end of file